@@ -5,6 +5,7 @@ import "time"
 const (
 	OutputDir                   = "output"
 	DebugDir                    = "debug"
+	ThumbnailDir                = "thumbnails" // Cached thumbnails of result images
 	DefaultWebPort         uint = 8080
 	TemplatesDir                = "templates"
 	DataFile                    = "_data/cameras.json"
@@ -13,50 +14,27 @@ const (
 	DefaultGetFrameTimeout uint = 3
 )
 
+// Resolution detectors selectable via RESOLUTION_DETECTOR
+const (
+	ResolutionDetectorLibav   = "libav"
+	ResolutionDetectorFFprobe = "ffprobe"
+)
+
 var (
-	GlobalFrameRate     int
-	GlobalFrameInterval time.Duration
-	GlobalDebugMode     bool
+	GlobalFrameRate          int
+	GlobalFrameInterval      time.Duration
+	GlobalDebugMode          bool
+	GlobalResolutionDetector string
+	GlobalImageBanner        bool // camera name and capture time drawn on saved images, set from IMAGE_BANNER
 )
 
 // Readonly so we dont need to protect it with lock.
 
-// ModelType represents supported model types
+// ModelType represents a model type, model types are registered in the model registry of the data store
 type ModelType string
 
-// Supported model types as constants
+// Model types the pipeline handles specially
 const (
-	ModelTypeOther      ModelType = "other"
-	ModelTypeGesture    ModelType = "gesture"
-	ModelTypePonding    ModelType = "ponding"
-	ModelTypeSmoke      ModelType = "smoke"
-	ModelTypeMouse      ModelType = "mouse"
-	ModelTypeTshirt     ModelType = "tshirt"
-	ModelTypeCigar      ModelType = "cigar"
-	ModelTypeHelmet     ModelType = "helmet"
-	ModelTypeFire       ModelType = "fire"
-	ModelTypeFall       ModelType = "fall"
-	ModelTypeSafetybelt ModelType = "safetybelt"
+	ModelTypeOther ModelType = "other" // default model type of inference servers
+	ModelTypeFall  ModelType = "fall"  // polled per camera task instead of per frame requests
 )
-
-// getClassIndexFromModelType maps modelType to YOLO class index
-// This function is used to generate YOLO format labels in DEBUG mode
-func GetClassIndexFromModelType(modelType string) int {
-	classMap := map[string]int{
-		"other":      0,
-		"gesture":    1,
-		"ponding":    2,
-		"smoke":      3,
-		"mouse":      4,
-		"tshirt":     5,
-		"cigar":      6,
-		"helmet":     7,
-		"fire":       8,
-		"fall":       9,
-		"safetybelt": 10,
-	}
-	if classIndex, exists := classMap[modelType]; exists {
-		return classIndex
-	}
-	return classMap["other"]
-}
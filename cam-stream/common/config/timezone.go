@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultTimezone is used when TIMEZONE is not set, the alert platform expects Shanghai time
+const DefaultTimezone = "Asia/Shanghai"
+
+// GlobalTimezone is the configured IANA time zone for alert timestamps, file names and
+// schedules without their own time zone. Set once at startup.
+var GlobalTimezone = time.Local
+
+// SetTimezone loads the IANA time zone used for timestamps, empty selects the default
+func SetTimezone(name string) error {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %v", name, err)
+	}
+	GlobalTimezone = loc
+	return nil
+}
+
+// InTimezone converts a time to the configured time zone
+func InTimezone(t time.Time) time.Time {
+	return t.In(GlobalTimezone)
+}
@@ -3,12 +3,14 @@ package common
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/jpeg"
 
-	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
 )
 
 type Detection struct {
@@ -18,173 +20,271 @@ type Detection struct {
 	Y1         int     `json:"y1"`
 	X2         int     `json:"x2"`
 	Y2         int     `json:"y2"`
+	// Tracking info, zero when the detection is not tracked
+	TrackID   int     `json:"track_id,omitempty"`
+	TrackAge  int     `json:"track_age,omitempty"`  // Number of updates the track has been matched
+	VelocityX float64 `json:"velocity_x,omitempty"` // Box center velocity in pixels per second
+	VelocityY float64 `json:"velocity_y,omitempty"`
 }
 
-// DrawDetections draws detection boxes on the image
-func DrawDetections(imageData []byte, detections []Detection, cameraName string, saveConfidenceLabel bool) ([]byte, error) {
-	return DrawDetectionsWithServerInfo(imageData, detections, cameraName, saveConfidenceLabel, "")
+// BoxStyle is how a detection box is drawn
+type BoxStyle struct {
+	Color     color.RGBA
+	Thickness int
+	Label     string // Drawn at the box when not empty
 }
 
-// DrawDetectionsWithServerInfo draws detection boxes on the image with server info in confidence labels
-func DrawDetectionsWithServerInfo(imageData []byte, detections []Detection, cameraName string, saveConfidenceLabel bool, serverID string) ([]byte, error) {
+// DefaultBoxStyle returns the style of detections without a configured one
+func DefaultBoxStyle(class string) BoxStyle {
+	return BoxStyle{Color: ClassColor(class), Thickness: 3}
+}
+
+// DrawDetections draws detection boxes on the image with default styles and no labels
+func DrawDetections(imageData []byte, detections []Detection) ([]byte, error) {
+	styles := make([]BoxStyle, len(detections))
+	for i, det := range detections {
+		styles[i] = DefaultBoxStyle(det.Class)
+	}
+	return DrawDetectionsWithStyles(imageData, detections, styles, "")
+}
+
+// DrawDetectionsWithStyles draws detection boxes with their styles and labels, styles[i] belongs to detections[i],
+// and a banner line across the top of the image when banner is not empty
+func DrawDetectionsWithStyles(imageData []byte, detections []Detection, styles []BoxStyle, banner string) ([]byte, error) {
+	_, labeled, err := DrawDetectionImages(imageData, detections, styles, banner)
+	return labeled, err
+}
+
+// DrawDetectionImages decodes the image once and returns it with boxes and banner but without labels,
+// and with the labels of the styles on top. When no style has a label both are the same encoded image.
+func DrawDetectionImages(imageData []byte, detections []Detection, styles []BoxStyle, banner string) (plain, labeled []byte, err error) {
 	img, err := jpeg.Decode(bytes.NewReader(imageData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode JPEG: %v", err)
+		return nil, nil, fmt.Errorf("failed to decode JPEG: %v", err)
 	}
 
-	// Convert to RGBA for drawing
+	// Convert to RGBA for drawing, the YCbCr conversion of draw.Draw is a fast path
 	bounds := img.Bounds()
 	rgbaImg := image.NewRGBA(bounds)
 	draw.Draw(rgbaImg, bounds, img, bounds.Min, draw.Src)
 
 	// Draw detection boxes
-	for _, det := range detections {
-		boxColor := getClassColor(det.Class)
-		drawThickRectangle(rgbaImg, det.X1, det.Y1, det.X2, det.Y2, boxColor, 3)
-		if !saveConfidenceLabel {
-			continue
+	for i, det := range detections {
+		style := styles[i]
+		drawThickRectangle(rgbaImg, det.X1, det.Y1, det.X2, det.Y2, style.Color, style.Thickness)
+	}
+
+	var face font.Face
+	hasLabels := false
+	for _, style := range styles {
+		hasLabels = hasLabels || style.Label != ""
+	}
+	if banner != "" || hasLabels {
+		face = labelFace(labelFontSize(bounds.Dy()))
+	}
+	if banner != "" {
+		drawBanner(rgbaImg, face, banner)
+	}
+
+	plain, err = encodeJPEG(rgbaImg)
+	if err != nil || !hasLabels {
+		return plain, plain, err
+	}
+
+	// Labels are drawn after all boxes so boxes never cover labels, and labels of boxes at the top cover the banner
+	for i, det := range detections {
+		if styles[i].Label != "" {
+			drawLabel(rgbaImg, face, det, styles[i])
 		}
-		drawConfidenceLabelWithServerInfo(rgbaImg, det, serverID)
 	}
+	labeled, err = encodeJPEG(rgbaImg)
+	return plain, labeled, err
+}
 
-	// Encode back to JPEG
+// encodeJPEG encodes a drawn image
+func encodeJPEG(img *image.RGBA) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, rgbaImg, &jpeg.Options{Quality: 90}); err != nil {
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
 		return nil, fmt.Errorf("failed to encode JPEG: %v", err)
 	}
-
 	return buf.Bytes(), nil
 }
 
-// drawThickRectangle draws a rectangle with specified thickness
-func drawThickRectangle(img *image.RGBA, x1, y1, x2, y2 int, col color.RGBA, thickness int) {
-	// Top and bottom borders
-	for t := 0; t < thickness; t++ {
-		for x := x1; x <= x2; x++ {
-			if x >= 0 && x < img.Bounds().Max.X {
-				// Top
-				if y1+t >= 0 && y1+t < img.Bounds().Max.Y {
-					img.Set(x, y1+t, col)
-				}
-				// Bottom
-				if y2-t >= 0 && y2-t < img.Bounds().Max.Y {
-					img.Set(x, y2-t, col)
-				}
-			}
+// DrawDetectionsRGB24 draws detection boxes in place on a raw RGB24 frame, styles[i] belongs to detections[i]
+// and labels are not drawn
+func DrawDetectionsRGB24(data []byte, width, height int, detections []Detection, styles []BoxStyle) {
+	for i, det := range detections {
+		for _, r := range borderRects(det.X1, det.Y1, det.X2, det.Y2, styles[i].Thickness) {
+			fillRectRGB24(data, width, height, r, styles[i].Color)
 		}
 	}
+}
 
-	// Left and right borders
-	for t := 0; t < thickness; t++ {
-		for y := y1; y <= y2; y++ {
-			if y >= 0 && y < img.Bounds().Max.Y {
-				// Left
-				if x1+t >= 0 && x1+t < img.Bounds().Max.X {
-					img.Set(x1+t, y, col)
-				}
-				// Right
-				if x2-t >= 0 && x2-t < img.Bounds().Max.X {
-					img.Set(x2-t, y, col)
-				}
-			}
-		}
+// fillRectRGB24 fills a rectangle of a raw RGB24 frame clipped to the frame, the first row is
+// set pixel by pixel and copied into the others
+func fillRectRGB24(data []byte, width, height int, r image.Rectangle, col color.RGBA) {
+	r = r.Intersect(image.Rect(0, 0, width, height))
+	if r.Empty() {
+		return
+	}
+	rowLen := r.Dx() * 3
+	start := (r.Min.Y*width + r.Min.X) * 3
+	row := data[start : start+rowLen]
+	for i := 0; i < rowLen; i += 3 {
+		row[i] = col.R
+		row[i+1] = col.G
+		row[i+2] = col.B
+	}
+	for y := r.Min.Y + 1; y < r.Max.Y; y++ {
+		offset := (y*width + r.Min.X) * 3
+		copy(data[offset:offset+rowLen], row)
 	}
 }
 
-// drawConfidenceLabelWithServerInfo draws confidence score label with server info
-func drawConfidenceLabelWithServerInfo(img *image.RGBA, det Detection, serverID string) {
-	// Format confidence as percentage with server info
-	var confidenceText string
-	if serverID != "" {
-		confidenceText = fmt.Sprintf("%.1f%% [%s]", det.Confidence*100, serverID)
-	} else {
-		confidenceText = fmt.Sprintf("%.1f%%", det.Confidence*100)
+// drawThickRectangle draws a rectangle with specified thickness, the border lies inside the corners
+func drawThickRectangle(img *image.RGBA, x1, y1, x2, y2 int, col color.RGBA, thickness int) {
+	for _, r := range borderRects(x1, y1, x2, y2, thickness) {
+		fillRect(img, r, col)
 	}
+}
 
-	// Position label above the detection box with some padding
-	labelX := det.X1 + 5
-	labelY := det.Y1 - 5
-
-	// If the label would be outside the image bounds, position it inside the box
-	if labelY <= 20 {
-		labelY = det.Y1 + 25
+// borderRects returns the top, bottom, left and right border of a box with inclusive corners
+func borderRects(x1, y1, x2, y2, thickness int) [4]image.Rectangle {
+	return [4]image.Rectangle{
+		image.Rect(x1, y1, x2+1, y1+thickness),
+		image.Rect(x1, y2-thickness+1, x2+1, y2+1),
+		image.Rect(x1, y1, x1+thickness, y2+1),
+		image.Rect(x2-thickness+1, y1, x2+1, y2+1),
 	}
+}
 
-	// Draw confidence label using gg library for better text rendering
-	ctx := gg.NewContextForRGBA(img)
-
-	// Try to load font for confidence labels
-	fontSize := 16.0
-	fontPath := "./assets/fonts/msyh.ttc"
+// fillRect fills a rectangle clipped to the image with an opaque color, the first row is
+// set pixel by pixel and copied into the others
+func fillRect(img *image.RGBA, r image.Rectangle, col color.RGBA) {
+	r = r.Intersect(img.Rect)
+	if r.Empty() {
+		return
+	}
+	rowLen := r.Dx() * 4
+	start := img.PixOffset(r.Min.X, r.Min.Y)
+	row := img.Pix[start : start+rowLen]
+	for i := 0; i < rowLen; i += 4 {
+		row[i] = col.R
+		row[i+1] = col.G
+		row[i+2] = col.B
+		row[i+3] = col.A
+	}
+	for y := r.Min.Y + 1; y < r.Max.Y; y++ {
+		offset := img.PixOffset(r.Min.X, y)
+		copy(img.Pix[offset:offset+rowLen], row)
+	}
+}
 
-	if err := ctx.LoadFontFace(fontPath, fontSize); err != nil {
-		// Fall back to manual text drawing if font loading fails
-		drawManualConfidenceText(img, labelX, labelY, confidenceText)
+// blendRect draws a translucent premultiplied color over a rectangle clipped to the image
+func blendRect(img *image.RGBA, r image.Rectangle, col color.RGBA) {
+	r = r.Intersect(img.Rect)
+	if r.Empty() {
 		return
 	}
+	inv := 255 - uint32(col.A)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		offset := img.PixOffset(r.Min.X, y)
+		row := img.Pix[offset : offset+r.Dx()*4]
+		for i := 0; i < len(row); i += 4 {
+			row[i] = col.R + uint8(uint32(row[i])*inv/255)
+			row[i+1] = col.G + uint8(uint32(row[i+1])*inv/255)
+			row[i+2] = col.B + uint8(uint32(row[i+2])*inv/255)
+			row[i+3] = col.A + uint8(uint32(row[i+3])*inv/255)
+		}
+	}
+}
 
-	// Use gg library for high-quality text rendering
-	// Draw background rectangle for better readability
-	textWidth, textHeight := ctx.MeasureString(confidenceText)
-	padding := 4.0
+// drawText draws a line of text with its top left corner at x, y
+func drawText(img *image.RGBA, face font.Face, x, y int, text string, col color.RGBA) {
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(col),
+		Face: face,
+		Dot:  fixed.P(x, y+face.Metrics().Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+}
 
-	// Draw semi-transparent background
-	ctx.SetColor(color.RGBA{0, 0, 0, 180})
-	ctx.DrawRectangle(float64(labelX)-padding, float64(labelY)-textHeight-padding, textWidth+2*padding, textHeight+2*padding)
-	ctx.Fill()
+// textSize returns the width and line height of a text
+func textSize(face font.Face, text string) (int, int) {
+	metrics := face.Metrics()
+	return font.MeasureString(face, text).Ceil(), (metrics.Ascent + metrics.Descent).Ceil()
+}
 
-	// Draw confidence text in bright yellow/green
-	ctx.SetColor(color.RGBA{255, 255, 0, 255})
-	ctx.DrawString(confidenceText, float64(labelX), float64(labelY))
+// drawLabel draws the label of a detection above its box, or inside it at the top of the image
+func drawLabel(img *image.RGBA, face font.Face, det Detection, style BoxStyle) {
+	textWidth, textHeight := textSize(face, style.Label)
+	padding := 3
 
+	top := det.Y1 - textHeight - 2*padding
+	if top < 0 {
+		top = det.Y1
+	}
+
+	// Background in the box color for matching labels to boxes
+	fillRect(img, image.Rect(det.X1, top, det.X1+textWidth+2*padding, top+textHeight+2*padding), style.Color)
+	drawText(img, face, det.X1+padding, top+padding, style.Label, contrastColor(style.Color))
 }
 
-// drawManualConfidenceText draws confidence text manually when font loading fails
-func drawManualConfidenceText(img *image.RGBA, x, y int, text string) {
-	charWidth := 8
-	charHeight := 12
-	padding := 2
+// drawBanner draws a line of text on a dark bar across the top of the image
+func drawBanner(img *image.RGBA, face font.Face, text string) {
+	_, textHeight := textSize(face, text)
+	padding := textHeight / 4
 
-	// Calculate background size
-	textWidth := len(text) * charWidth
+	blendRect(img, image.Rect(img.Rect.Min.X, img.Rect.Min.Y, img.Rect.Max.X, img.Rect.Min.Y+textHeight+2*padding),
+		color.RGBA{0, 0, 0, 160})
+	drawText(img, face, img.Rect.Min.X+2*padding, img.Rect.Min.Y+padding, text, color.RGBA{255, 255, 255, 255})
+}
 
-	// Draw background rectangle for better visibility
-	bgColor := color.RGBA{0, 0, 0, 180}
-	for dy := -padding; dy < charHeight+padding; dy++ {
-		for dx := -padding; dx < textWidth+padding; dx++ {
-			px := x + dx
-			py := y + dy
-			if px >= 0 && py >= 0 && px < img.Bounds().Max.X && py < img.Bounds().Max.Y {
-				img.Set(px, py, bgColor)
-			}
-		}
+// contrastColor returns black or white, whichever is readable on the background
+func contrastColor(bg color.RGBA) color.RGBA {
+	luminance := 0.299*float64(bg.R) + 0.587*float64(bg.G) + 0.114*float64(bg.B)
+	if luminance > 140 {
+		return color.RGBA{0, 0, 0, 255}
 	}
+	return color.RGBA{255, 255, 255, 255}
+}
 
-	// Draw text in bright yellow
-	textColor := color.RGBA{255, 255, 0, 255}
-	for i := range text {
-		charX := x + i*charWidth
-		if charX >= img.Bounds().Max.X {
-			break
-		}
-
-		// Draw character as small rectangles
-		for dy := 2; dy < charHeight-2; dy++ {
-			for dx := 1; dx < charWidth-1; dx++ {
-				px := charX + dx
-				py := y + dy
-				if px >= 0 && py >= 0 && px < img.Bounds().Max.X && py < img.Bounds().Max.Y {
-					img.Set(px, py, textColor)
-				}
-			}
-		}
-	}
+// classPalette holds well distinguishable box colors
+var classPalette = []color.RGBA{
+	{0, 255, 0, 255},
+	{255, 56, 56, 255},
+	{255, 157, 151, 255},
+	{255, 112, 31, 255},
+	{255, 178, 29, 255},
+	{207, 210, 49, 255},
+	{72, 249, 10, 255},
+	{26, 147, 52, 255},
+	{0, 212, 187, 255},
+	{44, 153, 168, 255},
+	{0, 194, 255, 255},
+	{52, 69, 147, 255},
+	{100, 115, 255, 255},
+	{132, 56, 255, 255},
+	{203, 56, 255, 255},
+	{255, 149, 200, 255},
 }
 
-// TODO: support differences between classes.
-// getClassColor returns a color for a detection class
-func getClassColor(_ string) color.RGBA {
+// ClassColor returns the default color of a class, the same class always gets the same color
+func ClassColor(class string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(class))
+	return classPalette[h.Sum32()%uint32(len(classPalette))]
+}
 
-	// Default color for unknown classes
-	return color.RGBA{0, 255, 0, 255}
+// ParseHexColor parses a "#rrggbb" color
+func ParseHexColor(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	return color.RGBA{r, g, b, 255}, nil
 }
@@ -0,0 +1,154 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+)
+
+// benchmarkFrame returns a JPEG camera frame of the given size with a gradient, so it does not
+// encode to a trivially small image
+func benchmarkFrame(b *testing.B, width, height int) []byte {
+	b.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// benchmarkDetections returns n labeled boxes spread over the frame
+func benchmarkDetections(width, height, n int) ([]Detection, []BoxStyle) {
+	detections := make([]Detection, n)
+	styles := make([]BoxStyle, n)
+	for i := range detections {
+		x := (i * width / n) % (width - width/8)
+		y := (i * height / 3) % (height - height/4)
+		class := fmt.Sprintf("class_%d", i%3)
+		detections[i] = Detection{Class: class, Confidence: 0.87, X1: x, Y1: y, X2: x + width/8, Y2: y + height/4, TrackID: i + 1}
+		styles[i] = DefaultBoxStyle(class)
+		styles[i].Label = fmt.Sprintf("#%d 安全帽 87.0%% [helmet1]", i+1)
+	}
+	return detections, styles
+}
+
+func BenchmarkDrawDetectionImages(b *testing.B) {
+	for _, size := range []struct {
+		name          string
+		width, height int
+	}{
+		{"1080p", 1920, 1080},
+		{"4K", 3840, 2160},
+	} {
+		b.Run(size.name, func(b *testing.B) {
+			frame := benchmarkFrame(b, size.width, size.height)
+			detections, styles := benchmarkDetections(size.width, size.height, 5)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := DrawDetectionImages(frame, detections, styles, "GATE-01 2024-05-01 08:00:00"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// legacySetRectangle draws a box border pixel by pixel with img.Set, as drawing did before boxes
+// were filled row by row
+func legacySetRectangle(img *image.RGBA, x1, y1, x2, y2 int, col color.RGBA, thickness int) {
+	bounds := img.Bounds()
+	for t := 0; t < thickness; t++ {
+		for x := x1; x <= x2; x++ {
+			if x >= 0 && x < bounds.Max.X {
+				if y1+t >= 0 && y1+t < bounds.Max.Y {
+					img.Set(x, y1+t, col)
+				}
+				if y2-t >= 0 && y2-t < bounds.Max.Y {
+					img.Set(x, y2-t, col)
+				}
+			}
+		}
+	}
+	for t := 0; t < thickness; t++ {
+		for y := y1; y <= y2; y++ {
+			if y >= 0 && y < bounds.Max.Y {
+				if x1+t >= 0 && x1+t < bounds.Max.X {
+					img.Set(x1+t, y, col)
+				}
+				if x2-t >= 0 && x2-t < bounds.Max.X {
+					img.Set(x2-t, y, col)
+				}
+			}
+		}
+	}
+}
+
+// legacyDrawDetections decodes, draws and encodes one result image the way each of the two result
+// images was drawn before. Text uses the current drawer, the old one came from a dropped dependency.
+func legacyDrawDetections(imageData []byte, detections []Detection, styles []BoxStyle, banner string) ([]byte, error) {
+	img, err := jpeg.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	rgbaImg := image.NewRGBA(bounds)
+	draw.Draw(rgbaImg, bounds, img, bounds.Min, draw.Src)
+
+	for i, det := range detections {
+		legacySetRectangle(rgbaImg, det.X1, det.Y1, det.X2, det.Y2, styles[i].Color, styles[i].Thickness)
+	}
+	face := labelFace(labelFontSize(bounds.Dy()))
+	if banner != "" {
+		drawBanner(rgbaImg, face, banner)
+	}
+	for i, det := range detections {
+		if styles[i].Label != "" {
+			drawLabel(rgbaImg, face, det, styles[i])
+		}
+	}
+	return encodeJPEG(rgbaImg)
+}
+
+// BenchmarkDrawDetectionImagesBaseline is the drawing path DrawDetectionImages replaced: the frame
+// is decoded twice, once for the image without labels and once for the one with labels, and boxes are
+// set pixel by pixel. Compare with BenchmarkDrawDetectionImages.
+func BenchmarkDrawDetectionImagesBaseline(b *testing.B) {
+	for _, size := range []struct {
+		name          string
+		width, height int
+	}{
+		{"1080p", 1920, 1080},
+		{"4K", 3840, 2160},
+	} {
+		b.Run(size.name, func(b *testing.B) {
+			frame := benchmarkFrame(b, size.width, size.height)
+			detections, styles := benchmarkDetections(size.width, size.height, 5)
+			plainStyles := make([]BoxStyle, len(styles))
+			for i, style := range styles {
+				style.Label = ""
+				plainStyles[i] = style
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := legacyDrawDetections(frame, detections, plainStyles, "GATE-01 2024-05-01 08:00:00"); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := legacyDrawDetections(frame, detections, styles, "GATE-01 2024-05-01 08:00:00"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package common
+
+import (
+	_ "embed"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+)
+
+// labelFontData is the font of labels and banners, Unifont covers the CJK class and camera names
+//
+//go:embed fonts/unifont-15.1.05.otf
+var labelFontData []byte
+
+var labelFont *opentype.Font
+var labelFontOnce sync.Once
+
+// labelFace returns a face of the embedded font. Faces are not safe for concurrent use,
+// so every drawing call gets its own while the parsed font is shared.
+func labelFace(size float64) font.Face {
+	labelFontOnce.Do(func() {
+		parsed, err := opentype.Parse(labelFontData)
+		if err == nil {
+			labelFont = parsed
+		}
+	})
+	if labelFont == nil {
+		return basicfont.Face7x13
+	}
+	face, err := opentype.NewFace(labelFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return basicfont.Face7x13
+	}
+	return face
+}
+
+// labelFontSize returns the text size for an image height, multiples of 16 keep Unifont's pixel glyphs sharp
+func labelFontSize(imageHeight int) float64 {
+	if imageHeight > 1440 {
+		return 32
+	}
+	return 16
+}
@@ -0,0 +1,73 @@
+package common
+
+// IoU returns the intersection over union of two detection boxes
+func IoU(a, b Detection) float64 {
+	ix1 := max(a.X1, b.X1)
+	iy1 := max(a.Y1, b.Y1)
+	ix2 := min(a.X2, b.X2)
+	iy2 := min(a.Y2, b.Y2)
+	if ix2 <= ix1 || iy2 <= iy1 {
+		return 0
+	}
+
+	intersection := float64((ix2 - ix1) * (iy2 - iy1))
+	areaA := float64((a.X2 - a.X1) * (a.Y2 - a.Y1))
+	areaB := float64((b.X2 - b.X1) * (b.Y2 - b.Y1))
+	return intersection / (areaA + areaB - intersection)
+}
+
+// OverlapRatio returns the intersection of two boxes divided by the area of the smaller one,
+// so a small object inside a large one (e.g. a cigarette in a person box) scores 1
+func OverlapRatio(a, b Detection) float64 {
+	ix1 := max(a.X1, b.X1)
+	iy1 := max(a.Y1, b.Y1)
+	ix2 := min(a.X2, b.X2)
+	iy2 := min(a.Y2, b.Y2)
+	if ix2 <= ix1 || iy2 <= iy1 {
+		return 0
+	}
+
+	intersection := float64((ix2 - ix1) * (iy2 - iy1))
+	areaA := float64((a.X2 - a.X1) * (a.Y2 - a.Y1))
+	areaB := float64((b.X2 - b.X1) * (b.Y2 - b.Y1))
+	return intersection / min(areaA, areaB)
+}
+
+// Center returns the center point of a detection box
+func (d Detection) Center() (float64, float64) {
+	return float64(d.X1+d.X2) / 2, float64(d.Y1+d.Y2) / 2
+}
+
+// BottomCenter returns the bottom center point of a detection box,
+// which approximates the ground position of people and objects
+func (d Detection) BottomCenter() (float64, float64) {
+	return float64(d.X1+d.X2) / 2, float64(d.Y2)
+}
+
+// PointInPolygon reports whether point (x, y) lies inside the polygon using ray casting
+func PointInPolygon(x, y float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		xi, yi := polygon[i][0], polygon[i][1]
+		xj, yj := polygon[j][0], polygon[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// SideOfLine returns a positive value when (x, y) lies right of the line a->b
+// in image coordinates (y pointing down), negative when left and zero when on it
+func SideOfLine(ax, ay, bx, by, x, y float64) float64 {
+	return (bx-ax)*(y-ay) - (by-ay)*(x-ax)
+}
+
+// SegmentsIntersect reports whether segment p1-p2 intersects segment q1-q2
+func SegmentsIntersect(p1, p2, q1, q2 [2]float64) bool {
+	d1 := SideOfLine(q1[0], q1[1], q2[0], q2[1], p1[0], p1[1])
+	d2 := SideOfLine(q1[0], q1[1], q2[0], q2[1], p2[0], p2[1])
+	d3 := SideOfLine(p1[0], p1[1], p2[0], p2[1], q1[0], q1[1])
+	d4 := SideOfLine(p1[0], p1[1], p2[0], p2[1], q2[0], q2[1])
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIoU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Detection
+		want float64
+	}{
+		{"identical", Detection{X1: 0, Y1: 0, X2: 10, Y2: 10}, Detection{X1: 0, Y1: 0, X2: 10, Y2: 10}, 1},
+		{"half overlap", Detection{X1: 0, Y1: 0, X2: 10, Y2: 10}, Detection{X1: 5, Y1: 0, X2: 15, Y2: 10}, 50.0 / 150},
+		{"touching edges", Detection{X1: 0, Y1: 0, X2: 10, Y2: 10}, Detection{X1: 10, Y1: 0, X2: 20, Y2: 10}, 0},
+		{"apart", Detection{X1: 0, Y1: 0, X2: 10, Y2: 10}, Detection{X1: 20, Y1: 20, X2: 30, Y2: 30}, 0},
+	}
+	for _, tt := range tests {
+		if got := IoU(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: IoU = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOverlapRatio(t *testing.T) {
+	person := Detection{X1: 0, Y1: 0, X2: 100, Y2: 200}
+	tests := []struct {
+		name  string
+		other Detection
+		want  float64
+	}{
+		{"small box inside", Detection{X1: 40, Y1: 20, X2: 50, Y2: 30}, 1},
+		{"half outside", Detection{X1: 95, Y1: 20, X2: 105, Y2: 30}, 0.5},
+		{"outside", Detection{X1: 150, Y1: 20, X2: 160, Y2: 30}, 0},
+	}
+	for _, tt := range tests {
+		if got := OverlapRatio(person, tt.other); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: OverlapRatio = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	square := [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	// an L shape, the notch at the top right is outside
+	concave := [][2]float64{{0, 0}, {5, 0}, {5, 5}, {10, 5}, {10, 10}, {0, 10}}
+
+	tests := []struct {
+		name    string
+		polygon [][2]float64
+		x, y    float64
+		want    bool
+	}{
+		{"center", square, 5, 5, true},
+		{"outside left", square, -1, 5, false},
+		{"outside below", square, 5, 11, false},
+		// boundary points are half open: the left and top edges belong to the polygon, the right and
+		// bottom edges do not, so a point on the border of two adjacent zones is in exactly one of them
+		{"left edge", square, 0, 5, true},
+		{"top edge", square, 5, 0, true},
+		{"right edge", square, 10, 5, false},
+		{"bottom edge", square, 5, 10, false},
+		{"concave inside", concave, 2, 8, true},
+		{"concave notch", concave, 8, 2, false},
+		{"concave lower arm", concave, 8, 8, true},
+		{"degenerate", [][2]float64{{0, 0}, {10, 10}}, 5, 5, false},
+		{"empty", nil, 5, 5, false},
+	}
+	for _, tt := range tests {
+		if got := PointInPolygon(tt.x, tt.y, tt.polygon); got != tt.want {
+			t.Errorf("%s: PointInPolygon(%v, %v) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestPointInPolygonAdjacentZones(t *testing.T) {
+	left := [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	right := [][2]float64{{10, 0}, {20, 0}, {20, 10}, {10, 10}}
+	for _, y := range []float64{0, 2.5, 5, 9.9} {
+		if PointInPolygon(10, y, left) == PointInPolygon(10, y, right) {
+			t.Errorf("point 10,%v on the shared edge is in both or neither zone", y)
+		}
+	}
+}
+
+func TestSideOfLine(t *testing.T) {
+	// a horizontal line drawn left to right, image y points down
+	tests := []struct {
+		name string
+		x, y float64
+		want int // sign of the result
+	}{
+		{"below is right", 5, 5, 1},
+		{"above is left", 5, -5, -1},
+		{"on the line", 5, 0, 0},
+		{"on the extension", 20, 0, 0},
+	}
+	for _, tt := range tests {
+		got := SideOfLine(0, 0, 10, 0, tt.x, tt.y)
+		if sign(got) != tt.want {
+			t.Errorf("%s: SideOfLine = %v, want sign %d", tt.name, got, tt.want)
+		}
+	}
+	if sign(SideOfLine(10, 0, 0, 0, 5, 5)) != -1 {
+		t.Error("reversing the line must swap the sides")
+	}
+}
+
+// sign returns -1, 0 or 1
+func sign(v float64) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func TestSegmentsIntersect(t *testing.T) {
+	line := [2][2]float64{{0, 5}, {10, 5}}
+	tests := []struct {
+		name   string
+		p1, p2 [2]float64
+		want   bool
+	}{
+		{"crossing", [2]float64{5, 0}, [2]float64{5, 10}, true},
+		{"crossing diagonally", [2]float64{0, 0}, [2]float64{10, 10}, true},
+		{"same side", [2]float64{5, 0}, [2]float64{6, 4}, false},
+		{"past the line end", [2]float64{15, 0}, [2]float64{15, 10}, false},
+		{"parallel", [2]float64{0, 6}, [2]float64{10, 6}, false},
+		{"collinear", [2]float64{2, 5}, [2]float64{8, 5}, false},
+		// touching is not crossing, the rule engine waits until the track leaves the line
+		{"ends on the line", [2]float64{5, 0}, [2]float64{5, 5}, false},
+		{"starts on the line", [2]float64{5, 5}, [2]float64{5, 10}, false},
+		{"through the line end", [2]float64{10, 0}, [2]float64{10, 10}, false},
+	}
+	for _, tt := range tests {
+		if got := SegmentsIntersect(tt.p1, tt.p2, line[0], line[1]); got != tt.want {
+			t.Errorf("%s: SegmentsIntersect = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
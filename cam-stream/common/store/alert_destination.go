@@ -0,0 +1,275 @@
+package store
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Alert destination types
+const (
+	AlertDestinationHTTP = "http"
+	AlertDestinationMQTT = "mqtt"
+	AlertDestinationSMTP = "smtp"
+)
+
+// Alert modes of an alert receiver
+const (
+	AlertModePerBox   = "per_box"   // one alert per detection box, the legacy platform format
+	AlertModePerFrame = "per_frame" // one alert per frame and model listing all detections
+)
+
+// ValidateAlertMode checks an alert mode, empty means per box
+func ValidateAlertMode(mode string) error {
+	switch mode {
+	case "", AlertModePerBox, AlertModePerFrame:
+		return nil
+	}
+	return fmt.Errorf("unsupported alert_mode %q", mode)
+}
+
+// Validate checks alert mode and image options of the global alert server
+func (c *AlertServerConfig) Validate() error {
+	if err := ValidateAlertMode(c.Mode); err != nil {
+		return err
+	}
+	if err := c.Image.Validate(); err != nil {
+		return fmt.Errorf("image: %v", err)
+	}
+	return nil
+}
+
+// Supported MQTT broker URL schemes
+var mqttSchemes = map[string]bool{
+	"tcp": true, "mqtt": true, "ssl": true, "tls": true, "mqtts": true, "ws": true, "wss": true,
+}
+
+// MQTTOptions are the publish settings of an MQTT destination, the broker is the destination URL
+type MQTTOptions struct {
+	// Topic pattern, {camera}, {camera_id}, {model} and {server} are replaced per alert
+	Topic    string `json:"topic"`
+	QoS      byte   `json:"qos"`
+	Retain   bool   `json:"retain"`
+	ClientID string `json:"client_id,omitempty"` // defaults to cam-stream-<destination id>
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// TLS settings for ssl://, tls://, mqtts:// and wss:// brokers
+	CACertFile         string `json:"ca_cert_file,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// Validate checks topic pattern, QoS and TLS settings
+func (o *MQTTOptions) Validate() error {
+	if o.Topic == "" {
+		return fmt.Errorf("mqtt topic is required")
+	}
+	if strings.ContainsAny(o.Topic, "+#") {
+		return fmt.Errorf("mqtt topic %q must not contain wildcards", o.Topic)
+	}
+	if o.QoS > 2 {
+		return fmt.Errorf("mqtt qos must be 0, 1 or 2")
+	}
+	if o.CACertFile != "" {
+		if _, err := os.Stat(o.CACertFile); err != nil {
+			return fmt.Errorf("mqtt ca_cert_file: %v", err)
+		}
+	}
+	return nil
+}
+
+// EmailRoute sends matching alerts to additional recipients, empty filters match everything
+type EmailRoute struct {
+	Models  []string `json:"models,omitempty"`   // model types
+	Cameras []string `json:"cameras,omitempty"`  // camera IDs
+	RuleIDs []string `json:"rule_ids,omitempty"` // geometry or composite rule IDs
+	To      []string `json:"to"`
+}
+
+// SMTPOptions are the mail settings of an email destination, the server is the destination URL
+type SMTPOptions struct {
+	From     string       `json:"from"`
+	To       []string     `json:"to,omitempty"`     // recipients of every alert
+	Routes   []EmailRoute `json:"routes,omitempty"` // per model, camera or rule recipients
+	Username string       `json:"username,omitempty"`
+	Password string       `json:"password,omitempty"`
+	// Upgrade smtp:// connections with STARTTLS, smtps:// always uses TLS
+	StartTLS           bool `json:"starttls"`
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// text/template subject and plain text body, empty means the built-in ones
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+	// Models alerted immediately, alerts of other models go to the digest if enabled; empty means all are critical
+	CriticalModels []string `json:"critical_models,omitempty"`
+	// Interval of the summary mail of non-critical alerts, 0 disables the digest
+	DigestIntervalMinutes int `json:"digest_interval_minutes,omitempty"`
+	// Maximum immediate mails per hour, 0 means unlimited; excess alerts go to the digest or are dropped
+	RateLimitPerHour int `json:"rate_limit_per_hour,omitempty"`
+}
+
+// IsCritical reports whether alerts of the model are mailed immediately
+func (o *SMTPOptions) IsCritical(modelType string) bool {
+	if len(o.CriticalModels) == 0 {
+		return true
+	}
+	for _, model := range o.CriticalModels {
+		if model == modelType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks addresses and limits
+func (o *SMTPOptions) Validate() error {
+	if _, err := mail.ParseAddress(o.From); err != nil {
+		return fmt.Errorf("invalid smtp from address %q", o.From)
+	}
+	if len(o.To) == 0 && len(o.Routes) == 0 {
+		return fmt.Errorf("smtp destination needs recipients or routes")
+	}
+	addresses := append([]string{}, o.To...)
+	for _, route := range o.Routes {
+		if len(route.To) == 0 {
+			return fmt.Errorf("smtp route without recipients")
+		}
+		addresses = append(addresses, route.To...)
+	}
+	for _, address := range addresses {
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid recipient %q", address)
+		}
+	}
+	if o.DigestIntervalMinutes < 0 || o.RateLimitPerHour < 0 {
+		return fmt.Errorf("digest_interval_minutes and rate_limit_per_hour must not be negative")
+	}
+	return nil
+}
+
+// AlertDestination is an additional alert receiver next to the global alert server
+type AlertDestination struct {
+	ID      string             `json:"id"`
+	Name    string             `json:"name"`
+	Type    string             `json:"type"` // "http", "mqtt" or "smtp"
+	Enabled bool               `json:"enabled"`
+	URL     string             `json:"url"`
+	Headers map[string]string  `json:"headers,omitempty"`    // Extra HTTP headers, e.g. authorization
+	MQTT    *MQTTOptions       `json:"mqtt,omitempty"`       // MQTT publish settings, URL is the broker
+	SMTP    *SMTPOptions       `json:"smtp,omitempty"`       // Email settings, URL is the mail server
+	Image   *AlertImageOptions `json:"image,omitempty"`      // Image sent with alerts, default is the full frame
+	Mode    string             `json:"alert_mode,omitempty"` // "per_box" (default) or "per_frame"
+	// Go text/template rendering the JSON payload, empty means the default alert request format
+	Template string `json:"template,omitempty"`
+	// Base URL of this service as seen by the receiver, used to build image URLs for templates
+	ImageBaseURL string    `json:"image_base_url,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// GetType returns the destination type or the default one
+func (d *AlertDestination) GetType() string {
+	if d.Type == "" {
+		return AlertDestinationHTTP
+	}
+	return d.Type
+}
+
+// Validate checks type and connection settings, templates are checked by the alert service
+func (d *AlertDestination) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("destination name is required")
+	}
+	switch d.GetType() {
+	case AlertDestinationHTTP:
+		u, err := url.Parse(d.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid http url %q", d.URL)
+		}
+	case AlertDestinationMQTT:
+		u, err := url.Parse(d.URL)
+		if err != nil || !mqttSchemes[u.Scheme] || u.Host == "" {
+			return fmt.Errorf("invalid mqtt broker url %q", d.URL)
+		}
+		if d.MQTT == nil {
+			return fmt.Errorf("mqtt settings are required")
+		}
+		if err := d.MQTT.Validate(); err != nil {
+			return err
+		}
+	case AlertDestinationSMTP:
+		u, err := url.Parse(d.URL)
+		if err != nil || (u.Scheme != "smtp" && u.Scheme != "smtps") || u.Host == "" {
+			return fmt.Errorf("invalid smtp server url %q", d.URL)
+		}
+		if d.SMTP == nil {
+			return fmt.Errorf("smtp settings are required")
+		}
+		if err := d.SMTP.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported destination type %q", d.Type)
+	}
+	if err := ValidateAlertMode(d.Mode); err != nil {
+		return err
+	}
+	if err := d.Image.Validate(); err != nil {
+		return fmt.Errorf("image: %v", err)
+	}
+	if d.ImageBaseURL != "" {
+		if u, err := url.Parse(d.ImageBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid image_base_url %q", d.ImageBaseURL)
+		}
+	}
+	return nil
+}
+
+// SafeGetAlertDestination returns the alert destination with the given ID
+func SafeGetAlertDestination(id string) (*AlertDestination, bool) {
+	dataStoreMutex.RLock()
+	defer dataStoreMutex.RUnlock()
+	destination, exists := Data.AlertDestinations[id]
+	return destination, exists
+}
+
+// MaskedSecret replaces passwords and header values in API responses. Sending it back keeps the stored value.
+const MaskedSecret = "********"
+
+// Masked returns a copy of the destination with header values and passwords replaced by MaskedSecret
+func (d *AlertDestination) Masked() *AlertDestination {
+	masked := *d
+	if len(d.Headers) > 0 {
+		masked.Headers = make(map[string]string, len(d.Headers))
+		for name := range d.Headers {
+			masked.Headers[name] = MaskedSecret
+		}
+	}
+	if d.MQTT != nil && d.MQTT.Password != "" {
+		mqtt := *d.MQTT
+		mqtt.Password = MaskedSecret
+		masked.MQTT = &mqtt
+	}
+	if d.SMTP != nil && d.SMTP.Password != "" {
+		smtp := *d.SMTP
+		smtp.Password = MaskedSecret
+		masked.SMTP = &smtp
+	}
+	return &masked
+}
+
+// RestoreSecrets replaces masked header values and passwords with those of the saved destination
+func (d *AlertDestination) RestoreSecrets(saved *AlertDestination) {
+	for name, value := range d.Headers {
+		if value == MaskedSecret {
+			d.Headers[name] = saved.Headers[name]
+		}
+	}
+	if d.MQTT != nil && d.MQTT.Password == MaskedSecret && saved.MQTT != nil {
+		d.MQTT.Password = saved.MQTT.Password
+	}
+	if d.SMTP != nil && d.SMTP.Password == MaskedSecret && saved.SMTP != nil {
+		d.SMTP.Password = saved.SMTP.Password
+	}
+}
@@ -0,0 +1,83 @@
+package store
+
+import "fmt"
+
+// Alert image modes
+const (
+	AlertImageFull      = "full"      // the whole annotated frame
+	AlertImageCrop      = "crop"      // a padded crop around the alert box
+	AlertImageThumbnail = "thumbnail" // the whole frame scaled down
+	AlertImageNone      = "none"      // no image
+)
+
+// Defaults of the alert image options
+const (
+	DefaultAlertImageQuality       = 85
+	DefaultAlertImageCropPadding   = 0.2
+	DefaultAlertThumbnailDimension = 320
+)
+
+// AlertImageOptions controls the image sent with alerts to one destination
+type AlertImageOptions struct {
+	Mode         string  `json:"mode,omitempty"`          // "full" (default), "crop", "thumbnail" or "none"
+	CropPadding  float64 `json:"crop_padding,omitempty"`  // Crop: padding around the box relative to its size, default 0.2
+	MaxDimension int     `json:"max_dimension,omitempty"` // Longest side in pixels, 0 keeps the size (thumbnail default 320)
+	Quality      int     `json:"quality,omitempty"`       // JPEG quality 1-100, default 85
+}
+
+// GetMode returns the image mode, nil options send the full frame
+func (o *AlertImageOptions) GetMode() string {
+	if o == nil || o.Mode == "" {
+		return AlertImageFull
+	}
+	return o.Mode
+}
+
+// GetCropPadding returns the crop padding or the default one
+func (o *AlertImageOptions) GetCropPadding() float64 {
+	if o == nil || o.CropPadding <= 0 {
+		return DefaultAlertImageCropPadding
+	}
+	return o.CropPadding
+}
+
+// GetMaxDimension returns the maximum image side, 0 means unlimited
+func (o *AlertImageOptions) GetMaxDimension() int {
+	if o == nil {
+		return 0
+	}
+	if o.MaxDimension <= 0 && o.GetMode() == AlertImageThumbnail {
+		return DefaultAlertThumbnailDimension
+	}
+	return o.MaxDimension
+}
+
+// GetQuality returns the JPEG quality or the default one
+func (o *AlertImageOptions) GetQuality() int {
+	if o == nil || o.Quality <= 0 {
+		return DefaultAlertImageQuality
+	}
+	return o.Quality
+}
+
+// Validate checks mode and ranges
+func (o *AlertImageOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.Mode {
+	case "", AlertImageFull, AlertImageCrop, AlertImageThumbnail, AlertImageNone:
+	default:
+		return fmt.Errorf("unsupported image mode %q", o.Mode)
+	}
+	if o.CropPadding < 0 || o.CropPadding > 5 {
+		return fmt.Errorf("crop_padding must be between 0 and 5")
+	}
+	if o.MaxDimension < 0 {
+		return fmt.Errorf("max_dimension must not be negative")
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	return nil
+}
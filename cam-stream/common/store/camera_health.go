@@ -0,0 +1,77 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+// MaxStreamChangeRecords limits the stream change history kept per camera
+const MaxStreamChangeRecords = 20
+
+// StreamChangeRecord records a detected change of stream parameters
+type StreamChangeRecord struct {
+	DetectedAt time.Time `json:"detected_at"`
+	From       string    `json:"from"` // e.g. "1920x1080 h264"
+	To         string    `json:"to"`
+}
+
+// CameraHealthState represents the runtime stream health of a camera (not persisted)
+type CameraHealthState struct {
+	CameraID      string               `json:"camera_id"`
+	Connected     bool                 `json:"connected"`
+	Width         int                  `json:"width"`
+	Height        int                  `json:"height"`
+	Codec         string               `json:"codec"`
+	FPS           float64              `json:"fps"`
+	LastFrameAt   time.Time            `json:"last_frame_at"`
+	Reconnects    int                  `json:"reconnects"`
+	Republishing  bool                 `json:"republishing"`
+	LastError     string               `json:"last_error,omitempty"`
+	StreamChanges []StreamChangeRecord `json:"stream_changes,omitempty"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// Runtime-only camera health tracking (not persisted)
+var CameraHealthStates = make(map[string]*CameraHealthState)
+var cameraHealthMutex sync.RWMutex
+
+// SafeUpdateCameraHealth runs fn on the health state of a camera, creating it if needed
+func SafeUpdateCameraHealth(cameraID string, fn func(health *CameraHealthState)) {
+	cameraHealthMutex.Lock()
+	defer cameraHealthMutex.Unlock()
+	health, exists := CameraHealthStates[cameraID]
+	if !exists {
+		health = &CameraHealthState{CameraID: cameraID}
+		CameraHealthStates[cameraID] = health
+	}
+	fn(health)
+	health.UpdatedAt = time.Now()
+}
+
+// SafeGetCameraHealth returns a copy of the health state of a camera
+func SafeGetCameraHealth(cameraID string) (CameraHealthState, bool) {
+	cameraHealthMutex.RLock()
+	defer cameraHealthMutex.RUnlock()
+	health, exists := CameraHealthStates[cameraID]
+	if !exists {
+		return CameraHealthState{}, false
+	}
+	healthCopy := *health
+	healthCopy.StreamChanges = append([]StreamChangeRecord(nil), health.StreamChanges...)
+	return healthCopy, true
+}
+
+// SafeDeleteCameraHealth removes the health state of a deleted camera
+func SafeDeleteCameraHealth(cameraID string) {
+	cameraHealthMutex.Lock()
+	defer cameraHealthMutex.Unlock()
+	delete(CameraHealthStates, cameraID)
+}
+
+// AddStreamChange appends a change record, keeping only the latest ones
+func (h *CameraHealthState) AddStreamChange(record StreamChangeRecord) {
+	h.StreamChanges = append(h.StreamChanges, record)
+	if len(h.StreamChanges) > MaxStreamChangeRecords {
+		h.StreamChanges = h.StreamChanges[len(h.StreamChanges)-MaxStreamChangeRecords:]
+	}
+}
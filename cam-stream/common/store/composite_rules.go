@@ -0,0 +1,133 @@
+package store
+
+import "fmt"
+
+// Composite condition operators
+const (
+	CompositeOpAnd      = "and"      // all conditions hold
+	CompositeOpOr       = "or"       // at least one condition holds
+	CompositeOpNot      = "not"      // the single condition does not hold
+	CompositeOpDetected = "detected" // at least min_count matching detections in one result
+	CompositeOpOverlap  = "overlap"  // a detection matching select overlaps one matching with
+)
+
+// DefaultCompositeWindowSeconds is the time window used when a rule does not set one
+const DefaultCompositeWindowSeconds = 5.0
+
+// DetectionSelector selects detections from the results of a camera's bindings
+type DetectionSelector struct {
+	ServerID      string   `json:"server_id,omitempty"`  // empty means any server
+	ModelType     string   `json:"model_type,omitempty"` // empty means any model
+	Classes       []string `json:"classes,omitempty"`    // empty means any class
+	MinConfidence float64  `json:"min_confidence,omitempty"`
+}
+
+// Matches reports whether a detection of the given server and model is selected
+func (s *DetectionSelector) Matches(serverID, modelType, class string, confidence float64) bool {
+	if s.ServerID != "" && s.ServerID != serverID {
+		return false
+	}
+	if s.ModelType != "" && s.ModelType != modelType {
+		return false
+	}
+	if confidence < s.MinConfidence {
+		return false
+	}
+	if len(s.Classes) == 0 {
+		return true
+	}
+	for _, c := range s.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// CompositeCondition is a node of a composite rule expression
+type CompositeCondition struct {
+	Op         string               `json:"op"`
+	Conditions []CompositeCondition `json:"conditions,omitempty"`  // and, or, not
+	Select     *DetectionSelector   `json:"select,omitempty"`      // detected, overlap
+	MinCount   int                  `json:"min_count,omitempty"`   // detected: default 1
+	With       *DetectionSelector   `json:"with,omitempty"`        // overlap: the other detections
+	MinOverlap float64              `json:"min_overlap,omitempty"` // overlap: intersection over the smaller box, 0 means any
+}
+
+// GetMinCount returns the configured minimum count or the default one
+func (c *CompositeCondition) GetMinCount() int {
+	if c.MinCount <= 0 {
+		return 1
+	}
+	return c.MinCount
+}
+
+// Validate checks the condition tree
+func (c *CompositeCondition) Validate() error {
+	switch c.Op {
+	case CompositeOpAnd, CompositeOpOr:
+		if len(c.Conditions) == 0 {
+			return fmt.Errorf("%q needs at least one condition", c.Op)
+		}
+	case CompositeOpNot:
+		if len(c.Conditions) != 1 {
+			return fmt.Errorf("%q needs exactly one condition", c.Op)
+		}
+	case CompositeOpDetected:
+		if c.Select == nil {
+			return fmt.Errorf("%q needs a selector", c.Op)
+		}
+		if c.MinCount < 0 {
+			return fmt.Errorf("min_count must not be negative")
+		}
+	case CompositeOpOverlap:
+		if c.Select == nil || c.With == nil {
+			return fmt.Errorf("%q needs select and with selectors", c.Op)
+		}
+		if c.MinOverlap < 0 || c.MinOverlap > 1 {
+			return fmt.Errorf("min_overlap must be between 0 and 1")
+		}
+	default:
+		return fmt.Errorf("unsupported operator %q", c.Op)
+	}
+
+	for i := range c.Conditions {
+		if err := c.Conditions[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompositeRule combines the results of several bindings of a camera within a time window
+type CompositeRule struct {
+	ID              string             `json:"id"`
+	Name            string             `json:"name"`
+	Enabled         bool               `json:"enabled"`
+	WindowSeconds   float64            `json:"window_seconds,omitempty"`   // default 5
+	CooldownSeconds float64            `json:"cooldown_seconds,omitempty"` // minimum time between two alerts
+	Message         string             `json:"message,omitempty"`          // alert message, defaults to the rule name
+	Condition       CompositeCondition `json:"condition"`
+}
+
+// GetWindowSeconds returns the configured time window or the default one
+func (r *CompositeRule) GetWindowSeconds() float64 {
+	if r.WindowSeconds <= 0 {
+		return DefaultCompositeWindowSeconds
+	}
+	return r.WindowSeconds
+}
+
+// Validate checks the rule parameters and expression
+func (r *CompositeRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	if r.WindowSeconds < 0 || r.CooldownSeconds < 0 {
+		return fmt.Errorf("rule %q: window_seconds and cooldown_seconds must not be negative", r.Name)
+	}
+	if err := r.Condition.Validate(); err != nil {
+		return fmt.Errorf("rule %q: %v", r.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import "fmt"
+
+// EventLifecycleConfig configures how detections of a binding are grouped into events
+type EventLifecycleConfig struct {
+	Enabled               bool    `json:"enabled"`
+	ConfirmFrames         int     `json:"confirm_frames,omitempty"`          // Frames with detections needed to open an event
+	ConfirmSeconds        float64 `json:"confirm_seconds,omitempty"`         // Time the condition must persist to open an event
+	UpdateIntervalSeconds float64 `json:"update_interval_seconds,omitempty"` // Interval of ongoing notifications, 0 disables them
+	ResolveAfterSeconds   float64 `json:"resolve_after_seconds,omitempty"`   // Absence period after which an event is resolved
+}
+
+const (
+	DefaultEventConfirmFrames       = 3
+	DefaultEventResolveAfterSeconds = 30
+)
+
+// IsEnabled reports whether lifecycle events are enabled, nil-safe
+func (c *EventLifecycleConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// GetConfirmFrames returns the configured confirmation frames or the default
+func (c *EventLifecycleConfig) GetConfirmFrames() int {
+	if c.ConfirmFrames <= 0 {
+		return DefaultEventConfirmFrames
+	}
+	return c.ConfirmFrames
+}
+
+// GetResolveAfterSeconds returns the configured absence period or the default
+func (c *EventLifecycleConfig) GetResolveAfterSeconds() float64 {
+	if c.ResolveAfterSeconds <= 0 {
+		return DefaultEventResolveAfterSeconds
+	}
+	return c.ResolveAfterSeconds
+}
+
+// Validate checks the lifecycle parameters
+func (c *EventLifecycleConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ConfirmFrames < 0 || c.ConfirmSeconds < 0 || c.UpdateIntervalSeconds < 0 || c.ResolveAfterSeconds < 0 {
+		return fmt.Errorf("lifecycle settings must not be negative")
+	}
+	if c.UpdateIntervalSeconds > 0 && c.UpdateIntervalSeconds < 1 {
+		return fmt.Errorf("update_interval_seconds must be at least 1")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultFFmpegTransport      = "tcp"
+	DefaultFFmpegTimeoutSeconds = 10
+	MaxFFmpegTimeoutSeconds     = 120
+	MaxFFmpegOutputDimension    = 7680
+)
+
+// allowedFFmpegTransports lists values accepted for -rtsp_transport
+var allowedFFmpegTransports = map[string]bool{
+	"tcp":           true,
+	"udp":           true,
+	"udp_multicast": true,
+	"http":          true,
+}
+
+// ffmpegInputOption describes an input option users may pass to ffmpeg
+type ffmpegInputOption struct {
+	expectsValue bool
+	// Option of the ffmpeg command line rather than of the demuxer, the ffprobe and libav probes do not know it
+	cliOnly bool
+}
+
+// allowedFFmpegInputOptions lists input options users may pass to ffmpeg
+var allowedFFmpegInputOptions = map[string]ffmpegInputOption{
+	"-fflags":                      {expectsValue: true},
+	"-flags":                       {expectsValue: true},
+	"-analyzeduration":             {expectsValue: true},
+	"-probesize":                   {expectsValue: true},
+	"-max_delay":                   {expectsValue: true},
+	"-reorder_queue_size":          {expectsValue: true},
+	"-buffer_size":                 {expectsValue: true},
+	"-rtsp_flags":                  {expectsValue: true},
+	"-user_agent":                  {expectsValue: true},
+	"-allowed_media_types":         {expectsValue: true},
+	"-use_wallclock_as_timestamps": {expectsValue: true},
+	"-hwaccel":                     {expectsValue: true, cliOnly: true},
+	"-re":                          {cliOnly: true},
+}
+
+// FFmpegOptions holds advanced per-camera FFmpeg input settings
+type FFmpegOptions struct {
+	Transport      string   `json:"transport,omitempty"`       // "tcp" (default), "udp", "udp_multicast" or "http"
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // Socket timeout, 0 means default
+	OutputWidth    int      `json:"output_width,omitempty"`    // Output frame width, 0 keeps source/aspect ratio
+	OutputHeight   int      `json:"output_height,omitempty"`   // Output frame height, 0 keeps source/aspect ratio
+	MaxDimension   int      `json:"max_dimension,omitempty"`   // Downscale so the longest side does not exceed this
+	InputArgs      []string `json:"input_args,omitempty"`      // Extra input options, e.g. ["-fflags", "nobuffer"]
+}
+
+// Equal reports whether both options start the same stream, nil equals empty options
+func (o *FFmpegOptions) Equal(other *FFmpegOptions) bool {
+	var a, b FFmpegOptions
+	if o != nil {
+		a = *o
+	}
+	if other != nil {
+		b = *other
+	}
+	if len(a.InputArgs) != len(b.InputArgs) {
+		return false
+	}
+	for i := range a.InputArgs {
+		if a.InputArgs[i] != b.InputArgs[i] {
+			return false
+		}
+	}
+	return a.Transport == b.Transport && a.TimeoutSeconds == b.TimeoutSeconds && a.OutputWidth == b.OutputWidth &&
+		a.OutputHeight == b.OutputHeight && a.MaxDimension == b.MaxDimension
+}
+
+// GetTransport returns the configured transport or the default one
+func (o *FFmpegOptions) GetTransport() string {
+	if o == nil || o.Transport == "" {
+		return DefaultFFmpegTransport
+	}
+	return o.Transport
+}
+
+// GetTimeoutSeconds returns the configured socket timeout or the default one
+func (o *FFmpegOptions) GetTimeoutSeconds() int {
+	if o == nil || o.TimeoutSeconds <= 0 {
+		return DefaultFFmpegTimeoutSeconds
+	}
+	return o.TimeoutSeconds
+}
+
+// GetInputArgs returns the extra input options, nil-safe
+func (o *FFmpegOptions) GetInputArgs() []string {
+	if o == nil {
+		return nil
+	}
+	return o.InputArgs
+}
+
+// GetProbeInputArgs returns the extra input options understood by stream probes and the
+// ffmpeg command line options left out, nil-safe
+func (o *FFmpegOptions) GetProbeInputArgs() (args []string, ignored []string) {
+	inputArgs := o.GetInputArgs()
+	for i := 0; i < len(inputArgs); i++ {
+		option := allowedFFmpegInputOptions[inputArgs[i]]
+		n := 1
+		if option.expectsValue && i+1 < len(inputArgs) {
+			n = 2
+		}
+		if option.cliOnly {
+			ignored = append(ignored, inputArgs[i:i+n]...)
+		} else {
+			args = append(args, inputArgs[i:i+n]...)
+		}
+		i += n - 1
+	}
+	return args, ignored
+}
+
+// Validate checks that all options are within supported ranges and
+// that extra input options only use allowlisted flags.
+func (o *FFmpegOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	if o.Transport != "" && !allowedFFmpegTransports[o.Transport] {
+		return fmt.Errorf("unsupported transport %q", o.Transport)
+	}
+	if o.TimeoutSeconds < 0 || o.TimeoutSeconds > MaxFFmpegTimeoutSeconds {
+		return fmt.Errorf("timeout_seconds must be between 0 and %d", MaxFFmpegTimeoutSeconds)
+	}
+
+	dimensions := []struct {
+		name  string
+		value int
+	}{
+		{"output_width", o.OutputWidth},
+		{"output_height", o.OutputHeight},
+		{"max_dimension", o.MaxDimension},
+	}
+	for _, dimension := range dimensions {
+		name, value := dimension.name, dimension.value
+		if value < 0 || value > MaxFFmpegOutputDimension {
+			return fmt.Errorf("%s must be between 0 and %d", name, MaxFFmpegOutputDimension)
+		}
+		if value%2 != 0 {
+			return fmt.Errorf("%s must be an even number", name)
+		}
+	}
+
+	for i := 0; i < len(o.InputArgs); i++ {
+		option := o.InputArgs[i]
+		spec, allowed := allowedFFmpegInputOptions[option]
+		if !allowed {
+			return fmt.Errorf("input option %q is not allowed", option)
+		}
+		if !spec.expectsValue {
+			continue
+		}
+		if i+1 >= len(o.InputArgs) || strings.HasPrefix(o.InputArgs[i+1], "-") {
+			return fmt.Errorf("input option %q requires a value", option)
+		}
+		i++
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import "fmt"
+
+// Geometry rule types
+const (
+	GeometryRuleZone      = "zone"      // intrusion, or loitering when min_dwell_seconds > 0
+	GeometryRuleLine      = "line"      // directed tripwire
+	GeometryRuleOccupancy = "occupancy" // more than max_count objects inside a zone
+)
+
+// Line crossing directions, left and right as seen looking from the first to the second point
+const (
+	LineDirectionAny         = "any"
+	LineDirectionLeftToRight = "left_to_right"
+	LineDirectionRightToLeft = "right_to_left"
+)
+
+// Point is a normalized image coordinate (0.0-1.0)
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// GeometryRule is a per-camera rule evaluated against tracked detections
+type GeometryRule struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"` // "zone", "line" or "occupancy"
+	Enabled         bool     `json:"enabled"`
+	ServerID        string   `json:"server_id,omitempty"`         // Only evaluate results of this server, empty means all
+	Classes         []string `json:"classes,omitempty"`           // Only evaluate these classes, empty means all
+	Polygon         []Point  `json:"polygon,omitempty"`           // Zone and occupancy area
+	MinDwellSeconds float64  `json:"min_dwell_seconds,omitempty"` // Zone: time inside before firing
+	MaxCount        int      `json:"max_count,omitempty"`         // Occupancy: fire when more objects are inside
+	Line            []Point  `json:"line,omitempty"`              // Line: exactly two points
+	Direction       string   `json:"direction,omitempty"`         // Line: crossing direction, empty means any
+}
+
+// MatchesClass reports whether the rule applies to the given class
+func (r *GeometryRule) MatchesClass(class string) bool {
+	if len(r.Classes) == 0 {
+		return true
+	}
+	for _, c := range r.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the rule geometry and parameters
+func (r *GeometryRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+
+	switch r.Type {
+	case GeometryRuleZone, GeometryRuleOccupancy:
+		if len(r.Polygon) < 3 {
+			return fmt.Errorf("rule %q: polygon needs at least 3 points", r.Name)
+		}
+		if err := validatePoints(r.Polygon); err != nil {
+			return fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+		if r.MinDwellSeconds < 0 {
+			return fmt.Errorf("rule %q: min_dwell_seconds must not be negative", r.Name)
+		}
+		if r.Type == GeometryRuleOccupancy && r.MaxCount < 0 {
+			return fmt.Errorf("rule %q: max_count must not be negative", r.Name)
+		}
+	case GeometryRuleLine:
+		if len(r.Line) != 2 {
+			return fmt.Errorf("rule %q: line needs exactly 2 points", r.Name)
+		}
+		if err := validatePoints(r.Line); err != nil {
+			return fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+		if r.Line[0] == r.Line[1] {
+			return fmt.Errorf("rule %q: line points must differ", r.Name)
+		}
+		switch r.Direction {
+		case "", LineDirectionAny, LineDirectionLeftToRight, LineDirectionRightToLeft:
+		default:
+			return fmt.Errorf("rule %q: unsupported direction %q", r.Name, r.Direction)
+		}
+	default:
+		return fmt.Errorf("rule %q: unsupported type %q", r.Name, r.Type)
+	}
+
+	return nil
+}
+
+// validatePoints checks that all points are normalized coordinates
+func validatePoints(points []Point) error {
+	for _, p := range points {
+		if p.X < 0 || p.X > 1 || p.Y < 0 || p.Y > 1 {
+			return fmt.Errorf("point (%.3f, %.3f) is outside the normalized range 0-1", p.X, p.Y)
+		}
+	}
+	return nil
+}
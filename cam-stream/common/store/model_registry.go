@@ -0,0 +1,249 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Score selection strategies of a model, which score of an inference result is the detection confidence
+const (
+	ScoreDetection      = "score"     // "score" of the result, results without a box are skipped
+	ScoreClassification = "cls_score" // "cls_score" of detect-then-classify models, needs positive det and cls scores
+)
+
+// modelNamePattern restricts model names, they are part of inference server IDs and URLs
+var modelNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// colorPattern matches "#rrggbb" colors
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// maxStyleThickness bounds the box line width
+const maxStyleThickness = 20
+
+// DrawStyle is how detections are drawn on result images, empty fields fall back to the
+// model's style and then to the defaults
+type DrawStyle struct {
+	Color     string `json:"color,omitempty"`     // "#rrggbb", default is a fixed color per class
+	Thickness int    `json:"thickness,omitempty"` // Box line width in pixels, default 3
+	// Label of debug images with placeholders {class}, {model}, {confidence}, {track} and {server},
+	// default "{track} {class} {confidence} {server}"
+	LabelFormat string `json:"label_format,omitempty"`
+}
+
+// Validate checks color and thickness
+func (s *DrawStyle) Validate() error {
+	if s == nil {
+		return nil
+	}
+	if s.Color != "" && !colorPattern.MatchString(s.Color) {
+		return fmt.Errorf("invalid color %q, expected #rrggbb", s.Color)
+	}
+	if s.Thickness < 0 || s.Thickness > maxStyleThickness {
+		return fmt.Errorf("thickness must be between 0 (default) and %d", maxStyleThickness)
+	}
+	return nil
+}
+
+// Merge returns the style with empty fields taken from fallback
+func (s *DrawStyle) Merge(fallback *DrawStyle) DrawStyle {
+	var merged DrawStyle
+	if fallback != nil {
+		merged = *fallback
+	}
+	if s == nil {
+		return merged
+	}
+	if s.Color != "" {
+		merged.Color = s.Color
+	}
+	if s.Thickness > 0 {
+		merged.Thickness = s.Thickness
+	}
+	if s.LabelFormat != "" {
+		merged.LabelFormat = s.LabelFormat
+	}
+	return merged
+}
+
+// ModelClass is a class reported by a model and its index in exported datasets
+type ModelClass struct {
+	Name        string     `json:"name"`
+	Index       int        `json:"index"`
+	DisplayName string     `json:"display_name,omitempty"` // Name in labels, e.g. "安全帽"
+	Style       *DrawStyle `json:"style,omitempty"`        // Overrides the model's style
+}
+
+// ModelDefinition describes a model type that inference servers can serve
+type ModelDefinition struct {
+	Name             string       `json:"name"`                      // Model type of inference servers, e.g. "helmet"
+	DisplayName      string       `json:"display_name"`              // Name shown to operators, e.g. "安全帽"
+	Aliases          []string     `json:"aliases,omitempty"`         // Other display names, e.g. in camera spreadsheets
+	Classes          []ModelClass `json:"classes"`                   // Reported classes, the first one is used for unknown class names
+	IgnoredClasses   []string     `json:"ignored_classes,omitempty"` // Classes dropped from results, e.g. fire boxes of a shared smoke/fire model
+	ScoreStrategy    string       `json:"score_strategy,omitempty"`  // "score" (default) or "cls_score"
+	DefaultThreshold float64      `json:"default_threshold"`         // Threshold proposed for new bindings
+	Style            *DrawStyle   `json:"style,omitempty"`           // How detections of all classes are drawn
+	Description      string       `json:"description,omitempty"`     // Optional description
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// GetScoreStrategy returns the score selection strategy or the default one
+func (m *ModelDefinition) GetScoreStrategy() string {
+	if m == nil || m.ScoreStrategy == "" {
+		return ScoreDetection
+	}
+	return m.ScoreStrategy
+}
+
+// ClassFor returns the class of a reported class name, nil if the model does not list it
+func (m *ModelDefinition) ClassFor(className string) *ModelClass {
+	if m == nil {
+		return nil
+	}
+	for i := range m.Classes {
+		if m.Classes[i].Name == className {
+			return &m.Classes[i]
+		}
+	}
+	return nil
+}
+
+// ClassIndex returns the dataset index of a class, -1 if the model does not list it
+func (m *ModelDefinition) ClassIndex(className string) int {
+	if class := m.ClassFor(className); class != nil {
+		return class.Index
+	}
+	return -1
+}
+
+// IsIgnoredClass reports whether detections of a class are dropped
+func (m *ModelDefinition) IsIgnoredClass(className string) bool {
+	if m == nil {
+		return false
+	}
+	for _, ignored := range m.IgnoredClasses {
+		if ignored == className {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks name, classes, strategy and threshold
+func (m *ModelDefinition) Validate() error {
+	if !modelNamePattern.MatchString(m.Name) {
+		return fmt.Errorf("model name %q must only contain lowercase letters, digits and underscores", m.Name)
+	}
+	if m.DisplayName == "" {
+		return fmt.Errorf("display name is required")
+	}
+	seen := make(map[string]bool)
+	indices := make(map[int]string)
+	for _, class := range m.Classes {
+		if class.Name == "" {
+			return fmt.Errorf("class name is required")
+		}
+		if seen[class.Name] {
+			return fmt.Errorf("class %s is listed twice", class.Name)
+		}
+		if class.Index < 0 {
+			return fmt.Errorf("class %s has a negative index", class.Name)
+		}
+		if other, exists := indices[class.Index]; exists {
+			return fmt.Errorf("classes %s and %s have the same index %d", other, class.Name, class.Index)
+		}
+		indices[class.Index] = class.Name
+		if err := class.Style.Validate(); err != nil {
+			return fmt.Errorf("class %s style: %v", class.Name, err)
+		}
+		seen[class.Name] = true
+	}
+	switch m.ScoreStrategy {
+	case "", ScoreDetection, ScoreClassification:
+	default:
+		return fmt.Errorf("unsupported score strategy %q", m.ScoreStrategy)
+	}
+	if m.DefaultThreshold < 0 || m.DefaultThreshold > 1 {
+		return fmt.Errorf("default threshold must be between 0 and 1")
+	}
+	if err := m.Style.Validate(); err != nil {
+		return fmt.Errorf("style: %v", err)
+	}
+	return nil
+}
+
+// ClassIndexConflict returns an error if a class of the model has the dataset index of a class of another
+// model, exported datasets need one class per index. The caller holds the lock when models is Data.Models.
+func ClassIndexConflict(models map[string]*ModelDefinition, model *ModelDefinition) error {
+	for _, other := range models {
+		if other.Name == model.Name {
+			continue
+		}
+		for _, class := range model.Classes {
+			for _, otherClass := range other.Classes {
+				if class.Index == otherClass.Index {
+					return fmt.Errorf("index %d of class %s is used by class %s of model %s",
+						class.Index, class.Name, otherClass.Name, other.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultModelDefinitions returns the models known before the registry existed, they seed data stores without models
+func DefaultModelDefinitions() map[string]*ModelDefinition {
+	now := time.Now()
+	model := func(name, displayName string, index int) *ModelDefinition {
+		return &ModelDefinition{
+			Name:             name,
+			DisplayName:      displayName,
+			Classes:          []ModelClass{{Name: name, Index: index, DisplayName: displayName}},
+			DefaultThreshold: 0.5,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+	}
+
+	models := []*ModelDefinition{
+		model("other", "其他", 0),
+		model("gesture", "手势", 1),
+		model("ponding", "积水", 2),
+		model("smoke", "烟雾", 3),
+		model("mouse", "老鼠", 4),
+		model("tshirt", "短袖", 5),
+		model("cigar", "吸烟", 6),
+		model("helmet", "安全帽", 7),
+		model("fire", "火焰", 8),
+		model("fall", "摔倒", 9),
+		model("safetybelt", "安全带", 10),
+	}
+	definitions := make(map[string]*ModelDefinition, len(models))
+	for _, m := range models {
+		definitions[m.Name] = m
+	}
+
+	// smoke and fire are served by the same model, each server drops the other's boxes
+	definitions["smoke"].IgnoredClasses = []string{"fire"}
+	definitions["fire"].IgnoredClasses = []string{"smoke"}
+	// the tshirt model detects persons and classifies their sleeves
+	definitions["tshirt"].ScoreStrategy = ScoreClassification
+	definitions["fall"].Aliases = []string{"倒地"}
+	return definitions
+}
+
+// SafeGetModelDefinition returns the registered model with the given name
+func SafeGetModelDefinition(name string) (*ModelDefinition, bool) {
+	dataStoreMutex.RLock()
+	defer dataStoreMutex.RUnlock()
+	model, exists := Data.Models[name]
+	return model, exists
+}
+
+// ModelDefinitionExists reports whether a model with the given name is registered
+func ModelDefinitionExists(name string) bool {
+	_, exists := SafeGetModelDefinition(name)
+	return exists
+}
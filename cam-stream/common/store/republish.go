@@ -0,0 +1,63 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	DefaultRepublishBitrateKbps = 2048
+	DefaultOverlayTTLMillis     = 1000
+)
+
+// RepublishConfig configures pushing the annotated stream of a camera to an RTSP/RTMP server
+type RepublishConfig struct {
+	Enabled          bool   `json:"enabled"`
+	URL              string `json:"url"`                          // e.g. rtsp://mediamtx:8554/cam1_ai
+	BitrateKbps      int    `json:"bitrate_kbps,omitempty"`       // H.264 target bitrate, 0 means default
+	OverlayTTLMillis int    `json:"overlay_ttl_millis,omitempty"` // How long detections stay drawn, 0 means default
+}
+
+// GetBitrateKbps returns the configured bitrate or the default one
+func (c *RepublishConfig) GetBitrateKbps() int {
+	if c == nil || c.BitrateKbps <= 0 {
+		return DefaultRepublishBitrateKbps
+	}
+	return c.BitrateKbps
+}
+
+// GetOverlayTTLMillis returns the configured overlay lifetime or the default one
+func (c *RepublishConfig) GetOverlayTTLMillis() int {
+	if c == nil || c.OverlayTTLMillis <= 0 {
+		return DefaultOverlayTTLMillis
+	}
+	return c.OverlayTTLMillis
+}
+
+// Validate checks the target URL and encoder settings
+func (c *RepublishConfig) Validate() error {
+	if c == nil || !c.Enabled {
+		return nil
+	}
+
+	target, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid republish url: %v", err)
+	}
+	switch target.Scheme {
+	case "rtsp", "rtsps", "rtmp", "rtmps":
+	default:
+		return fmt.Errorf("republish url must use rtsp, rtsps, rtmp or rtmps scheme")
+	}
+	if target.Host == "" {
+		return fmt.Errorf("republish url must contain a host")
+	}
+	if c.BitrateKbps < 0 || c.BitrateKbps > 50000 {
+		return fmt.Errorf("bitrate_kbps must be between 0 and 50000")
+	}
+	if c.OverlayTTLMillis < 0 {
+		return fmt.Errorf("overlay_ttl_millis must not be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"cam-stream/common/config"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Schedule modes for cameras and bindings
+const (
+	ScheduleModeInference = "inference" // no inference outside the schedule
+	ScheduleModeAlerts    = "alerts"    // inference keeps running, alerts are muted outside the schedule
+)
+
+const scheduleDateLayout = "2006-01-02"
+
+// TimeWindow is a daily time range on the given weekdays, End before Start spans midnight
+type TimeWindow struct {
+	Days  []int  `json:"days"`  // 0 = Sunday ... 6 = Saturday
+	Start string `json:"start"` // "HH:MM"
+	End   string `json:"end"`   // "HH:MM"
+}
+
+// Schedule is a weekly set of active time windows
+type Schedule struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Timezone  string       `json:"timezone,omitempty"` // IANA name, empty means the TIMEZONE setting
+	Windows   []TimeWindow `json:"windows"`
+	Holidays  []string     `json:"holidays,omitempty"` // "YYYY-MM-DD" dates on which the schedule is inactive
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// ScheduleBinding attaches a schedule to a camera or inference server binding
+type ScheduleBinding struct {
+	ScheduleID string `json:"schedule_id"`
+	Mode       string `json:"mode,omitempty"` // "inference" (default) or "alerts"
+}
+
+// GetMode returns the configured mode or the default one
+func (b *ScheduleBinding) GetMode() string {
+	if b.Mode == "" {
+		return ScheduleModeInference
+	}
+	return b.Mode
+}
+
+// Validate checks that the mode is supported and the referenced schedule exists
+func (b *ScheduleBinding) Validate(scheduleExists func(id string) bool) error {
+	if b == nil {
+		return nil
+	}
+	switch b.Mode {
+	case "", ScheduleModeInference, ScheduleModeAlerts:
+	default:
+		return fmt.Errorf("unsupported schedule mode %q", b.Mode)
+	}
+	if b.ScheduleID == "" {
+		return fmt.Errorf("schedule_id is required")
+	}
+	if !scheduleExists(b.ScheduleID) {
+		return fmt.Errorf("schedule %q not found", b.ScheduleID)
+	}
+	return nil
+}
+
+// Loaded time zones, time.LoadLocation reads from disk on every call
+var locationCache sync.Map
+
+// loadLocation returns the time zone with the given IANA name, empty means the configured one
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return config.GlobalTimezone, nil
+	}
+	if loc, ok := locationCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate checks time zone, windows and holidays
+func (s *Schedule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("schedule name is required")
+	}
+	if _, err := loadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %v", s.Timezone, err)
+	}
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("schedule needs at least one time window")
+	}
+	for _, w := range s.Windows {
+		if len(w.Days) == 0 {
+			return fmt.Errorf("time window %s-%s has no days", w.Start, w.End)
+		}
+		for _, day := range w.Days {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("invalid day %d, expected 0 (Sunday) to 6 (Saturday)", day)
+			}
+		}
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return fmt.Errorf("time window %s-%s is empty", w.Start, w.End)
+		}
+	}
+	for _, holiday := range s.Holidays {
+		if _, err := time.Parse(scheduleDateLayout, holiday); err != nil {
+			return fmt.Errorf("invalid holiday %q, expected YYYY-MM-DD", holiday)
+		}
+	}
+	return nil
+}
+
+// IsActive reports whether t falls into one of the schedule's windows.
+// Windows spanning midnight belong to the day they start on, holidays
+// disable the windows starting on that date.
+func (s *Schedule) IsActive(t time.Time) bool {
+	loc, err := loadLocation(s.Timezone)
+	if err != nil {
+		return true
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	yesterday := local.AddDate(0, 0, -1)
+
+	for _, w := range s.Windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			continue
+		}
+
+		if start < end {
+			if minute >= start && minute < end && s.activeOn(w, local) {
+				return true
+			}
+			continue
+		}
+		// overnight window: evening part today or morning part of yesterday's window
+		if minute >= start && s.activeOn(w, local) {
+			return true
+		}
+		if minute < end && s.activeOn(w, yesterday) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeOn reports whether the window applies to the given local date
+func (s *Schedule) activeOn(w TimeWindow, date time.Time) bool {
+	for _, holiday := range s.Holidays {
+		if holiday == date.Format(scheduleDateLayout) {
+			return false
+		}
+	}
+	for _, day := range w.Days {
+		if day == int(date.Weekday()) {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeGetSchedule returns the schedule with the given ID
+func SafeGetSchedule(id string) (*Schedule, bool) {
+	dataStoreMutex.RLock()
+	defer dataStoreMutex.RUnlock()
+	schedule, exists := Data.Schedules[id]
+	return schedule, exists
+}
+
+// ScheduleExists reports whether a schedule with the given ID exists
+func ScheduleExists(id string) bool {
+	_, exists := SafeGetSchedule(id)
+	return exists
+}
+
+// IsScheduleActive reports whether the attached schedule is active at t.
+// A nil binding or a deleted schedule never restricts anything.
+func IsScheduleActive(binding *ScheduleBinding, t time.Time) bool {
+	if binding == nil {
+		return true
+	}
+	schedule, exists := SafeGetSchedule(binding.ScheduleID)
+	if !exists {
+		return true
+	}
+	return schedule.IsActive(t)
+}
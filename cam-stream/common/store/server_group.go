@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Balancing strategies of a server group
+const (
+	BalanceRoundRobin     = "round_robin"     // rotate through the healthy members
+	BalanceLeastInflight  = "least_inflight"  // the healthy member with the fewest running requests
+	BalanceConsistentHash = "consistent_hash" // the same member per camera while it is healthy, for stateful models
+)
+
+// ServerGroup is a set of inference servers of one model type that bindings can target instead of a single server
+type ServerGroup struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ModelType   string    `json:"model_type"`            // Model type of all members
+	ServerIDs   []string  `json:"server_ids"`            // Member inference servers
+	Balance     string    `json:"balance,omitempty"`     // "round_robin" (default), "least_inflight" or "consistent_hash"
+	Description string    `json:"description,omitempty"` // Optional description
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GetBalance returns the balancing strategy or the default one
+func (g *ServerGroup) GetBalance() string {
+	if g.Balance == "" {
+		return BalanceRoundRobin
+	}
+	return g.Balance
+}
+
+// Validate checks name, strategy and members, member model types are checked against the servers by the caller
+func (g *ServerGroup) Validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("group name is required")
+	}
+	switch g.Balance {
+	case "", BalanceRoundRobin, BalanceLeastInflight, BalanceConsistentHash:
+	default:
+		return fmt.Errorf("unsupported balance %q", g.Balance)
+	}
+	if len(g.ServerIDs) == 0 {
+		return fmt.Errorf("at least one member server is required")
+	}
+	seen := make(map[string]bool)
+	for _, id := range g.ServerIDs {
+		if seen[id] {
+			return fmt.Errorf("server %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// TargetID returns the group or server the binding sends frames to, it keys the per-binding runtime state
+func (b *InferenceServerBinding) TargetID() string {
+	if b.GroupID != "" {
+		return b.GroupID
+	}
+	return b.ServerID
+}
+
+// SafeGetServerGroup returns the server group with the given ID
+func SafeGetServerGroup(id string) (*ServerGroup, bool) {
+	dataStoreMutex.RLock()
+	defer dataStoreMutex.RUnlock()
+	group, exists := Data.ServerGroups[id]
+	return group, exists
+}
+
+// ServerGroupExists reports whether a server group with the given ID exists
+func ServerGroupExists(id string) bool {
+	_, exists := SafeGetServerGroup(id)
+	return exists
+}
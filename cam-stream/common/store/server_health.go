@@ -0,0 +1,164 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Inference server health states
+const (
+	ServerHealthUnknown = "unknown" // not probed yet
+	ServerHealthUp      = "up"
+	ServerHealthDown    = "down" // inference is suspended until probes succeed again
+)
+
+// Defaults of the inference server health check
+const (
+	DefaultHealthCheckIntervalSecs = 10
+	DefaultHealthCheckTimeoutSecs  = 3
+	DefaultHealthFailureThreshold  = 3
+	DefaultHealthSuccessThreshold  = 2
+)
+
+// healthOutcomeWindow is the number of recent requests the error rate is computed over
+const healthOutcomeWindow = 100
+
+// HealthCheckConfig configures the health prober of an inference server
+type HealthCheckConfig struct {
+	Disabled bool `json:"disabled,omitempty"` // Never suspend inference to this server
+	// GET endpoint answering 2xx when healthy, empty probes the inference URL where any non-5xx response counts
+	URL              string `json:"url,omitempty"`
+	IntervalSeconds  int    `json:"interval_seconds,omitempty"`  // Probe interval, default 10
+	TimeoutSeconds   int    `json:"timeout_seconds,omitempty"`   // Probe timeout, default 3
+	FailureThreshold int    `json:"failure_threshold,omitempty"` // Consecutive failed probes or requests marking the server down, default 3
+	SuccessThreshold int    `json:"success_threshold,omitempty"` // Consecutive successful probes marking it up again, default 2
+}
+
+// IsEnabled reports whether the server is probed and suspended when unhealthy, nil config means enabled
+func (c *HealthCheckConfig) IsEnabled() bool {
+	return c == nil || !c.Disabled
+}
+
+// GetInterval returns the probe interval
+func (c *HealthCheckConfig) GetInterval() time.Duration {
+	if c == nil || c.IntervalSeconds <= 0 {
+		return DefaultHealthCheckIntervalSecs * time.Second
+	}
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+// GetTimeout returns the probe timeout
+func (c *HealthCheckConfig) GetTimeout() time.Duration {
+	if c == nil || c.TimeoutSeconds <= 0 {
+		return DefaultHealthCheckTimeoutSecs * time.Second
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// GetFailureThreshold returns the consecutive failures marking a server down
+func (c *HealthCheckConfig) GetFailureThreshold() int {
+	if c == nil || c.FailureThreshold <= 0 {
+		return DefaultHealthFailureThreshold
+	}
+	return c.FailureThreshold
+}
+
+// GetSuccessThreshold returns the consecutive successful probes marking a server up again
+func (c *HealthCheckConfig) GetSuccessThreshold() int {
+	if c == nil || c.SuccessThreshold <= 0 {
+		return DefaultHealthSuccessThreshold
+	}
+	return c.SuccessThreshold
+}
+
+// Validate checks the probe URL and ranges
+func (c *HealthCheckConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid health check url %q", c.URL)
+		}
+	}
+	if c.IntervalSeconds < 0 || c.TimeoutSeconds < 0 || c.FailureThreshold < 0 || c.SuccessThreshold < 0 {
+		return fmt.Errorf("health check intervals and thresholds must not be negative")
+	}
+	return nil
+}
+
+// InferenceServerHealth represents the runtime health of an inference server (not persisted)
+type InferenceServerHealth struct {
+	ServerID             string    `json:"server_id"`
+	Status               string    `json:"status"` // "unknown", "up" or "down"
+	ConsecutiveFailures  int       `json:"consecutive_failures"`
+	ConsecutiveSuccesses int       `json:"consecutive_successes"`
+	LastProbeAt          time.Time `json:"last_probe_at,omitempty"`
+	ProbeLatencyMs       float64   `json:"probe_latency_ms"`     // Latency of the last successful probe
+	InferenceLatencyMs   float64   `json:"inference_latency_ms"` // Moving average of successful inference requests
+	Requests             int64     `json:"requests"`             // Probes and inference requests
+	Errors               int64     `json:"errors"`
+	ErrorRate            float64   `json:"error_rate"` // Over the last 100 requests
+	LastError            string    `json:"last_error,omitempty"`
+	DownSince            time.Time `json:"down_since,omitempty"`
+	UpdatedAt            time.Time `json:"updated_at"`
+	outcomes             []bool    // recent request outcomes, true means failed
+}
+
+// Runtime-only inference server health tracking (not persisted)
+var ServerHealthStates = make(map[string]*InferenceServerHealth)
+var serverHealthMutex sync.RWMutex
+
+// SafeUpdateServerHealth runs fn on the health state of an inference server, creating it if needed
+func SafeUpdateServerHealth(serverID string, fn func(health *InferenceServerHealth)) {
+	serverHealthMutex.Lock()
+	defer serverHealthMutex.Unlock()
+	health, exists := ServerHealthStates[serverID]
+	if !exists {
+		health = &InferenceServerHealth{ServerID: serverID, Status: ServerHealthUnknown}
+		ServerHealthStates[serverID] = health
+	}
+	fn(health)
+	health.UpdatedAt = time.Now()
+}
+
+// SafeGetServerHealth returns a copy of the health state of an inference server
+func SafeGetServerHealth(serverID string) (InferenceServerHealth, bool) {
+	serverHealthMutex.RLock()
+	defer serverHealthMutex.RUnlock()
+	health, exists := ServerHealthStates[serverID]
+	if !exists {
+		return InferenceServerHealth{ServerID: serverID, Status: ServerHealthUnknown}, false
+	}
+	healthCopy := *health
+	healthCopy.outcomes = nil
+	return healthCopy, true
+}
+
+// SafeDeleteServerHealth removes the health state of a deleted or changed inference server
+func SafeDeleteServerHealth(serverID string) {
+	serverHealthMutex.Lock()
+	defer serverHealthMutex.Unlock()
+	delete(ServerHealthStates, serverID)
+}
+
+// AddOutcome counts a request and updates the error rate over the recent ones
+func (h *InferenceServerHealth) AddOutcome(failed bool) {
+	h.Requests++
+	if failed {
+		h.Errors++
+	}
+	h.outcomes = append(h.outcomes, failed)
+	if len(h.outcomes) > healthOutcomeWindow {
+		h.outcomes = h.outcomes[len(h.outcomes)-healthOutcomeWindow:]
+	}
+	failures := 0
+	for _, outcome := range h.outcomes {
+		if outcome {
+			failures++
+		}
+	}
+	h.ErrorRate = float64(failures) / float64(len(h.outcomes))
+}
@@ -20,13 +20,21 @@ type InferenceServer struct {
 	Enabled     bool      `json:"enabled"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// Optional health check settings, servers are probed and suspended while down by default
+	HealthCheck *HealthCheckConfig `json:"health_check,omitempty"`
 }
 
 // InferenceServerBinding represents a binding between camera and inference server with threshold
 type InferenceServerBinding struct {
 	ServerID     string  `json:"server_id"`
-	Threshold    float64 `json:"threshold"`     // Minimum confidence threshold (0.0-1.0) for saving images
-	MaxThreshold float64 `json:"max_threshold"` // Maximum confidence threshold (0.0-1.0) for saving images
+	GroupID      string  `json:"group_id,omitempty"`   // Server group to balance over instead of a single server
+	Threshold    float64 `json:"threshold"`            // Minimum confidence threshold (0.0-1.0) for saving images
+	MaxThreshold float64 `json:"max_threshold"`        // Maximum confidence threshold (0.0-1.0) for saving images
+	RulesOnly    bool    `json:"rules_only,omitempty"` // Only save and alert rule events, not every detection
+	// Optional event lifecycle, replaces per-detection alerts with started/ongoing/resolved alerts
+	Lifecycle *EventLifecycleConfig `json:"lifecycle,omitempty"`
+	// Optional schedule, inference or alerts of this binding only run inside it
+	Schedule *ScheduleBinding `json:"schedule,omitempty"`
 }
 
 type CameraConfig struct {
@@ -34,6 +42,11 @@ type CameraConfig struct {
 	Name                    string                   `json:"name"` // Now directly contains KKS encoding
 	RTSPUrl                 string                   `json:"rtsp_url"`
 	InferenceServerBindings []InferenceServerBinding `json:"inference_server_bindings,omitempty"` // Array of server bindings with thresholds
+	FFmpegOptions           *FFmpegOptions           `json:"ffmpeg_options,omitempty"`            // Advanced per-camera FFmpeg input settings
+	Republish               *RepublishConfig         `json:"republish,omitempty"`                 // Optional annotated stream output
+	GeometryRules           []GeometryRule           `json:"geometry_rules,omitempty"`            // Zone, line crossing and occupancy rules
+	CompositeRules          []CompositeRule          `json:"composite_rules,omitempty"`           // Rules combining the results of several bindings
+	Schedule                *ScheduleBinding         `json:"schedule,omitempty"`                  // Optional schedule for all bindings of the camera
 	Enabled                 bool                     `json:"enabled"`
 	Running                 bool                     `json:"running"`
 	CreatedAt               time.Time                `json:"created_at"`
@@ -42,9 +55,11 @@ type CameraConfig struct {
 
 // AlertServerConfig represents the global alert server configuration
 type AlertServerConfig struct {
-	URL       string    `json:"url"`     // Alert platform URL
-	Enabled   bool      `json:"enabled"` // Whether alert is enabled globally
-	UpdatedAt time.Time `json:"updated_at"`
+	URL       string             `json:"url"`                  // Alert platform URL
+	Enabled   bool               `json:"enabled"`              // Whether alert is enabled globally
+	Image     *AlertImageOptions `json:"image,omitempty"`      // Image sent with alerts, default is the full frame
+	Mode      string             `json:"alert_mode,omitempty"` // "per_box" (default) or "per_frame"
+	UpdatedAt time.Time          `json:"updated_at"`
 }
 
 // FallDetectionTaskState represents the state of a fall detection task
@@ -66,12 +81,23 @@ type DataStore struct {
 	Cameras          map[string]*CameraConfig    `json:"cameras"`
 	InferenceServers map[string]*InferenceServer `json:"inference_servers"`
 	AlertServer      *AlertServerConfig          `json:"alert_server,omitempty"` // Global alert server config
+	Schedules        map[string]*Schedule        `json:"schedules,omitempty"`
+	// Additional alert receivers with their own payload format
+	AlertDestinations map[string]*AlertDestination `json:"alert_destinations,omitempty"`
+	// Groups of inference servers with load balancing and failover
+	ServerGroups map[string]*ServerGroup `json:"server_groups,omitempty"`
+	// Model types with their classes and score selection, seeded with the built-in models
+	Models map[string]*ModelDefinition `json:"models,omitempty"`
 }
 
 // Global data store
 var Data = &DataStore{
-	Cameras:          make(map[string]*CameraConfig),
-	InferenceServers: make(map[string]*InferenceServer),
+	Cameras:           make(map[string]*CameraConfig),
+	InferenceServers:  make(map[string]*InferenceServer),
+	Schedules:         make(map[string]*Schedule),
+	AlertDestinations: make(map[string]*AlertDestination),
+	ServerGroups:      make(map[string]*ServerGroup),
+	Models:            DefaultModelDefinitions(),
 }
 
 // Global mutex to protect dataStore concurrent access
@@ -148,6 +174,19 @@ func LoadDataStore() error {
 		if Data.InferenceServers == nil {
 			Data.InferenceServers = make(map[string]*InferenceServer)
 		}
+		if Data.Schedules == nil {
+			Data.Schedules = make(map[string]*Schedule)
+		}
+		if Data.AlertDestinations == nil {
+			Data.AlertDestinations = make(map[string]*AlertDestination)
+		}
+		if Data.ServerGroups == nil {
+			Data.ServerGroups = make(map[string]*ServerGroup)
+		}
+		// data files from before the model registry get the built-in models
+		if Data.Models == nil {
+			Data.Models = DefaultModelDefinitions()
+		}
 	})
 
 	var camerasCount, serversCount int
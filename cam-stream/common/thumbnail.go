@@ -0,0 +1,43 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// thumbnailQuality is the JPEG quality of thumbnails, they are small enough that artifacts hardly show
+const thumbnailQuality = 80
+
+// MakeThumbnail decodes a JPEG and returns it scaled down to fit into maxSize x maxSize,
+// smaller images are only re-encoded
+func MakeThumbnail(r io.Reader, maxSize int) ([]byte, error) {
+	src, err := jpeg.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			w, h = maxSize, max(1, h*maxSize/w)
+		} else {
+			w, h = max(1, w*maxSize/h), maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	// BiLinear widens its kernel when shrinking, unlike ApproxBiLinear it does not alias
+	xdraw.BiLinear.Scale(dst, dst.Bounds(), src, bounds, xdraw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %v", err)
+	}
+	return buf.Bytes(), nil
+}
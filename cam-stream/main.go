@@ -4,6 +4,7 @@ import (
 	"cam-stream/common/config"
 	"cam-stream/common/log"
 	"cam-stream/common/store"
+	"cam-stream/rtsp"
 	"cam-stream/service"
 	"fmt"
 	"os"
@@ -12,6 +13,9 @@ import (
 	"strconv"
 	"syscall"
 	"time"
+
+	// Embedded time zone database, the runtime image may not ship tzdata
+	_ "time/tzdata"
 )
 
 func autoStartRunningCameras(rtspManager *service.RTSPManager) error {
@@ -85,6 +89,33 @@ func runApplication() error {
 		}
 	}
 
+	// Camera name and capture time on saved images, enabled unless IMAGE_BANNER is 0 or false.
+	bannerStr := os.Getenv("IMAGE_BANNER")
+	config.GlobalImageBanner = bannerStr != "0" && bannerStr != "false"
+
+	// Resolution detector, empty means libav when compiled in and ffprobe otherwise.
+	detector := os.Getenv("RESOLUTION_DETECTOR")
+	switch detector {
+	case "", config.ResolutionDetectorLibav, config.ResolutionDetectorFFprobe:
+		config.GlobalResolutionDetector = detector
+	default:
+		log.Error(fmt.Sprintf("invalid RESOLUTION_DETECTOR value '%s', expected '%s' or '%s'",
+			detector, config.ResolutionDetectorLibav, config.ResolutionDetectorFFprobe))
+		os.Exit(-1)
+	}
+	if detector == config.ResolutionDetectorLibav && !rtsp.LibavDetectorAvailable() {
+		log.Error(fmt.Sprintf("RESOLUTION_DETECTOR is '%s' but libav is not compiled in, build with cgo on linux or use '%s'",
+			config.ResolutionDetectorLibav, config.ResolutionDetectorFFprobe))
+		os.Exit(-1)
+	}
+
+	// Time zone of alert timestamps, file names and schedules, defaults to Asia/Shanghai.
+	if err := config.SetTimezone(os.Getenv("TIMEZONE")); err != nil {
+		log.Error(err.Error())
+		os.Exit(-1)
+	}
+	log.Info(fmt.Sprintf("timezone: %s", config.GlobalTimezone))
+
 	// Load persistent data store
 	if err := store.LoadDataStore(); err != nil {
 		return fmt.Errorf("failed to load data store: %v", err)
@@ -108,6 +139,9 @@ func runApplication() error {
 		}
 	}()
 
+	// Probe inference servers so unhealthy ones are suspended instead of timing out on every frame
+	service.StartServerHealthProber()
+
 	if err := autoStartRunningCameras(rtspManager); err != nil {
 		log.Warn(fmt.Sprintf("failed to auto-start some cameras: %v", err))
 	}
@@ -1,6 +1,7 @@
 package rtsp
 
 import (
+	"cam-stream/common/store"
 	"fmt"
 	"sync"
 )
@@ -25,7 +26,7 @@ func NewFFmpegProxyManager(config *FFmpegProxyConfig) *FFmpegProxyManager {
 }
 
 // StartProxy starts a proxy for the given camera ID and RTSP URL (resolution auto-detected)
-func (fpm *FFmpegProxyManager) StartProxy(cameraID, rtspURL string) (*FFmpegStreamProxy, error) {
+func (fpm *FFmpegProxyManager) StartProxy(cameraID, rtspURL string, options *store.FFmpegOptions) (*FFmpegStreamProxy, error) {
 	fpm.mutex.Lock()
 	defer fpm.mutex.Unlock()
 
@@ -40,7 +41,7 @@ func (fpm *FFmpegProxyManager) StartProxy(cameraID, rtspURL string) (*FFmpegStre
 	}
 
 	// Create and start new proxy (resolution auto-detected via ffprobe)
-	proxy := NewFFmpegStreamProxy(rtspURL)
+	proxy := NewFFmpegStreamProxy(rtspURL, options, fpm.config)
 	if err := proxy.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start proxy for camera %s: %v", cameraID, err)
 	}
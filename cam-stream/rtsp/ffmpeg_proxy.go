@@ -3,10 +3,12 @@ package rtsp
 import (
 	"bufio"
 	"cam-stream/common/log"
+	"cam-stream/common/store"
 	"context"
 	"fmt"
 	"io"
 	"os/exec"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -14,6 +16,8 @@ import (
 // FFmpegStreamProxy converts unstable RTSP to stable raw frame stream via pipe
 type FFmpegStreamProxy struct {
 	originalRTSP  string
+	options       *store.FFmpegOptions
+	config        *FFmpegProxyConfig
 	cmd           *exec.Cmd
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -21,11 +25,13 @@ type FFmpegStreamProxy struct {
 	isRunning     bool
 	frameChan     chan *RawFrame
 	errorChan     chan error
+	changeChan    chan *StreamChangedError
 	stdout        io.ReadCloser
 	stderr        io.ReadCloser
 	frameWidth    int
 	frameHeight   int
 	bytesPerFrame int
+	streamInfo    *StreamInfo
 }
 
 // RawFrame represents a raw video frame
@@ -38,30 +44,49 @@ type RawFrame struct {
 
 // FFmpegProxyConfig configuration for FFmpeg proxy
 type FFmpegProxyConfig struct {
-	Timeout      time.Duration // Connection timeout
-	ReconnectMax int           // Max reconnect attempts
-	FrameRate    int           // Frames per second
+	Timeout         time.Duration // Connection timeout
+	ReconnectMax    int           // Max reconnect attempts
+	FrameRate       int           // Frames per second
+	ReprobeInterval time.Duration // Interval for checking stream parameter changes, 0 disables
 }
 
 // DefaultFFmpegProxyConfig returns default configuration
 func DefaultFFmpegProxyConfig() *FFmpegProxyConfig {
 	return &FFmpegProxyConfig{
-		Timeout:      10 * time.Second,
-		ReconnectMax: 10,
-		FrameRate:    10, // Default 10 FPS for stability
+		Timeout:         10 * time.Second,
+		ReconnectMax:    10,
+		FrameRate:       10, // Default 10 FPS for stability
+		ReprobeInterval: 60 * time.Second,
 	}
 }
 
+// StreamChangedError is reported when the stream parameters no longer match
+// the ones detected at start, the proxy must be restarted to pick them up.
+type StreamChangedError struct {
+	Previous *StreamInfo
+	Current  *StreamInfo
+}
+
+func (e *StreamChangedError) Error() string {
+	return fmt.Sprintf("stream changed from %s to %s", e.Previous, e.Current)
+}
+
 // NewFFmpegStreamProxy creates a new FFmpeg stream proxy that auto-detects resolution
-func NewFFmpegStreamProxy(rtspURL string) *FFmpegStreamProxy {
+func NewFFmpegStreamProxy(rtspURL string, options *store.FFmpegOptions, config *FFmpegProxyConfig) *FFmpegStreamProxy {
 	ctx, cancel := context.WithCancel(context.Background())
+	if config == nil {
+		config = DefaultFFmpegProxyConfig()
+	}
 
 	return &FFmpegStreamProxy{
 		originalRTSP: rtspURL,
+		options:      options,
+		config:       config,
 		ctx:          ctx,
 		cancel:       cancel,
 		frameChan:    make(chan *RawFrame, 5), // buffer 5 frames
 		errorChan:    make(chan error, 5),
+		changeChan:   make(chan *StreamChangedError, 1),
 		// Resolution will be detected from first frame
 		frameWidth:    0,
 		frameHeight:   0,
@@ -69,21 +94,55 @@ func NewFFmpegStreamProxy(rtspURL string) *FFmpegStreamProxy {
 	}
 }
 
-// detectResolution uses C++ stream detector to get actual resolution
+// detectResolution probes the stream to get actual resolution
 func (fsp *FFmpegStreamProxy) detectResolution() error {
-	width, height, err := GetResolution(fsp.originalRTSP)
+	info, err := GetStreamInfo(fsp.originalRTSP, fsp.options)
 	if err != nil {
 		return fmt.Errorf("failed to detect stream resolution: %v", err)
 	}
+	fsp.streamInfo = info
+	width, height := info.Width, info.Height
 
-	fsp.frameWidth = width
-	fsp.frameHeight = height
-	fsp.bytesPerFrame = width * height * 3 // RGB24
+	outWidth, outHeight := outputResolution(width, height, fsp.options)
+	fsp.frameWidth = outWidth
+	fsp.frameHeight = outHeight
+	fsp.bytesPerFrame = outWidth * outHeight * 3 // RGB24
 
-	log.Info(fmt.Sprintf("detected resolution: %dx%d", width, height))
+	log.Info(fmt.Sprintf("detected stream: %dx%d %s %.2f fps, output resolution: %dx%d",
+		width, height, info.Codec, info.FPS, outWidth, outHeight))
 	return nil
 }
 
+// outputResolution applies the configured scaling to the source resolution.
+// Results are rounded to even numbers as required by most pixel formats.
+func outputResolution(width, height int, options *store.FFmpegOptions) (int, int) {
+	if options == nil {
+		return width, height
+	}
+
+	outWidth, outHeight := width, height
+	switch {
+	case options.OutputWidth > 0 && options.OutputHeight > 0:
+		outWidth, outHeight = options.OutputWidth, options.OutputHeight
+	case options.OutputWidth > 0:
+		outWidth = options.OutputWidth
+		outHeight = height * options.OutputWidth / width
+	case options.OutputHeight > 0:
+		outHeight = options.OutputHeight
+		outWidth = width * options.OutputHeight / height
+	}
+
+	if options.MaxDimension > 0 {
+		longest := max(outWidth, outHeight)
+		if longest > options.MaxDimension {
+			outWidth = outWidth * options.MaxDimension / longest
+			outHeight = outHeight * options.MaxDimension / longest
+		}
+	}
+
+	return max(outWidth&^1, 2), max(outHeight&^1, 2)
+}
+
 // Start starts the FFmpeg proxy process
 func (fsp *FFmpegStreamProxy) Start() error {
 	fsp.mutex.Lock()
@@ -129,6 +188,9 @@ func (fsp *FFmpegStreamProxy) Start() error {
 	// Start error monitoring goroutine
 	go fsp.monitorErrors()
 
+	// Start stream change monitoring goroutine
+	go fsp.monitorStreamChanges()
+
 	return nil
 }
 
@@ -136,15 +198,19 @@ func (fsp *FFmpegStreamProxy) Start() error {
 func (fsp *FFmpegStreamProxy) buildFFmpegArgs() []string {
 	args := []string{
 		"-v", "error", // minimal logging
-		"-rtsp_transport", "tcp", // force TCP transport
-		"-timeout", "10000000", // socket timeout in microseconds
+		"-rtsp_transport", fsp.options.GetTransport(), // transport, TCP by default
+		"-timeout", strconv.Itoa(fsp.options.GetTimeoutSeconds() * 1000000), // socket timeout in microseconds
+	}
+	// extra input options, validated against the allowlist when the camera was saved
+	args = append(args, fsp.options.GetInputArgs()...)
+	args = append(args,
 		"-i", fsp.originalRTSP, // input RTSP URL
 		"-f", "rawvideo", // output raw video
 		"-pix_fmt", "rgb24", // RGB24 pixel format
 		"-s", fmt.Sprintf("%dx%d", fsp.frameWidth, fsp.frameHeight), // use detected resolution
-		"-r", "10", // frame rate
+		"-r", strconv.Itoa(fsp.config.FrameRate), // frame rate
 		"-", // output to stdout
-	}
+	)
 
 	return args
 }
@@ -216,6 +282,43 @@ func (fsp *FFmpegStreamProxy) monitorErrors() {
 	}
 }
 
+// monitorStreamChanges periodically reprobes the stream and reports
+// resolution or codec changes, since ffmpeg silently rescales to the
+// resolution detected at start.
+func (fsp *FFmpegStreamProxy) monitorStreamChanges() {
+	if fsp.config.ReprobeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(fsp.config.ReprobeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-fsp.ctx.Done():
+			return
+		case <-ticker.C:
+			current, err := GetStreamInfo(fsp.originalRTSP, fsp.options)
+			if err != nil {
+				// the decoder reports real connection problems, a failed reprobe is not fatal
+				log.Warn(fmt.Sprintf("failed to reprobe stream: %v", err))
+				continue
+			}
+
+			previous := fsp.GetStreamInfo()
+			if previous.SameFormat(current) {
+				continue
+			}
+
+			select {
+			case fsp.changeChan <- &StreamChangedError{Previous: previous, Current: current}:
+			default:
+			}
+			return
+		}
+	}
+}
+
 // GetFrameTimeout gets the next raw frame with timeout
 func (fsp *FFmpegStreamProxy) GetFrameTimeout(timeout time.Duration) (*RawFrame, error) {
 	select {
@@ -226,6 +329,8 @@ func (fsp *FFmpegStreamProxy) GetFrameTimeout(timeout time.Duration) (*RawFrame,
 		return frame, nil
 	case err := <-fsp.errorChan:
 		return nil, err
+	case changed := <-fsp.changeChan:
+		return nil, changed
 	case <-time.After(timeout):
 		return nil, fmt.Errorf("frame timeout")
 	case <-fsp.ctx.Done():
@@ -233,6 +338,18 @@ func (fsp *FFmpegStreamProxy) GetFrameTimeout(timeout time.Duration) (*RawFrame,
 	}
 }
 
+// GetStreamInfo returns the stream parameters detected at start
+func (fsp *FFmpegStreamProxy) GetStreamInfo() *StreamInfo {
+	fsp.mutex.RLock()
+	defer fsp.mutex.RUnlock()
+	return fsp.streamInfo
+}
+
+// FrameRate returns the rate at which the proxy outputs frames
+func (fsp *FFmpegStreamProxy) FrameRate() int {
+	return fsp.config.FrameRate
+}
+
 // IsRunning checks if the proxy is running
 func (fsp *FFmpegStreamProxy) IsRunning() bool {
 	fsp.mutex.RLock()
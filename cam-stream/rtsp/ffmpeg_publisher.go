@@ -0,0 +1,194 @@
+package rtsp
+
+import (
+	"bufio"
+	"cam-stream/common/log"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FFmpegStreamPublisher encodes raw RGB24 frames to H.264 and pushes them to an RTSP/RTMP server
+type FFmpegStreamPublisher struct {
+	targetURL   string
+	width       int
+	height      int
+	frameRate   int
+	bitrateKbps int
+	cmd         *exec.Cmd
+	ctx         context.Context
+	cancel      context.CancelFunc
+	mutex       sync.RWMutex
+	isRunning   bool
+	frameChan   chan []byte
+	stdin       io.WriteCloser
+	stderr      io.ReadCloser
+}
+
+// NewFFmpegStreamPublisher creates a new publisher for frames of the given size
+func NewFFmpegStreamPublisher(targetURL string, width, height, frameRate, bitrateKbps int) *FFmpegStreamPublisher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &FFmpegStreamPublisher{
+		targetURL:   targetURL,
+		width:       width,
+		height:      height,
+		frameRate:   frameRate,
+		bitrateKbps: bitrateKbps,
+		ctx:         ctx,
+		cancel:      cancel,
+		frameChan:   make(chan []byte, 2), // small buffer to keep latency low
+	}
+}
+
+// Start starts the FFmpeg encoder process
+func (fsp *FFmpegStreamPublisher) Start() error {
+	fsp.mutex.Lock()
+	defer fsp.mutex.Unlock()
+
+	if fsp.isRunning {
+		return fmt.Errorf("ffmpeg publisher already running")
+	}
+
+	fsp.cmd = exec.CommandContext(fsp.ctx, "ffmpeg", fsp.buildFFmpegArgs()...)
+
+	stdin, err := fsp.cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %v", err)
+	}
+	fsp.stdin = stdin
+
+	stderr, err := fsp.cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %v", err)
+	}
+	fsp.stderr = stderr
+
+	if err := fsp.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+
+	fsp.isRunning = true
+
+	go fsp.writeFrames()
+	go fsp.monitorErrors()
+
+	return nil
+}
+
+// buildFFmpegArgs builds ffmpeg command arguments for raw frame input and H.264 output
+func (fsp *FFmpegStreamPublisher) buildFFmpegArgs() []string {
+	bitrate := fmt.Sprintf("%dk", fsp.bitrateKbps)
+	args := []string{
+		"-v", "error", // minimal logging
+		"-f", "rawvideo", // raw video input
+		"-pix_fmt", "rgb24", // RGB24 pixel format
+		"-s", fmt.Sprintf("%dx%d", fsp.width, fsp.height), // frame size
+		"-r", strconv.Itoa(fsp.frameRate), // input frame rate
+		"-i", "-", // read frames from stdin
+		"-c:v", "libx264", // H.264 encoder
+		"-preset", "veryfast",
+		"-tune", "zerolatency",
+		"-pix_fmt", "yuv420p", // widest player compatibility
+		"-g", strconv.Itoa(fsp.frameRate * 2), // keyframe every 2 seconds
+		"-b:v", bitrate,
+		"-maxrate", bitrate,
+		"-bufsize", bitrate,
+	}
+
+	if strings.HasPrefix(fsp.targetURL, "rtmp") {
+		args = append(args, "-f", "flv")
+	} else {
+		args = append(args, "-f", "rtsp", "-rtsp_transport", "tcp")
+	}
+
+	return append(args, fsp.targetURL)
+}
+
+// writeFrames feeds queued frames to the encoder
+func (fsp *FFmpegStreamPublisher) writeFrames() {
+	defer func() {
+		fsp.mutex.Lock()
+		fsp.isRunning = false
+		fsp.mutex.Unlock()
+		fsp.stdin.Close()
+	}()
+
+	for {
+		select {
+		case <-fsp.ctx.Done():
+			return
+		case data := <-fsp.frameChan:
+			if _, err := fsp.stdin.Write(data); err != nil {
+				log.Warn(fmt.Sprintf("failed to write frame to publisher %s: %v", fsp.targetURL, err))
+				return
+			}
+		}
+	}
+}
+
+// monitorErrors logs FFmpeg stderr output
+func (fsp *FFmpegStreamPublisher) monitorErrors() {
+	scanner := bufio.NewScanner(fsp.stderr)
+	for scanner.Scan() {
+		log.Warn(fmt.Sprintf("ffmpeg publisher error: %s", scanner.Text()))
+	}
+}
+
+// WriteFrame queues a raw RGB24 frame, frames are dropped when the encoder falls behind
+func (fsp *FFmpegStreamPublisher) WriteFrame(data []byte) error {
+	if len(data) != fsp.width*fsp.height*3 {
+		return fmt.Errorf("invalid frame size: got %d bytes, expected %d", len(data), fsp.width*fsp.height*3)
+	}
+
+	select {
+	case fsp.frameChan <- data:
+	case <-fsp.ctx.Done():
+		return fmt.Errorf("publisher stopped")
+	default:
+		// Drop frame if channel is full to maintain real-time performance
+	}
+	return nil
+}
+
+// Matches reports whether the publisher was created for the given target and frame size
+func (fsp *FFmpegStreamPublisher) Matches(targetURL string, width, height int) bool {
+	return fsp.targetURL == targetURL && fsp.width == width && fsp.height == height
+}
+
+// IsRunning checks if the publisher is running
+func (fsp *FFmpegStreamPublisher) IsRunning() bool {
+	fsp.mutex.RLock()
+	defer fsp.mutex.RUnlock()
+	return fsp.isRunning
+}
+
+// Stop stops the FFmpeg encoder process
+func (fsp *FFmpegStreamPublisher) Stop() error {
+	fsp.cancel()
+
+	if fsp.cmd != nil && fsp.cmd.Process != nil {
+		done := make(chan error, 1)
+		go func() {
+			done <- fsp.cmd.Wait()
+		}()
+
+		select {
+		case <-time.After(5 * time.Second):
+			// Force kill process
+			fsp.cmd.Process.Kill()
+			<-done
+		case <-done:
+		}
+	}
+
+	fsp.mutex.Lock()
+	fsp.isRunning = false
+	fsp.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,169 @@
+package rtsp
+
+import (
+	"cam-stream/common/config"
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamInfo describes the video stream of a camera
+type StreamInfo struct {
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Codec   string  `json:"codec"`
+	FPS     float64 `json:"fps"`
+	BitRate int64   `json:"bit_rate"`
+}
+
+// String formats the parameters relevant for decoding, e.g. "1920x1080 h264"
+func (si *StreamInfo) String() string {
+	if si == nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dx%d %s", si.Width, si.Height, si.Codec)
+}
+
+// SameFormat reports whether both streams decode to the same frame layout
+func (si *StreamInfo) SameFormat(other *StreamInfo) bool {
+	if si == nil || other == nil {
+		return si == other
+	}
+	return si.Width == other.Width && si.Height == other.Height && si.Codec == other.Codec
+}
+
+// ffprobeOutput is the subset of `ffprobe -print_format json` output we use
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+		BitRate      string `json:"bit_rate"`
+	} `json:"streams"`
+	Format struct {
+		BitRate string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// LibavDetectorAvailable reports whether the in-process libav detector is compiled in
+func LibavDetectorAvailable() bool {
+	return libavDetectorAvailable
+}
+
+// GetResolution detects the width and height of the stream
+func GetResolution(rtspUrl string, options *store.FFmpegOptions) (int, int, error) {
+	info, err := GetStreamInfo(rtspUrl, options)
+	if err != nil {
+		return 0, 0, err
+	}
+	return info.Width, info.Height, nil
+}
+
+// GetStreamInfo probes the stream with the configured detector.
+// libav is used when compiled in, unless ffprobe is selected explicitly.
+func GetStreamInfo(rtspUrl string, options *store.FFmpegOptions) (*StreamInfo, error) {
+	switch config.GlobalResolutionDetector {
+	case config.ResolutionDetectorFFprobe:
+		return probeStreamFFprobe(rtspUrl, options)
+	case config.ResolutionDetectorLibav:
+		return probeStreamLibav(rtspUrl, options)
+	default:
+		if libavDetectorAvailable {
+			return probeStreamLibav(rtspUrl, options)
+		}
+		return probeStreamFFprobe(rtspUrl, options)
+	}
+}
+
+// probeStreamFFprobe shells out to ffprobe, no cgo required
+func probeStreamFFprobe(rtspUrl string, options *store.FFmpegOptions) (*StreamInfo, error) {
+	timeoutSeconds := options.GetTimeoutSeconds()
+	args := []string{
+		"-v", "error",
+		"-print_format", "json",
+		"-show_streams",
+		"-show_format",
+		"-select_streams", "v:0",
+		"-rtsp_transport", options.GetTransport(),
+		"-timeout", strconv.Itoa(timeoutSeconds * 1000000),
+	}
+	inputArgs, ignored := options.GetProbeInputArgs()
+	if len(ignored) > 0 {
+		log.Warn(fmt.Sprintf("ffprobe does not support input options %v, probing without them", ignored))
+	}
+	args = append(args, inputArgs...)
+	args = append(args, rtspUrl)
+
+	// leave ffprobe some time to analyze the stream after connecting
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second+10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "ffprobe", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("ffprobe failed: %v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("ffprobe failed: %v", err)
+	}
+
+	return parseFFprobeOutput(output)
+}
+
+// parseFFprobeOutput extracts stream info from ffprobe JSON output
+func parseFFprobeOutput(output []byte) (*StreamInfo, error) {
+	var probe ffprobeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+	if len(probe.Streams) == 0 {
+		return nil, fmt.Errorf("detection failed: no video stream found")
+	}
+
+	stream := probe.Streams[0]
+	if stream.Width <= 0 || stream.Height <= 0 {
+		return nil, fmt.Errorf("detection failed: invalid resolution: %dx%d", stream.Width, stream.Height)
+	}
+
+	fps := parseFrameRate(stream.AvgFrameRate)
+	if fps == 0 {
+		fps = parseFrameRate(stream.RFrameRate)
+	}
+
+	bitRate, _ := strconv.ParseInt(stream.BitRate, 10, 64)
+	if bitRate == 0 {
+		bitRate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+	}
+
+	return &StreamInfo{
+		Width:   stream.Width,
+		Height:  stream.Height,
+		Codec:   stream.CodecName,
+		FPS:     fps,
+		BitRate: bitRate,
+	}, nil
+}
+
+// parseFrameRate parses ffprobe rationals like "25/1" or "30000/1001"
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
@@ -1,4 +1,4 @@
-//go:build linux
+//go:build linux && cgo && !ffprobe
 
 package rtsp
 
@@ -15,16 +15,20 @@ package rtsp
 typedef struct {
     int width;
     int height;
+    double fps;
+    long long bit_rate;
+    char codec[32];
     int success;
     char error_message[256];
 } DetectionResult;
 
-DetectionResult detect_resolution(const char* url) {
+DetectionResult detect_resolution(const char* url, AVDictionary* options) {
     DetectionResult result = {0};
     AVFormatContext* fmt_ctx = NULL;
     int video_stream_index = -1;
 
     if (!url) {
+        av_dict_free(&options);
         result.success = 0;
         strncpy(result.error_message, "invalid URL provided", sizeof(result.error_message) - 1);
         return result;
@@ -38,13 +42,9 @@ DetectionResult detect_resolution(const char* url) {
     av_register_all();
     #endif
 
-    // set RTSP client options
-    AVDictionary* options = NULL;
-    av_dict_set(&options, "rtsp_transport", "tcp", 0);
-    av_dict_set(&options, "stimeout", "10000000", 0);
-    av_dict_set(&options, "user_agent", "stream_detector", 0);
-    av_dict_set(&options, "rtsp_flags", "prefer_tcp", 0);
-    av_dict_set(&options, "buffer_size", "1000000", 0);
+    // set RTSP client defaults, per-camera options passed by caller take precedence
+    av_dict_set(&options, "user_agent", "stream_detector", AV_DICT_DONT_OVERWRITE);
+    av_dict_set(&options, "buffer_size", "1000000", AV_DICT_DONT_OVERWRITE);
 
     // open input
     if (avformat_open_input(&fmt_ctx, url, NULL, &options) < 0) {
@@ -81,8 +81,14 @@ DetectionResult detect_resolution(const char* url) {
 
     // get resolution
     AVCodecParameters* codecpar = fmt_ctx->streams[video_stream_index]->codecpar;
+    AVRational frame_rate = fmt_ctx->streams[video_stream_index]->avg_frame_rate;
     int width = codecpar->width;
     int height = codecpar->height;
+    if (frame_rate.den > 0) {
+        result.fps = av_q2d(frame_rate);
+    }
+    result.bit_rate = codecpar->bit_rate;
+    strncpy(result.codec, avcodec_get_name(codecpar->codec_id), sizeof(result.codec) - 1);
 
     // cleanup
     avformat_close_input(&fmt_ctx);
@@ -104,20 +110,65 @@ DetectionResult detect_resolution(const char* url) {
 */
 import "C"
 import (
+	"cam-stream/common/log"
+	"cam-stream/common/store"
 	"fmt"
+	"strconv"
+	"strings"
 	"unsafe"
 )
 
-func GetResolution(rtspUrl string) (int, int, error) {
+// setDictOption sets a single libav option, the dictionary copies key and value
+func setDictOption(dict **C.AVDictionary, key, value string) {
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cValue))
+	C.av_dict_set(dict, cKey, cValue, 0)
+}
+
+const libavDetectorAvailable = true
+
+// probeStreamLibav opens the stream in-process through libavformat
+func probeStreamLibav(rtspUrl string, options *store.FFmpegOptions) (*StreamInfo, error) {
 	cURL := C.CString(rtspUrl)
 	defer C.free(unsafe.Pointer(cURL))
 
-	result := C.detect_resolution(cURL)
+	// detect_resolution takes ownership of the dictionary
+	var dict *C.AVDictionary
+	transport := options.GetTransport()
+	setDictOption(&dict, "rtsp_transport", transport)
+	if transport == "tcp" {
+		setDictOption(&dict, "rtsp_flags", "prefer_tcp")
+	}
+	setDictOption(&dict, "stimeout", strconv.Itoa(options.GetTimeoutSeconds()*1000000))
+	// ffmpeg command line options are no demuxer options, libav would silently ignore them
+	inputArgs, ignored := options.GetProbeInputArgs()
+	if len(ignored) > 0 {
+		log.Warn(fmt.Sprintf("libav does not support input options %v, probing without them", ignored))
+	}
+	for i := 0; i < len(inputArgs); i++ {
+		key := strings.TrimPrefix(inputArgs[i], "-")
+		value := "1"
+		if i+1 < len(inputArgs) && !strings.HasPrefix(inputArgs[i+1], "-") {
+			value = inputArgs[i+1]
+			i++
+		}
+		setDictOption(&dict, key, value)
+	}
+
+	result := C.detect_resolution(cURL, dict)
 
 	if result.success == 0 {
 		errMsg := C.GoString(&result.error_message[0])
-		return 0, 0, fmt.Errorf("detection failed: %s", errMsg)
+		return nil, fmt.Errorf("detection failed: %s", errMsg)
 	}
 
-	return int(result.width), int(result.height), nil
+	return &StreamInfo{
+		Width:   int(result.width),
+		Height:  int(result.height),
+		Codec:   C.GoString(&result.codec[0]),
+		FPS:     float64(result.fps),
+		BitRate: int64(result.bit_rate),
+	}, nil
 }
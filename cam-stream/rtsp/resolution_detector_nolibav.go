@@ -0,0 +1,14 @@
+//go:build !linux || !cgo || ffprobe
+
+package rtsp
+
+import (
+	"cam-stream/common/store"
+	"fmt"
+)
+
+const libavDetectorAvailable = false
+
+func probeStreamLibav(rtspUrl string, options *store.FFmpegOptions) (*StreamInfo, error) {
+	return nil, fmt.Errorf("libav detector not compiled in, build with cgo on linux")
+}
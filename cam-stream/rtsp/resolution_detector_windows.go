@@ -1,7 +0,0 @@
-//go:build windows
-
-package rtsp
-
-func GetResolution(rtspUrl string) (int, int, error) {
-	panic("method not supported on Windows")
-}
@@ -3,14 +3,15 @@ package service
 import (
 	"bytes"
 	"cam-stream/common"
+	"cam-stream/common/config"
 	"cam-stream/common/log"
 	"cam-stream/common/store"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"image/jpeg"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,50 +29,177 @@ type AlertRequest struct {
 	Y1        float64 `json:"y1"`
 	X2        float64 `json:"x2"`
 	Y2        float64 `json:"y2"`
+	TrackID   int     `json:"track_id,omitempty"`
 	Timestamp string  `json:"timestamp"`
+	// Rule event info, only set for alerts produced by rules
+	RuleID   string `json:"rule_id,omitempty"`
+	RuleName string `json:"rule_name,omitempty"`
+	RuleType string `json:"rule_type,omitempty"`
+	Message  string `json:"message,omitempty"`
+	// Event lifecycle info, only set for bindings with lifecycle enabled
+	EventID         string  `json:"event_id,omitempty"`
+	EventState      string  `json:"event_state,omitempty"` // "started", "ongoing" or "resolved"
+	EventStartedAt  string  `json:"event_started_at,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	// All boxes of a detection alert, receivers in per-box mode get one alert per entry instead
+	Detections []AlertDetection `json:"detections,omitempty"`
 }
 
-// SendAlertIfConfigured sends detection alert to management platform using global configuration
-func SendAlertIfConfigured(imageData []byte, modelType, cameraName string, score, x1, y1, x2, y2 float64) error {
+// AlertDetection is one box of a per-frame alert, coordinates are normalized like the alert box
+type AlertDetection struct {
+	Class   string  `json:"class"`
+	Score   float64 `json:"score"`
+	X1      float64 `json:"x1"`
+	Y1      float64 `json:"y1"`
+	X2      float64 `json:"x2"`
+	Y2      float64 `json:"y2"`
+	TrackID int     `json:"track_id,omitempty"`
+}
+
+// alertsForMode returns the requests a receiver gets for an alert: the alert itself in per-frame
+// mode, or one legacy alert per box with its own request ID in per-box mode
+func alertsForMode(alertReq *AlertRequest, mode string) []*AlertRequest {
+	if mode == store.AlertModePerFrame || len(alertReq.Detections) == 0 {
+		return []*AlertRequest{alertReq}
+	}
+	requests := make([]*AlertRequest, 0, len(alertReq.Detections))
+	for _, detection := range alertReq.Detections {
+		req := *alertReq
+		req.RequestID = uuid.New().String()
+		req.Score = detection.Score
+		req.X1, req.Y1, req.X2, req.Y2 = detection.X1, detection.Y1, detection.X2, detection.Y2
+		req.TrackID = detection.TrackID
+		req.Detections = nil
+		requests = append(requests, &req)
+	}
+	return requests
+}
+
+// formatAlertTime formats timestamps sent to the alert platform as RFC 3339 in the configured time zone
+func formatAlertTime(t time.Time) string {
+	return config.InTimezone(t).Format(time.RFC3339)
+}
+
+// imageSize returns the dimensions of a JPEG image
+func imageSize(imageData []byte) (int, int, error) {
+	img, err := jpeg.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil {
+		return 0, 0, err
+	}
+	return img.Width, img.Height, nil
+}
+
+// postAlertIfConfigured fills in image, request ID and timestamp and posts the alert
+// to the management platform and every enabled alert destination
+func postAlertIfConfigured(alertReq *AlertRequest, imageData []byte, source *AlertSource) error {
 	// Check if alert system is enabled and configured globally using thread-safe access
 	var alertServerURL string
 	var alertEnabled bool
+	var alertImage *store.AlertImageOptions
+	var alertMode string
+	var destinations []*store.AlertDestination
 	store.SafeReadDataStore(func() {
 		// TODO: this callback is not elegant.
 		// It should be with args.
 		if store.Data.AlertServer != nil {
 			alertEnabled = store.Data.AlertServer.Enabled
 			alertServerURL = store.Data.AlertServer.URL
+			alertImage = store.Data.AlertServer.Image
+			alertMode = store.Data.AlertServer.Mode
+		}
+		for _, destination := range store.Data.AlertDestinations {
+			if destination.Enabled {
+				destinations = append(destinations, destination)
+			}
 		}
 	})
 
-	if !alertEnabled || alertServerURL == "" {
+	if (!alertEnabled || alertServerURL == "") && len(destinations) == 0 {
 		return nil // Alert system not enabled or not configured, silently skip
 	}
 
-	// Encode image to base64
-	encodedImage := base64.StdEncoding.EncodeToString(imageData)
+	alertReq.RequestID = uuid.New().String()
+	alertReq.Timestamp = formatAlertTime(source.capturedAt())
 
-	// Create alert request using camera name directly as KKS
-	alertReq := AlertRequest{
-		Image:     encodedImage,
-		RequestID: uuid.New().String(),
-		Model:     modelType,
-		CameraKKS: cameraName, // Use camera name directly as KKS encoding
-		Score:     score,
-		X1:        x1,
-		Y1:        y1,
-		X2:        x2,
-		Y2:        y2,
-		Timestamp: time.Now().Format("2006-01-02T15:04:05+08:00"),
+	var errs []string
+	if alertEnabled && alertServerURL != "" {
+		for _, req := range alertsForMode(alertReq, alertMode) {
+			// Encode image to base64 as configured for the platform
+			platformReq, _, err := prepareAlertImage(req, imageData, alertImage)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			requestBody, err := json.Marshal(platformReq)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("failed to marshal alert request: %v", err))
+				continue
+			}
+			if err := postAlertJSON(alertServerURL, requestBody, nil); err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				log.Info(fmt.Sprintf("alert sent successfully to platform for camera %s (model: %s, score: %.3f)",
+					req.CameraKKS, req.Model, req.Score))
+			}
+		}
+	}
+
+	for _, destination := range destinations {
+		if err := deliverAlert(destination, alertReq, imageData, source); err != nil {
+			errs = append(errs, fmt.Sprintf("destination %s: %v", destination.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// deliverAlert sends an alert to one destination in its alert mode
+func deliverAlert(destination *store.AlertDestination, alertReq *AlertRequest, imageData []byte, source *AlertSource) error {
+	var errs []string
+	for _, req := range alertsForMode(alertReq, destination.Mode) {
+		if err := deliverAlertRequest(destination, req, imageData, source); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
+	return nil
+}
 
-	// Marshal request
-	requestBody, err := json.Marshal(alertReq)
+// deliverAlertRequest sends one alert request to a destination in its own payload format
+func deliverAlertRequest(destination *store.AlertDestination, alertReq *AlertRequest, imageData []byte, source *AlertSource) error {
+	alertReq, imageData, err := prepareAlertImage(alertReq, imageData, destination.Image)
 	if err != nil {
-		return fmt.Errorf("failed to marshal alert request: %v", err)
+		return err
+	}
+	if destination.GetType() == store.AlertDestinationSMTP {
+		return sendEmailAlert(destination, alertReq, imageData, source)
 	}
 
+	payload, err := buildAlertPayload(destination, alertReq, imageData, source)
+	if err != nil {
+		return err
+	}
+	switch destination.GetType() {
+	case store.AlertDestinationMQTT:
+		err = publishMQTTAlert(destination, payload, alertReq, source)
+	default:
+		err = postAlertJSON(destination.URL, payload, destination.Headers)
+	}
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("alert sent successfully to destination %s for camera %s (model: %s)",
+		destination.Name, alertReq.CameraKKS, alertReq.Model))
+	return nil
+}
+
+// postAlertJSON posts a JSON alert payload and checks the response status
+func postAlertJSON(url string, requestBody []byte, headers map[string]string) error {
 	// Create HTTP client (Connection: Close)
 	// TODO: this can be optimized by using keep-alive and reusing a global client.
 	// But I am a LAZY BONE.
@@ -85,12 +213,15 @@ func SendAlertIfConfigured(imageData []byte, modelType, cameraName string, score
 	}
 
 	// Send request
-	req, err := http.NewRequest("POST", alertServerURL, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return fmt.Errorf("failed to create alert request: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Connection", "close")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -104,14 +235,17 @@ func SendAlertIfConfigured(imageData []byte, modelType, cameraName string, score
 		return fmt.Errorf("platform returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	log.Info(fmt.Sprintf("alert sent successfully to platform for camera %s (model: %s, score: %.3f)",
-		cameraName, modelType, score))
-
 	return nil
 }
 
-// sendDetectionAlerts sends alerts for all detections in the result
-func sendDetectionAlerts(imageData []byte, detections []common.Detection, cameraName, modelType string) {
+// sendDetectionAlerts sends the detections of a frame as one alert, the alert box covers all
+// detections and receivers in per-box mode split it into one alert per detection
+func sendDetectionAlerts(imageData []byte, detections []common.Detection, source *AlertSource) {
+	cameraName, modelType := source.Camera.Name, source.Server.ModelType
+	if len(detections) == 0 {
+		return
+	}
+
 	// Get the real size of the image
 	img, err := jpeg.DecodeConfig(bytes.NewReader(imageData))
 	if err != nil {
@@ -119,17 +253,82 @@ func sendDetectionAlerts(imageData []byte, detections []common.Detection, camera
 		return
 	}
 
+	alertReq := AlertRequest{
+		Model:      modelType,
+		CameraKKS:  cameraName,
+		X1:         1,
+		Y1:         1,
+		Detections: make([]AlertDetection, 0, len(detections)),
+	}
 	for _, detection := range detections {
 		// Normalize coordinates
-		x1 := float64(detection.X1) / float64(img.Width)
-		y1 := float64(detection.Y1) / float64(img.Height)
-		x2 := float64(detection.X2) / float64(img.Width)
-		y2 := float64(detection.Y2) / float64(img.Height)
-
-		if err := SendAlertIfConfigured(imageData, modelType, cameraName, detection.Confidence, x1, y1, x2, y2); err != nil {
-			log.Warn(fmt.Sprintf("failed to send alert for detection %s: %v", detection.Class, err))
-		} else {
-			log.Info(fmt.Sprintf("sent alert for detection %s (confidence: %.3f) from camera %s", detection.Class, detection.Confidence, cameraName))
+		alertDetection := AlertDetection{
+			Class:   detection.Class,
+			Score:   detection.Confidence,
+			X1:      float64(detection.X1) / float64(img.Width),
+			Y1:      float64(detection.Y1) / float64(img.Height),
+			X2:      float64(detection.X2) / float64(img.Width),
+			Y2:      float64(detection.Y2) / float64(img.Height),
+			TrackID: detection.TrackID,
 		}
+		alertReq.Detections = append(alertReq.Detections, alertDetection)
+		alertReq.Score = max(alertReq.Score, alertDetection.Score)
+		alertReq.X1 = min(alertReq.X1, alertDetection.X1)
+		alertReq.Y1 = min(alertReq.Y1, alertDetection.Y1)
+		alertReq.X2 = max(alertReq.X2, alertDetection.X2)
+		alertReq.Y2 = max(alertReq.Y2, alertDetection.Y2)
+	}
+	if len(detections) == 1 {
+		alertReq.TrackID = detections[0].TrackID
+	}
+
+	if err := postAlertIfConfigured(&alertReq, imageData, source); err != nil {
+		log.Warn(fmt.Sprintf("failed to send alert for %d detections from camera %s: %v", len(detections), cameraName, err))
+	} else {
+		log.Info(fmt.Sprintf("sent alert for %d detections (max confidence: %.3f) from camera %s", len(detections), alertReq.Score, cameraName))
+	}
+}
+
+// sendRuleEventAlert sends an alert for a fired rule, the box covers all detections of the event
+func sendRuleEventAlert(imageData []byte, event *RuleEvent, source *AlertSource) {
+	cameraName, modelType := source.Camera.Name, source.Server.ModelType
+
+	img, err := jpeg.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil {
+		log.Warn(fmt.Sprintf("failed to decode image config for rule alert: %v", err))
+		return
+	}
+	// rules holding on absent objects have no detections, their alert has an empty box
+	var box common.Detection
+	for i, det := range event.Detections {
+		if i == 0 {
+			box = det
+			continue
+		}
+		box.X1 = min(box.X1, det.X1)
+		box.Y1 = min(box.Y1, det.Y1)
+		box.X2 = max(box.X2, det.X2)
+		box.Y2 = max(box.Y2, det.Y2)
+		box.Confidence = max(box.Confidence, det.Confidence)
+	}
+
+	alertReq := AlertRequest{
+		Model:     modelType,
+		CameraKKS: cameraName,
+		Score:     box.Confidence,
+		X1:        float64(box.X1) / float64(img.Width),
+		Y1:        float64(box.Y1) / float64(img.Height),
+		X2:        float64(box.X2) / float64(img.Width),
+		Y2:        float64(box.Y2) / float64(img.Height),
+		TrackID:   event.TrackID,
+		RuleID:    event.RuleID,
+		RuleName:  event.RuleName,
+		RuleType:  event.Type,
+		Message:   event.Message,
+	}
+	if err := postAlertIfConfigured(&alertReq, imageData, source); err != nil {
+		log.Warn(fmt.Sprintf("failed to send alert for rule %s: %v", event.RuleName, err))
+	} else {
+		log.Info(fmt.Sprintf("sent alert for rule %s from camera %s: %s", event.RuleName, cameraName, event.Message))
 	}
 }
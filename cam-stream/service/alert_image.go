@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"cam-stream/common/store"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	"golang.org/x/image/draw"
+)
+
+// prepareAlertImage applies the image options of a destination to an alert. It returns a copy
+// of the request carrying the encoded image, with the box relative to the crop in crop mode,
+// and the image bytes, nil in "none" mode.
+func prepareAlertImage(alertReq *AlertRequest, imageData []byte, options *store.AlertImageOptions) (*AlertRequest, []byte, error) {
+	req := *alertReq
+	mode := options.GetMode()
+	if mode == store.AlertImageNone || len(imageData) == 0 {
+		req.Image = ""
+		return &req, nil, nil
+	}
+	if mode == store.AlertImageFull && options.GetMaxDimension() == 0 && (options == nil || options.Quality == 0) {
+		// nothing to change, send the annotated frame as is
+		req.Image = base64.StdEncoding.EncodeToString(imageData)
+		return &req, imageData, nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode alert image: %v", err)
+	}
+
+	if mode == store.AlertImageCrop && req.X2 > req.X1 && req.Y2 > req.Y1 {
+		img = cropAlertImage(img, &req, options.GetCropPadding())
+	}
+
+	img = scaleToMaxDimension(img, options.GetMaxDimension())
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: options.GetQuality()}); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode alert image: %v", err)
+	}
+	req.Image = base64.StdEncoding.EncodeToString(buf.Bytes())
+	return &req, buf.Bytes(), nil
+}
+
+// cropAlertImage cuts a padded region around the alert box out of the frame
+// and rewrites the normalized alert and detection boxes relative to that region
+func cropAlertImage(img image.Image, req *AlertRequest, padding float64) image.Image {
+	bounds := img.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	x1, y1 := req.X1*width, req.Y1*height
+	x2, y2 := req.X2*width, req.Y2*height
+	padX, padY := (x2-x1)*padding, (y2-y1)*padding
+
+	crop := image.Rect(int(x1-padX), int(y1-padY), int(x2+padX+0.5), int(y2+padY+0.5)).
+		Add(bounds.Min).Intersect(bounds)
+	if crop.Empty() {
+		return img
+	}
+
+	cropX, cropY := float64(crop.Min.X-bounds.Min.X), float64(crop.Min.Y-bounds.Min.Y)
+	cropW, cropH := float64(crop.Dx()), float64(crop.Dy())
+	req.X1, req.Y1 = (x1-cropX)/cropW, (y1-cropY)/cropH
+	req.X2, req.Y2 = (x2-cropX)/cropW, (y2-cropY)/cropH
+	if len(req.Detections) > 0 {
+		// the request is a copy sharing the slice, remap a new one
+		detections := make([]AlertDetection, len(req.Detections))
+		for i, det := range req.Detections {
+			det.X1, det.Y1 = (det.X1*width-cropX)/cropW, (det.Y1*height-cropY)/cropH
+			det.X2, det.Y2 = (det.X2*width-cropX)/cropW, (det.Y2*height-cropY)/cropH
+			detections[i] = det
+		}
+		req.Detections = detections
+	}
+
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(crop)
+	}
+	cropped := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, crop.Min, draw.Src)
+	return cropped
+}
+
+// scaleToMaxDimension scales an image down so its longest side fits, 0 keeps the size
+func scaleToMaxDimension(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	longest := max(bounds.Dx(), bounds.Dy())
+	if maxDimension <= 0 || longest <= maxDimension {
+		return img
+	}
+
+	scale := float64(maxDimension) / float64(longest)
+	width := max(1, int(float64(bounds.Dx())*scale+0.5))
+	height := max(1, int(float64(bounds.Dy())*scale+0.5))
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"bytes"
+	"cam-stream/common"
+	"cam-stream/common/config"
+	"cam-stream/common/store"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// AlertSource describes where an alert comes from, it is exposed to payload templates
+type AlertSource struct {
+	Camera     *store.CameraConfig
+	Server     *store.InferenceServer
+	Detections []common.Detection
+	RuleEvent  *RuleEvent
+	Event      *DetectionEvent
+	ImagePath  string    // saved result image relative to the output directory, empty if not saved
+	CapturedAt time.Time // capture time of the frame, zero means the send time
+}
+
+// capturedAt returns the capture time of the alerted frame, or now if unknown
+func (s *AlertSource) capturedAt() time.Time {
+	if s == nil || s.CapturedAt.IsZero() {
+		return time.Now()
+	}
+	return s.CapturedAt
+}
+
+// AlertImage references the alert image in templates
+type AlertImage struct {
+	Base64 string // inline JPEG
+	URL    string // link to the saved result image, empty without image_base_url or saved image
+	Path   string // saved result image relative to the output directory
+	Width  int
+	Height int
+}
+
+// AlertTemplateData is the data available to alert payload templates
+type AlertTemplateData struct {
+	Alert      *AlertRequest // fields of the default payload
+	Camera     *store.CameraConfig
+	Server     *store.InferenceServer
+	Detections []common.Detection
+	RuleEvent  *RuleEvent
+	Event      *DetectionEvent
+	Image      AlertImage
+	Timestamp  time.Time // capture time in the configured time zone
+}
+
+// alertTemplateFuncs are the helper functions available to payload templates
+var alertTemplateFuncs = template.FuncMap{
+	// json encodes any value, strings included, so templates stay valid JSON
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"time": func(t time.Time, layout string) string {
+		return config.InTimezone(t).Format(layout)
+	},
+	"rfc3339": formatAlertTime,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+}
+
+// cachedAlertTemplate is a parsed template of a saved destination
+type cachedAlertTemplate struct {
+	source string
+	tmpl   *template.Template
+}
+
+// Parsed templates of saved destinations keyed by destination ID and template name
+var alertTemplateCache sync.Map
+
+// parseAlertTemplate parses a template of a destination. Only templates of saved destinations, those
+// with an ID, are cached so previews of templates being edited do not fill the cache.
+func parseAlertTemplate(destinationID, name, source string) (*template.Template, error) {
+	key := destinationID + "/" + name
+	if destinationID != "" {
+		if cached, ok := alertTemplateCache.Load(key); ok && cached.(*cachedAlertTemplate).source == source {
+			return cached.(*cachedAlertTemplate).tmpl, nil
+		}
+	}
+	tmpl, err := template.New(name).Funcs(alertTemplateFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if destinationID != "" {
+		alertTemplateCache.Store(key, &cachedAlertTemplate{source: source, tmpl: tmpl})
+	}
+	return tmpl, nil
+}
+
+// evictAlertTemplates drops the cached templates of an updated or deleted destination
+func evictAlertTemplates(destinationID string) {
+	alertTemplateCache.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), destinationID+"/") {
+			alertTemplateCache.Delete(key)
+		}
+		return true
+	})
+}
+
+// renderAlertText renders a template of a destination as plain text
+func renderAlertText(destinationID, name, source string, data *AlertTemplateData) (string, error) {
+	tmpl, err := parseAlertTemplate(destinationID, name, source)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// renderAlertTemplate renders a payload template and checks that the result is valid JSON
+func renderAlertTemplate(destinationID, source string, data *AlertTemplateData) ([]byte, error) {
+	payload, err := renderAlertText(destinationID, "payload", source, data)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(payload)) {
+		return nil, fmt.Errorf("template did not render valid JSON: %s", payload)
+	}
+	return []byte(payload), nil
+}
+
+// buildAlertPayload returns the request body of an alert for a destination
+func buildAlertPayload(destination *store.AlertDestination, alertReq *AlertRequest, imageData []byte, source *AlertSource) ([]byte, error) {
+	if destination.Template == "" {
+		return json.Marshal(alertReq)
+	}
+	return renderAlertTemplate(destination.ID, destination.Template, newAlertTemplateData(destination, alertReq, imageData, source))
+}
+
+// newAlertTemplateData collects the template data of an alert
+func newAlertTemplateData(destination *store.AlertDestination, alertReq *AlertRequest, imageData []byte, source *AlertSource) *AlertTemplateData {
+	if source == nil {
+		source = &AlertSource{}
+	}
+	camera := source.Camera
+	if camera != nil {
+		// stream URLs may contain credentials, receivers identify cameras by ID and name
+		cameraCopy := *camera
+		cameraCopy.RTSPUrl = ""
+		cameraCopy.Republish = nil
+		camera = &cameraCopy
+	}
+	data := &AlertTemplateData{
+		Alert:      alertReq,
+		Camera:     camera,
+		Server:     source.Server,
+		Detections: source.Detections,
+		RuleEvent:  source.RuleEvent,
+		Event:      source.Event,
+		Image: AlertImage{
+			Base64: alertReq.Image,
+			Path:   source.ImagePath,
+		},
+		Timestamp: config.InTimezone(source.capturedAt()),
+	}
+	if width, height, err := imageSize(imageData); err == nil {
+		data.Image.Width, data.Image.Height = width, height
+	}
+	if destination.ImageBaseURL != "" && source.ImagePath != "" {
+		data.Image.URL = strings.TrimSuffix(destination.ImageBaseURL, "/") + "/output/" + source.ImagePath
+	}
+	return data
+}
+
+// validateAlertDestination checks the destination settings and its template
+func validateAlertDestination(destination *store.AlertDestination) error {
+	if err := destination.Validate(); err != nil {
+		return err
+	}
+	if _, err := previewAlertPayload(destination); err != nil {
+		return fmt.Errorf("template: %v", err)
+	}
+	return nil
+}
+
+// previewAlertPayload renders the payload a destination would receive for a sample detection
+func previewAlertPayload(destination *store.AlertDestination) ([]byte, error) {
+	// templates being validated or edited are not cached
+	preview := *destination
+	preview.ID = ""
+	destination = &preview
+
+	alertReq, imageData, source := sampleAlert()
+	alertReq = alertsForMode(alertReq, destination.Mode)[0]
+	alertReq, imageData, err := prepareAlertImage(alertReq, imageData, destination.Image)
+	if err != nil {
+		return nil, err
+	}
+	if alertReq.Image != "" {
+		// keep the preview readable
+		alertReq.Image = fmt.Sprintf("(%d bytes base64 jpeg)", len(imageData))
+	}
+	if destination.GetType() == store.AlertDestinationSMTP {
+		email, err := renderEmailAlert(destination, alertReq, imageData, source)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(email)
+	}
+	return buildAlertPayload(destination, alertReq, imageData, source)
+}
+
+// sampleAlert returns a made-up helmet alert for previews and template validation
+func sampleAlert() (*AlertRequest, []byte, *AlertSource) {
+	now := time.Now()
+	camera := &store.CameraConfig{ID: "cam_sample", Name: "SAMPLE-KKS-001", RTSPUrl: "rtsp://192.168.1.10:554/stream"}
+	server := &store.InferenceServer{ID: "inf_helmet_sample", Name: "helmet-server", URL: "http://127.0.0.1:8901", ModelType: "helmet", Enabled: true}
+	detections := []common.Detection{{Class: "no_helmet", Confidence: 0.91, X1: 640, Y1: 200, X2: 820, Y2: 560, TrackID: 7}}
+	event := &RuleEvent{RuleID: "rule_sample", RuleName: "entrance", Type: RuleEventZoneIntrusion,
+		Message: "no_helmet #7 entered zone entrance", TrackID: 7, Detections: detections, Timestamp: now}
+
+	alertReq := &AlertRequest{
+		RequestID: "00000000-0000-0000-0000-000000000000",
+		Model:     server.ModelType,
+		CameraKKS: camera.Name,
+		Score:     0.91,
+		X1:        0.333,
+		Y1:        0.185,
+		X2:        0.427,
+		Y2:        0.519,
+		TrackID:   7,
+		Timestamp: formatAlertTime(now),
+		Detections: []AlertDetection{
+			{Class: "no_helmet", Score: 0.91, X1: 0.333, Y1: 0.185, X2: 0.427, Y2: 0.519, TrackID: 7},
+		},
+	}
+	source := &AlertSource{
+		Camera:     camera,
+		Server:     server,
+		Detections: detections,
+		RuleEvent:  event,
+		ImagePath:  server.ID + "/" + resultFileName(now, camera.ID, "helmet", "detection"),
+		CapturedAt: now,
+	}
+	return alertReq, sampleImage(), source
+}
+
+// sampleImage returns a blank 1920x1080 JPEG so previews report real image dimensions
+var sampleImage = sync.OnceValue(func() []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 1920, 1080)), &jpeg.Options{Quality: 10})
+	return buf.Bytes()
+})
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/store"
+	"sync"
+	"time"
+)
+
+// RuleEventComposite is the rule event type produced by composite rules
+const RuleEventComposite = "composite"
+
+// compositeObservation is the result of one binding for one frame
+type compositeObservation struct {
+	serverID   string
+	modelType  string
+	detections []common.Detection
+	timestamp  time.Time // Capture time, results of several bindings on one frame share it
+	frame      []byte
+}
+
+// compositeEvidence is a detection that made a condition hold, the binding that reported it and the frame it was seen on
+type compositeEvidence struct {
+	detection common.Detection
+	source    RuleEventSource
+	timestamp time.Time
+	frame     []byte
+}
+
+// compositeRuleState remembers whether a rule currently holds to alert on rising edges only
+type compositeRuleState struct {
+	active    bool
+	lastFired time.Time
+}
+
+// CompositeRuleEngine evaluates composite rules over the recent results of all bindings of one camera
+type CompositeRuleEngine struct {
+	observations []compositeObservation
+	states       map[string]*compositeRuleState
+	mutex        sync.Mutex
+}
+
+// NewCompositeRuleEngine creates a new composite rule engine
+func NewCompositeRuleEngine() *CompositeRuleEngine {
+	return &CompositeRuleEngine{states: make(map[string]*compositeRuleState)}
+}
+
+// Evaluate records the result of one binding and returns the rules that started to hold. Events carry
+// the frame their detections were seen on, which may be older than frame.
+func (e *CompositeRuleEngine) Evaluate(rules []store.CompositeRule, serverID, modelType string,
+	detections []common.Detection, timestamp time.Time, frame []byte) []RuleEvent {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	obs := compositeObservation{
+		serverID:   serverID,
+		modelType:  modelType,
+		detections: detections,
+		timestamp:  timestamp,
+	}
+	// only frames with detections can become evidence, do not keep the others for the window
+	if len(detections) > 0 {
+		obs.frame = frame
+	}
+	e.observations = append(e.observations, obs)
+
+	// keep what the longest window needs
+	maxWindow := 0.0
+	for i := range rules {
+		maxWindow = max(maxWindow, rules[i].GetWindowSeconds())
+	}
+	cutoff := timestamp.Add(-time.Duration(maxWindow * float64(time.Second)))
+	kept := e.observations[:0]
+	for _, obs := range e.observations {
+		if !obs.timestamp.Before(cutoff) {
+			kept = append(kept, obs)
+		}
+	}
+	e.observations = kept
+
+	var events []RuleEvent
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled {
+			continue
+		}
+
+		state, exists := e.states[rule.ID]
+		if !exists {
+			state = &compositeRuleState{}
+			e.states[rule.ID] = state
+		}
+
+		windowStart := timestamp.Add(-time.Duration(rule.GetWindowSeconds() * float64(time.Second)))
+		var window []compositeObservation
+		for _, obs := range e.observations {
+			if !obs.timestamp.Before(windowStart) {
+				window = append(window, obs)
+			}
+		}
+
+		holds, evidence := evaluateCondition(&rule.Condition, window)
+		wasActive := state.active
+		state.active = holds
+		if !holds || wasActive {
+			continue
+		}
+		cooldown := time.Duration(rule.CooldownSeconds * float64(time.Second))
+		if !state.lastFired.IsZero() && timestamp.Sub(state.lastFired) < cooldown {
+			continue
+		}
+		state.lastFired = timestamp
+
+		message := rule.Message
+		if message == "" {
+			message = rule.Name
+		}
+		// show the newest frame with evidence, rules holding by absence show the current frame without boxes
+		event := RuleEvent{
+			RuleID:    rule.ID,
+			RuleName:  rule.Name,
+			Type:      RuleEventComposite,
+			Message:   message,
+			Timestamp: timestamp,
+			frame:     frame,
+		}
+		for i, ev := range evidence {
+			if i == 0 || ev.timestamp.After(event.Timestamp) {
+				event.Timestamp, event.frame = ev.timestamp, ev.frame
+			}
+		}
+		for _, ev := range evidence {
+			if ev.timestamp.Equal(event.Timestamp) {
+				event.Detections = append(event.Detections, ev.detection)
+				event.Sources = append(event.Sources, ev.source)
+			}
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// evaluateCondition evaluates a condition over the observations of the window,
+// it returns whether it holds and the detections that made it hold
+func evaluateCondition(cond *store.CompositeCondition, window []compositeObservation) (bool, []compositeEvidence) {
+	switch cond.Op {
+	case store.CompositeOpAnd:
+		var evidence []compositeEvidence
+		for i := range cond.Conditions {
+			holds, dets := evaluateCondition(&cond.Conditions[i], window)
+			if !holds {
+				return false, nil
+			}
+			evidence = append(evidence, dets...)
+		}
+		return true, dedupeEvidence(evidence)
+	case store.CompositeOpOr:
+		holds := false
+		var evidence []compositeEvidence
+		for i := range cond.Conditions {
+			if ok, dets := evaluateCondition(&cond.Conditions[i], window); ok {
+				holds = true
+				evidence = append(evidence, dets...)
+			}
+		}
+		return holds, dedupeEvidence(evidence)
+	case store.CompositeOpNot:
+		holds, _ := evaluateCondition(&cond.Conditions[0], window)
+		return !holds, nil
+	case store.CompositeOpDetected:
+		// counts are per result so the same object seen in several frames is not counted twice
+		for i := len(window) - 1; i >= 0; i-- {
+			matching := selectDetections(cond.Select, &window[i])
+			if len(matching) >= cond.GetMinCount() {
+				return true, window[i].evidence(matching...)
+			}
+		}
+		return false, nil
+	case store.CompositeOpOverlap:
+		// boxes only overlap on the same frame, the other result may come from another binding
+		for i := len(window) - 1; i >= 0; i-- {
+			for _, a := range selectDetections(cond.Select, &window[i]) {
+				for j := len(window) - 1; j >= 0; j-- {
+					if !window[j].timestamp.Equal(window[i].timestamp) {
+						continue
+					}
+					for _, b := range selectDetections(cond.With, &window[j]) {
+						ratio := common.OverlapRatio(a, b)
+						if ratio > 0 && ratio >= cond.MinOverlap {
+							return true, append(window[i].evidence(a), window[j].evidence(b)...)
+						}
+					}
+				}
+			}
+		}
+		return false, nil
+	}
+	return false, nil
+}
+
+// selectDetections returns the detections of an observation matching the selector
+func selectDetections(selector *store.DetectionSelector, obs *compositeObservation) []common.Detection {
+	var matching []common.Detection
+	for _, det := range obs.detections {
+		if selector.Matches(obs.serverID, obs.modelType, det.Class, det.Confidence) {
+			matching = append(matching, det)
+		}
+	}
+	return matching
+}
+
+// evidence returns detections seen on the frame of the observation as evidence
+func (obs *compositeObservation) evidence(detections ...common.Detection) []compositeEvidence {
+	evidence := make([]compositeEvidence, len(detections))
+	for i, det := range detections {
+		evidence[i] = compositeEvidence{
+			detection: det,
+			source:    RuleEventSource{ServerID: obs.serverID, ModelType: obs.modelType},
+			timestamp: obs.timestamp,
+			frame:     obs.frame,
+		}
+	}
+	return evidence
+}
+
+// dedupeEvidence drops detections reported by several conditions
+func dedupeEvidence(evidence []compositeEvidence) []compositeEvidence {
+	var unique []compositeEvidence
+	for _, ev := range evidence {
+		duplicate := false
+		for _, u := range unique {
+			if u.detection == ev.detection && u.source == ev.source && u.timestamp.Equal(ev.timestamp) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			unique = append(unique, ev)
+		}
+	}
+	return unique
+}
+
+// Composite rule engines per camera
+var compositeEngines = make(map[string]*CompositeRuleEngine)
+var compositeEnginesMutex sync.Mutex
+
+// getCompositeRuleEngine returns the composite rule engine of a camera, creating it if needed
+func getCompositeRuleEngine(cameraID string) *CompositeRuleEngine {
+	compositeEnginesMutex.Lock()
+	defer compositeEnginesMutex.Unlock()
+	engine, exists := compositeEngines[cameraID]
+	if !exists {
+		engine = NewCompositeRuleEngine()
+		compositeEngines[cameraID] = engine
+	}
+	return engine
+}
+
+// clearCompositeRuleEngine drops the composite rule state of a deleted camera
+func clearCompositeRuleEngine(cameraID string) {
+	compositeEnginesMutex.Lock()
+	defer compositeEnginesMutex.Unlock()
+	delete(compositeEngines, cameraID)
+}
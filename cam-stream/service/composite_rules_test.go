@@ -0,0 +1,53 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/store"
+	"testing"
+)
+
+func TestCompositeRuleAcrossTwoBindings(t *testing.T) {
+	// a person box from the person model with a head box from the helmet model on the same frame
+	rules := []store.CompositeRule{{
+		ID:      "rule_no_helmet",
+		Name:    "no helmet",
+		Enabled: true,
+		Condition: store.CompositeCondition{
+			Op:     store.CompositeOpOverlap,
+			Select: &store.DetectionSelector{ModelType: "person"},
+			With:   &store.DetectionSelector{ModelType: "helmet", Classes: []string{"head"}},
+		},
+	}}
+	person := common.Detection{Class: "person", Confidence: 0.9, X1: 100, Y1: 100, X2: 200, Y2: 400}
+	head := common.Detection{Class: "head", Confidence: 0.8, X1: 130, Y1: 110, X2: 170, Y2: 150}
+
+	engine := NewCompositeRuleEngine()
+	personFrame, helmetFrame := []byte("person frame"), []byte("helmet frame")
+	if events := engine.Evaluate(rules, "srv_person", "person", []common.Detection{person}, frameTime(0), personFrame); len(events) != 0 {
+		t.Fatalf("rule fired on the person result alone: %+v", events)
+	}
+	events := engine.Evaluate(rules, "grp_helmet", "helmet", []common.Detection{head}, frameTime(0), helmetFrame)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+
+	event := events[0]
+	want := []RuleEventSource{{ServerID: "srv_person", ModelType: "person"}, {ServerID: "grp_helmet", ModelType: "helmet"}}
+	if len(event.Detections) != 2 || event.Detections[0] != person || event.Detections[1] != head {
+		t.Fatalf("event detections %+v", event.Detections)
+	}
+	if len(event.Sources) != len(want) || event.Sources[0] != want[0] || event.Sources[1] != want[1] {
+		t.Errorf("event sources %+v, want %+v", event.Sources, want)
+	}
+	if string(event.frame) != string(personFrame) {
+		t.Errorf("event shows frame %q", event.frame)
+	}
+
+	// the event is filed under the first source, each box is styled by the model that reported it
+	for i, src := range event.Sources {
+		server := ruleEventSourceServer(src)
+		if server.ID != want[i].ServerID || server.ModelType != want[i].ModelType {
+			t.Errorf("source %d resolved to server %s of model %s", i, server.ID, server.ModelType)
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package service
+
+import (
+	"archive/zip"
+	"cam-stream/common"
+	"cam-stream/common/config"
+	"cam-stream/common/store"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of dataset exports
+const (
+	DatasetFormatYOLO = "yolo" // images/, labels/ and data.yaml
+	DatasetFormatVOC  = "voc"  // Pascal VOC JPEGImages/, Annotations/ and ImageSets/Main/
+	DatasetFormatCOCO = "coco" // images/ and annotations/instances_{train,val}.json
+)
+
+// defaultValRatio is the share of samples put into the validation split
+const defaultValRatio = 0.2
+
+// Samples of dataset exports
+const (
+	DatasetIncludeAll           = "all"            // all results, verdicts correct and drop boxes where given
+	DatasetIncludeConfirmed     = "confirmed"      // reviewed results with only the boxes confirmed by verdicts
+	DatasetIncludeHardNegatives = "hard_negatives" // results whose boxes are all false positives, without labels
+)
+
+// Splits of exported datasets
+const (
+	splitTrain = "train"
+	splitVal   = "val"
+)
+
+// datasetSample is a saved result whose original frame goes into a dataset
+type datasetSample struct {
+	Name      string // File name in the archive, "<serverID>_<image name>"
+	ImagePath string // Original frame in the debug directory
+	Width     int
+	Height    int
+	Split     string // "train" or "val"
+	Metadata  ResultMetadata
+	Labels    []datasetLabel // Empty for negative samples
+}
+
+// datasetLabel is a detection box of a sample in pixels, clamped to the image
+type datasetLabel struct {
+	ClassIndex int
+	X1, Y1     int
+	X2, Y2     int
+}
+
+// datasetLabels converts detections to boxes with class indices from the model registry, boxes
+// outside the image are dropped. It returns false if a box has a class that no model lists,
+// dropping it would turn the object into background.
+func datasetLabels(detections []common.Detection, model *store.ModelDefinition, classes []datasetClass, width, height int) ([]datasetLabel, bool) {
+	labels := make([]datasetLabel, 0, len(detections))
+	for _, det := range detections {
+		classIndex := datasetClassIndex(model, det.Class, classes)
+		if classIndex < 0 {
+			return nil, false
+		}
+		label := datasetLabel{
+			ClassIndex: classIndex,
+			X1:         clampInt(det.X1, 0, width),
+			Y1:         clampInt(det.Y1, 0, height),
+			X2:         clampInt(det.X2, 0, width),
+			Y2:         clampInt(det.Y2, 0, height),
+		}
+		if label.X2 <= label.X1 || label.Y2 <= label.Y1 {
+			continue
+		}
+		labels = append(labels, label)
+	}
+	return labels, true
+}
+
+// datasetClassIndex returns the index of a class of the model, classes of other models set by
+// wrong class verdicts keep their own index and unknown classes -1
+func datasetClassIndex(model *store.ModelDefinition, className string, classes []datasetClass) int {
+	if index := model.ClassIndex(className); index >= 0 {
+		return index
+	}
+	for _, class := range classes {
+		if class.Name == className && class.Model != "" {
+			return class.Index
+		}
+	}
+	return -1
+}
+
+// clampInt limits v to [lo, hi]
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// datasetClass is a class of exported datasets
+type datasetClass struct {
+	Index       int
+	Name        string
+	DisplayName string
+	Model       string
+}
+
+// datasetClasses returns the classes of all registered models indexed by their dataset index.
+// Indices without a class get a placeholder so exported indices match the registry and earlier exports.
+// Models registered before indices had to be unique may share one, such registries cannot be exported.
+func datasetClasses() ([]datasetClass, error) {
+	var models []*store.ModelDefinition
+	store.SafeReadDataStore(func() {
+		for _, model := range store.Data.Models {
+			models = append(models, model)
+		}
+	})
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+
+	byIndex := make(map[int]datasetClass)
+	maxIndex := -1
+	for _, model := range models {
+		for _, class := range model.Classes {
+			if other, exists := byIndex[class.Index]; exists {
+				return nil, fmt.Errorf("class %s of model %s and class %s of model %s have the same index %d",
+					other.Name, other.Model, class.Name, model.Name, class.Index)
+			}
+			byIndex[class.Index] = datasetClass{Index: class.Index, Name: class.Name, DisplayName: class.DisplayName, Model: model.Name}
+			if class.Index > maxIndex {
+				maxIndex = class.Index
+			}
+		}
+	}
+
+	classes := make([]datasetClass, maxIndex+1)
+	for i := range classes {
+		class, exists := byIndex[i]
+		if !exists {
+			class = datasetClass{Index: i, Name: fmt.Sprintf("class_%d", i)}
+		}
+		classes[i] = class
+	}
+	return classes, nil
+}
+
+// datasetSplit puts a sample into the train or val split by a hash of its name,
+// so a sample stays in the same split across exports
+func datasetSplit(name string, valRatio float64) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	if float64(h.Sum32()%10000) < valRatio*10000 {
+		return splitVal
+	}
+	return splitTrain
+}
+
+// collectDatasetSamples finds the saved results matching the filter that have an original frame, include
+// selects them by their verdicts. Originals are only saved in DEBUG mode, results without one, with an
+// unregistered model or with boxes of unknown classes are counted as skipped.
+func collectDatasetSamples(outputDir string, filter *resultFilter, include string, valRatio float64,
+	classes []datasetClass) ([]datasetSample, int, error) {
+	var samples []datasetSample
+	skipped := 0
+	err := walkResults(outputDir, filter, func(serverID, imageName string, metadata *ResultMetadata) {
+		if len(metadata.Detections) == 0 {
+			return
+		}
+		detections := metadata.reviewedDetections(include == DatasetIncludeConfirmed)
+		switch include {
+		case DatasetIncludeConfirmed:
+			if len(metadata.Verdicts) == 0 {
+				return
+			}
+		case DatasetIncludeHardNegatives:
+			if !metadata.isHardNegative() {
+				return
+			}
+		}
+
+		model, exists := store.SafeGetModelDefinition(metadata.ModelType)
+		if !exists || len(model.Classes) == 0 {
+			skipped++
+			return
+		}
+		sample := datasetSample{
+			Name:      serverID + "_" + imageName,
+			ImagePath: filepath.Join(config.DebugDir, serverID, imageName),
+			Metadata:  *metadata,
+		}
+		var err error
+		if sample.Width, sample.Height, err = jpegSize(sample.ImagePath); err != nil {
+			skipped++
+			return
+		}
+		var known bool
+		if sample.Labels, known = datasetLabels(detections, model, classes, sample.Width, sample.Height); !known {
+			skipped++
+			return
+		}
+		sample.Split = datasetSplit(sample.Name, valRatio)
+		samples = append(samples, sample)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		if !samples[i].Metadata.CapturedAt.Equal(samples[j].Metadata.CapturedAt) {
+			return samples[i].Metadata.CapturedAt.Before(samples[j].Metadata.CapturedAt)
+		}
+		return samples[i].Name < samples[j].Name
+	})
+	return samples, skipped, nil
+}
+
+// jpegSize returns the dimensions of a JPEG file
+func jpegSize(path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	cfg, err := jpeg.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// writeDataset writes the samples as a zip archive in the given format
+func writeDataset(w io.Writer, format string, samples []datasetSample, classes []datasetClass) error {
+	zw := zip.NewWriter(w)
+	var err error
+	switch format {
+	case DatasetFormatYOLO:
+		err = writeYOLODataset(zw, samples, classes)
+	case DatasetFormatVOC:
+		err = writeVOCDataset(zw, samples, classes)
+	case DatasetFormatCOCO:
+		err = writeCOCODataset(zw, samples, classes)
+	default:
+		err = fmt.Errorf("unsupported dataset format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// addZipFile adds a file with the given content to the archive
+func addZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %v", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
+
+// addZipImage copies an image into the archive without compression, JPEGs do not shrink
+func addZipImage(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer src.Close()
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %v", name, err)
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
+
+// writeYOLODataset writes images/{split}, labels/{split} and data.yaml
+func writeYOLODataset(zw *zip.Writer, samples []datasetSample, classes []datasetClass) error {
+	for i := range samples {
+		sample := &samples[i]
+		if err := addZipImage(zw, "images/"+sample.Split+"/"+sample.Name, sample.ImagePath); err != nil {
+			return err
+		}
+
+		w, h := float64(sample.Width), float64(sample.Height)
+		var lines []string
+		for _, label := range sample.Labels {
+			cx := float64(label.X1+label.X2) / 2 / w
+			cy := float64(label.Y1+label.Y2) / 2 / h
+			bw := float64(label.X2-label.X1) / w
+			bh := float64(label.Y2-label.Y1) / h
+			lines = append(lines, fmt.Sprintf("%d %.6f %.6f %.6f %.6f", label.ClassIndex, cx, cy, bw, bh))
+		}
+		labelName := "labels/" + sample.Split + "/" + strings.TrimSuffix(sample.Name, ".jpg") + ".txt"
+		if err := addZipFile(zw, labelName, []byte(strings.Join(lines, "\n"))); err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("path: .\n")
+	b.WriteString("train: images/train\n")
+	b.WriteString("val: images/val\n")
+	fmt.Fprintf(&b, "nc: %d\n", len(classes))
+	b.WriteString("names:\n")
+	for _, class := range classes {
+		// JSON strings are valid YAML scalars
+		fmt.Fprintf(&b, "  %d: %s\n", class.Index, strconv.Quote(class.Name))
+	}
+	return addZipFile(zw, "data.yaml", []byte(b.String()))
+}
+
+// vocAnnotation is an annotation file of Pascal VOC
+type vocAnnotation struct {
+	XMLName   xml.Name    `xml:"annotation"`
+	Folder    string      `xml:"folder"`
+	Filename  string      `xml:"filename"`
+	Size      vocSize     `xml:"size"`
+	Segmented int         `xml:"segmented"`
+	Objects   []vocObject `xml:"object"`
+}
+
+type vocSize struct {
+	Width  int `xml:"width"`
+	Height int `xml:"height"`
+	Depth  int `xml:"depth"`
+}
+
+type vocObject struct {
+	Name      string `xml:"name"`
+	Pose      string `xml:"pose"`
+	Truncated int    `xml:"truncated"`
+	Difficult int    `xml:"difficult"`
+	BndBox    vocBox `xml:"bndbox"`
+}
+
+type vocBox struct {
+	XMin int `xml:"xmin"`
+	YMin int `xml:"ymin"`
+	XMax int `xml:"xmax"`
+	YMax int `xml:"ymax"`
+}
+
+// writeVOCDataset writes JPEGImages, Annotations, ImageSets/Main/{train,val}.txt and labels.txt
+func writeVOCDataset(zw *zip.Writer, samples []datasetSample, classes []datasetClass) error {
+	splits := map[string][]string{splitTrain: {}, splitVal: {}}
+	for i := range samples {
+		sample := &samples[i]
+		if err := addZipImage(zw, "JPEGImages/"+sample.Name, sample.ImagePath); err != nil {
+			return err
+		}
+
+		annotation := vocAnnotation{
+			Folder:   "JPEGImages",
+			Filename: sample.Name,
+			Size:     vocSize{Width: sample.Width, Height: sample.Height, Depth: 3},
+		}
+		for _, label := range sample.Labels {
+			annotation.Objects = append(annotation.Objects, vocObject{
+				Name: classes[label.ClassIndex].Name,
+				Pose: "Unspecified",
+				// VOC boxes are 1-based
+				BndBox: vocBox{XMin: label.X1 + 1, YMin: label.Y1 + 1, XMax: label.X2, YMax: label.Y2},
+			})
+		}
+		data, err := xml.MarshalIndent(annotation, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal annotation of %s: %v", sample.Name, err)
+		}
+		id := strings.TrimSuffix(sample.Name, ".jpg")
+		if err := addZipFile(zw, "Annotations/"+id+".xml", append(data, '\n')); err != nil {
+			return err
+		}
+		splits[sample.Split] = append(splits[sample.Split], id)
+	}
+
+	for _, split := range []string{splitTrain, splitVal} {
+		content := strings.Join(splits[split], "\n")
+		if err := addZipFile(zw, "ImageSets/Main/"+split+".txt", []byte(content)); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, len(classes))
+	for i, class := range classes {
+		names[i] = class.Name
+	}
+	return addZipFile(zw, "labels.txt", []byte(strings.Join(names, "\n")))
+}
+
+// cocoDataset is an instances file of COCO
+type cocoDataset struct {
+	Info        cocoInfo         `json:"info"`
+	Images      []cocoImage      `json:"images"`
+	Annotations []cocoAnnotation `json:"annotations"`
+	Categories  []cocoCategory   `json:"categories"`
+}
+
+type cocoInfo struct {
+	Description string `json:"description"`
+	DateCreated string `json:"date_created"`
+}
+
+type cocoImage struct {
+	ID           int    `json:"id"`
+	FileName     string `json:"file_name"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	DateCaptured string `json:"date_captured"`
+}
+
+type cocoAnnotation struct {
+	ID         int        `json:"id"`
+	ImageID    int        `json:"image_id"`
+	CategoryID int        `json:"category_id"`
+	BBox       [4]float64 `json:"bbox"` // x, y, width, height
+	Area       float64    `json:"area"`
+	IsCrowd    int        `json:"iscrowd"`
+}
+
+type cocoCategory struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Supercategory string `json:"supercategory"`
+}
+
+// writeCOCODataset writes images/{split} and annotations/instances_{split}.json,
+// category IDs are the dataset indices plus one because COCO tools treat 0 as background
+func writeCOCODataset(zw *zip.Writer, samples []datasetSample, classes []datasetClass) error {
+	categories := make([]cocoCategory, 0, len(classes))
+	for _, class := range classes {
+		categories = append(categories, cocoCategory{ID: class.Index + 1, Name: class.Name, Supercategory: class.Model})
+	}
+
+	created := config.InTimezone(time.Now()).Format(time.RFC3339)
+	datasets := make(map[string]*cocoDataset)
+	for _, split := range []string{splitTrain, splitVal} {
+		datasets[split] = &cocoDataset{
+			Info:        cocoInfo{Description: "cam-stream " + split + " export", DateCreated: created},
+			Images:      []cocoImage{},
+			Annotations: []cocoAnnotation{},
+			Categories:  categories,
+		}
+	}
+
+	annotationID := 0
+	for i := range samples {
+		sample := &samples[i]
+		if err := addZipImage(zw, "images/"+sample.Split+"/"+sample.Name, sample.ImagePath); err != nil {
+			return err
+		}
+
+		dataset := datasets[sample.Split]
+		imageID := i + 1
+		dataset.Images = append(dataset.Images, cocoImage{
+			ID:           imageID,
+			FileName:     sample.Name,
+			Width:        sample.Width,
+			Height:       sample.Height,
+			DateCaptured: sample.Metadata.CapturedAt.Format(time.RFC3339),
+		})
+		for _, label := range sample.Labels {
+			annotationID++
+			w, h := float64(label.X2-label.X1), float64(label.Y2-label.Y1)
+			dataset.Annotations = append(dataset.Annotations, cocoAnnotation{
+				ID:         annotationID,
+				ImageID:    imageID,
+				CategoryID: label.ClassIndex + 1,
+				BBox:       [4]float64{float64(label.X1), float64(label.Y1), w, h},
+				Area:       w * h,
+			})
+		}
+	}
+
+	for _, split := range []string{splitTrain, splitVal} {
+		data, err := json.MarshalIndent(datasets[split], "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s annotations: %v", split, err)
+		}
+		if err := addZipFile(zw, "annotations/instances_"+split+".json", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
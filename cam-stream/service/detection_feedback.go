@@ -0,0 +1,284 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/store"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Verdicts of operators on saved detections
+const (
+	VerdictTruePositive  = "true_positive"  // the box and class are correct
+	VerdictFalsePositive = "false_positive" // there is no such object
+	VerdictWrongClass    = "wrong_class"    // there is an object of another class
+	VerdictCorrectedBox  = "corrected_box"  // the object is there but the box is off, optionally with another class
+)
+
+// DetectionVerdict is an operator's verdict on a detection of a saved result
+type DetectionVerdict struct {
+	Detection int         `json:"detection"`          // Index into the detections of the result
+	Verdict   string      `json:"verdict"`            // "true_positive", "false_positive", "wrong_class" or "corrected_box"
+	Class     string      `json:"class,omitempty"`    // Correct class, required for wrong_class
+	Box       *VerdictBox `json:"box,omitempty"`      // Correct box in pixels, required for corrected_box
+	Operator  string      `json:"operator,omitempty"` // Who gave the verdict
+	Comment   string      `json:"comment,omitempty"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// VerdictBox is a corrected detection box in pixels
+type VerdictBox struct {
+	X1 int `json:"x1"`
+	Y1 int `json:"y1"`
+	X2 int `json:"x2"`
+	Y2 int `json:"y2"`
+}
+
+// Validate checks a verdict against the detections of its result
+func (v *DetectionVerdict) Validate(metadata *ResultMetadata) error {
+	if v.Detection < 0 || v.Detection >= len(metadata.Detections) {
+		return fmt.Errorf("detection %d does not exist, the result has %d detections", v.Detection, len(metadata.Detections))
+	}
+	switch v.Verdict {
+	case VerdictTruePositive, VerdictFalsePositive:
+		if v.Class != "" {
+			return fmt.Errorf("%s verdicts cannot change the class", v.Verdict)
+		}
+	case VerdictWrongClass:
+		if v.Class == "" || v.Class == metadata.Detections[v.Detection].Class {
+			return fmt.Errorf("wrong_class verdicts need the correct class")
+		}
+	case VerdictCorrectedBox:
+		if v.Box == nil {
+			return fmt.Errorf("corrected_box verdicts need the correct box")
+		}
+		if v.Box.X1 < 0 || v.Box.Y1 < 0 || v.Box.X2 <= v.Box.X1 || v.Box.Y2 <= v.Box.Y1 {
+			return fmt.Errorf("invalid box %d,%d,%d,%d", v.Box.X1, v.Box.Y1, v.Box.X2, v.Box.Y2)
+		}
+	default:
+		return fmt.Errorf("unsupported verdict %q", v.Verdict)
+	}
+	if v.Class != "" && !isRegisteredClass(v.Class) {
+		return fmt.Errorf("class %q is not a class of a registered model", v.Class)
+	}
+	return nil
+}
+
+// isRegisteredClass reports whether a class belongs to a registered model
+func isRegisteredClass(className string) bool {
+	registered := false
+	store.SafeReadDataStore(func() {
+		for _, model := range store.Data.Models {
+			for _, class := range model.Classes {
+				if class.Name == className {
+					registered = true
+					return
+				}
+			}
+		}
+	})
+	return registered
+}
+
+// verdict returns the verdict on a detection, nil if it was not reviewed
+func (m *ResultMetadata) verdict(index int) *DetectionVerdict {
+	for i := range m.Verdicts {
+		if m.Verdicts[i].Detection == index {
+			return &m.Verdicts[i]
+		}
+	}
+	return nil
+}
+
+// reviewedDetections returns the detections corrected by their verdicts without false positives,
+// confirmedOnly also drops detections without verdict
+func (m *ResultMetadata) reviewedDetections(confirmedOnly bool) []common.Detection {
+	detections := make([]common.Detection, 0, len(m.Detections))
+	for i, det := range m.Detections {
+		v := m.verdict(i)
+		if v == nil {
+			if !confirmedOnly {
+				detections = append(detections, det)
+			}
+			continue
+		}
+		switch v.Verdict {
+		case VerdictFalsePositive:
+			continue
+		case VerdictWrongClass:
+			det.Class = v.Class
+		case VerdictCorrectedBox:
+			det.X1, det.Y1, det.X2, det.Y2 = v.Box.X1, v.Box.Y1, v.Box.X2, v.Box.Y2
+			if v.Class != "" {
+				det.Class = v.Class
+			}
+		}
+		detections = append(detections, det)
+	}
+	return detections
+}
+
+// isHardNegative reports whether all detections of the result are false positives
+func (m *ResultMetadata) isHardNegative() bool {
+	if len(m.Detections) == 0 {
+		return false
+	}
+	for i := range m.Detections {
+		if v := m.verdict(i); v == nil || v.Verdict != VerdictFalsePositive {
+			return false
+		}
+	}
+	return true
+}
+
+// feedbackMutex serializes verdict updates of result metadata files
+var feedbackMutex sync.Mutex
+
+// updateResultVerdicts validates the verdicts and stores them in the metadata of a result, replacing earlier
+// verdicts on the same detections. reset drops all earlier verdicts first.
+func updateResultVerdicts(path string, verdicts []DetectionVerdict, reset bool) (*ResultMetadata, error) {
+	feedbackMutex.Lock()
+	defer feedbackMutex.Unlock()
+
+	metadata, err := readResultMetadata(path)
+	if err != nil {
+		return nil, err
+	}
+	if reset {
+		metadata.Verdicts = nil
+	}
+
+	now := time.Now()
+	for _, v := range verdicts {
+		if err := v.Validate(metadata); err != nil {
+			return nil, err
+		}
+		if v.Verdict != VerdictCorrectedBox {
+			v.Box = nil
+		}
+		v.UpdatedAt = now
+		if existing := metadata.verdict(v.Detection); existing != nil {
+			*existing = v
+		} else {
+			metadata.Verdicts = append(metadata.Verdicts, v)
+		}
+	}
+	sort.Slice(metadata.Verdicts, func(i, j int) bool { return metadata.Verdicts[i].Detection < metadata.Verdicts[j].Detection })
+
+	if err := writeResultMetadata(path, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// PrecisionStats counts the verdicts on the detections of a camera or model
+type PrecisionStats struct {
+	ID             string `json:"id"`   // Camera ID or model type
+	Name           string `json:"name"` // Camera name or model display name
+	Results        int    `json:"results"`
+	Detections     int    `json:"detections"`
+	Reviewed       int    `json:"reviewed"`
+	TruePositives  int    `json:"true_positives"`
+	FalsePositives int    `json:"false_positives"`
+	WrongClass     int    `json:"wrong_class"`
+	CorrectedBox   int    `json:"corrected_box"`
+	// Share of reviewed detections that found a real object of the right class, true positives and
+	// corrected boxes, nil until a detection is reviewed
+	Precision *float64 `json:"precision"`
+}
+
+// add counts the detections and verdicts of a result
+func (s *PrecisionStats) add(metadata *ResultMetadata) {
+	s.Results++
+	s.Detections += len(metadata.Detections)
+	for i := range metadata.Detections {
+		v := metadata.verdict(i)
+		if v == nil {
+			continue
+		}
+		s.Reviewed++
+		switch v.Verdict {
+		case VerdictTruePositive:
+			s.TruePositives++
+		case VerdictFalsePositive:
+			s.FalsePositives++
+		case VerdictWrongClass:
+			s.WrongClass++
+		case VerdictCorrectedBox:
+			s.CorrectedBox++
+		}
+	}
+}
+
+// finish computes the precision
+func (s *PrecisionStats) finish() {
+	if s.Reviewed == 0 {
+		s.Precision = nil
+		return
+	}
+	precision := float64(s.TruePositives+s.CorrectedBox) / float64(s.Reviewed)
+	s.Precision = &precision
+}
+
+// FeedbackStats is the precision of saved detections per camera and model
+type FeedbackStats struct {
+	Total   PrecisionStats   `json:"total"`
+	Cameras []PrecisionStats `json:"cameras"`
+	Models  []PrecisionStats `json:"models"`
+}
+
+// computeFeedbackStats counts the verdicts on the saved results matching the filter
+func computeFeedbackStats(outputDir string, filter *resultFilter) (*FeedbackStats, error) {
+	cameras := make(map[string]*PrecisionStats)
+	models := make(map[string]*PrecisionStats)
+	stats := &FeedbackStats{Total: PrecisionStats{ID: "total"}}
+
+	err := walkResults(outputDir, filter, func(serverID, imageName string, metadata *ResultMetadata) {
+		stats.Total.add(metadata)
+
+		// results saved without camera ID are counted by camera name
+		cameraKey := metadata.CameraID
+		if cameraKey == "" {
+			cameraKey = metadata.CameraName
+		}
+		if cameras[cameraKey] == nil {
+			cameras[cameraKey] = &PrecisionStats{ID: metadata.CameraID, Name: metadata.CameraName}
+		}
+		cameras[cameraKey].add(metadata)
+
+		if models[metadata.ModelType] == nil {
+			name := metadata.ModelType
+			if model, exists := store.SafeGetModelDefinition(metadata.ModelType); exists {
+				name = model.DisplayName
+			}
+			models[metadata.ModelType] = &PrecisionStats{ID: metadata.ModelType, Name: name}
+		}
+		models[metadata.ModelType].add(metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Total.finish()
+	stats.Cameras = sortedPrecisionStats(cameras)
+	stats.Models = sortedPrecisionStats(models)
+	return stats, nil
+}
+
+// sortedPrecisionStats finishes the stats and sorts them by name
+func sortedPrecisionStats(byKey map[string]*PrecisionStats) []PrecisionStats {
+	list := make([]PrecisionStats, 0, len(byKey))
+	for _, s := range byKey {
+		s.finish()
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
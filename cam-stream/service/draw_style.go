@@ -0,0 +1,90 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/config"
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultLabelFormat is the label of debug images without a configured format
+const defaultLabelFormat = "{track} {class} {confidence} {server}"
+
+// detectionStyles resolves the drawing style of every detection of a server from the model registry,
+// class styles override model styles. Classes the model does not list are drawn with their reported
+// name and default color unless the model sets one. Labels are only set when withLabels is set.
+func detectionStyles(server *store.InferenceServer, detections []common.Detection, withLabels bool) []common.BoxStyle {
+	model, _ := store.SafeGetModelDefinition(server.ModelType)
+	styles := make([]common.BoxStyle, len(detections))
+	for i, det := range detections {
+		className := det.Class
+		classDisplayName := det.Class
+		var style store.DrawStyle
+		if model != nil {
+			style = model.Style.Merge(nil)
+		}
+		if class := model.ClassFor(det.Class); class != nil {
+			className = class.Name
+			classDisplayName = class.Name
+			if class.DisplayName != "" {
+				classDisplayName = class.DisplayName
+			}
+			style = class.Style.Merge(&style)
+		}
+
+		boxStyle := common.DefaultBoxStyle(className)
+		if style.Color != "" {
+			if col, err := common.ParseHexColor(style.Color); err == nil {
+				boxStyle.Color = col
+			} else {
+				log.Warn(fmt.Sprintf("ignoring style of model %s: %v", server.ModelType, err))
+			}
+		}
+		if style.Thickness > 0 {
+			boxStyle.Thickness = style.Thickness
+		}
+		if withLabels {
+			modelDisplayName := server.ModelType
+			if model != nil {
+				modelDisplayName = model.DisplayName
+			}
+			boxStyle.Label = formatDetectionLabel(style.LabelFormat, det, classDisplayName, modelDisplayName, server.Name)
+		}
+		styles[i] = boxStyle
+	}
+	return styles
+}
+
+// formatDetectionLabel fills the placeholders of a label format, missing track IDs leave no gap
+func formatDetectionLabel(format string, det common.Detection, className, modelName, serverName string) string {
+	if format == "" {
+		format = defaultLabelFormat
+	}
+	track := ""
+	if det.TrackID > 0 {
+		track = fmt.Sprintf("#%d", det.TrackID)
+	}
+	server := ""
+	if serverName != "" {
+		server = "[" + serverName + "]"
+	}
+	label := strings.NewReplacer(
+		"{class}", className,
+		"{model}", modelName,
+		"{confidence}", fmt.Sprintf("%.1f%%", det.Confidence*100),
+		"{track}", track,
+		"{server}", server,
+	).Replace(format)
+	return strings.Join(strings.Fields(label), " ")
+}
+
+// resultBanner returns the camera name and capture time drawn across saved images, empty when disabled
+func resultBanner(cameraName string, capturedAt time.Time) string {
+	if !config.GlobalImageBanner {
+		return ""
+	}
+	return fmt.Sprintf("%s  %s", cameraName, config.InTimezone(capturedAt).Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"bytes"
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultEmailSubject = `[cam-stream] {{.Alert.Model}} alert on {{.Alert.CameraKKS}}`
+
+const defaultEmailBody = `Camera: {{.Alert.CameraKKS}}
+Model: {{.Alert.Model}}
+Score: {{printf "%.2f" .Alert.Score}}
+Time: {{.Alert.Timestamp}}
+{{with .RuleEvent}}Rule: {{.RuleName}} - {{.Message}}
+{{end}}{{with .Event}}Event: {{.ID}} ({{.State}})
+{{end}}{{range .Detections}}- {{.Class}} {{printf "%.2f" .Confidence}}
+{{end}}{{if .Image.URL}}Image: {{.Image.URL}}
+{{end}}`
+
+// EmailAlert is a rendered alert mail
+type EmailAlert struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// emailDigestEntry is a non-critical alert waiting for the next summary mail
+type emailDigestEntry struct {
+	to      []string
+	subject string
+	body    string
+}
+
+// emailDigest collects the digest entries of one destination
+type emailDigest struct {
+	entries []emailDigestEntry
+	since   time.Time
+}
+
+// Digests and sent mail timestamps for rate limiting, per destination
+var emailDigests = make(map[string]*emailDigest)
+var emailSent = make(map[string][]time.Time)
+var emailMutex sync.Mutex
+var emailDigestOnce sync.Once
+
+// renderEmailAlert renders recipients, subject and body of an alert mail
+func renderEmailAlert(destination *store.AlertDestination, alertReq *AlertRequest, imageData []byte, source *AlertSource) (*EmailAlert, error) {
+	options := destination.SMTP
+	data := newAlertTemplateData(destination, alertReq, imageData, source)
+
+	subjectTemplate, bodyTemplate := options.Subject, options.Body
+	if subjectTemplate == "" {
+		subjectTemplate = defaultEmailSubject
+	}
+	if bodyTemplate == "" {
+		bodyTemplate = defaultEmailBody
+	}
+	subject, err := renderAlertText(destination.ID, "subject", subjectTemplate, data)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %v", err)
+	}
+	body, err := renderAlertText(destination.ID, "body", bodyTemplate, data)
+	if err != nil {
+		return nil, fmt.Errorf("body: %v", err)
+	}
+
+	return &EmailAlert{
+		To:      emailRecipients(options, alertReq, source),
+		Subject: strings.TrimSpace(strings.ReplaceAll(subject, "\n", " ")),
+		Body:    body,
+	}, nil
+}
+
+// emailRecipients returns the default recipients plus those of every matching route
+func emailRecipients(options *store.SMTPOptions, alertReq *AlertRequest, source *AlertSource) []string {
+	cameraID := ""
+	if source != nil && source.Camera != nil {
+		cameraID = source.Camera.ID
+	}
+
+	seen := make(map[string]bool)
+	var recipients []string
+	add := func(addresses []string) {
+		for _, address := range addresses {
+			if !seen[address] {
+				seen[address] = true
+				recipients = append(recipients, address)
+			}
+		}
+	}
+
+	add(options.To)
+	for _, route := range options.Routes {
+		if matchesAny(route.Models, alertReq.Model) && matchesAny(route.Cameras, cameraID) && matchesAny(route.RuleIDs, alertReq.RuleID) {
+			add(route.To)
+		}
+	}
+	return recipients
+}
+
+// matchesAny reports whether value is in the filter, an empty filter matches everything
+func matchesAny(filter []string, value string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if f == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sendEmailAlert mails an alert immediately, or queues it for the digest when it is not
+// critical or the hourly rate limit is reached
+func sendEmailAlert(destination *store.AlertDestination, alertReq *AlertRequest, imageData []byte, source *AlertSource) error {
+	options := destination.SMTP
+	email, err := renderEmailAlert(destination, alertReq, imageData, source)
+	if err != nil {
+		return err
+	}
+	if len(email.To) == 0 {
+		return nil
+	}
+
+	digestEnabled := options.DigestIntervalMinutes > 0
+	if digestEnabled && !options.IsCritical(alertReq.Model) {
+		queueEmailDigest(destination.ID, email)
+		return nil
+	}
+	if !allowEmail(destination.ID, options.RateLimitPerHour) {
+		if digestEnabled {
+			queueEmailDigest(destination.ID, email)
+			return nil
+		}
+		return fmt.Errorf("rate limit of %d mails per hour reached, alert dropped", options.RateLimitPerHour)
+	}
+
+	message := buildEmailMessage(options.From, email.To, email.Subject, email.Body, imageData)
+	return deliverEmail(destination, email.To, message)
+}
+
+// allowEmail records an immediate mail if the destination is below its hourly limit
+func allowEmail(destinationID string, limitPerHour int) bool {
+	emailMutex.Lock()
+	defer emailMutex.Unlock()
+
+	now := time.Now()
+	var recent []time.Time
+	for _, sent := range emailSent[destinationID] {
+		if now.Sub(sent) < time.Hour {
+			recent = append(recent, sent)
+		}
+	}
+	if limitPerHour > 0 && len(recent) >= limitPerHour {
+		emailSent[destinationID] = recent
+		return false
+	}
+	emailSent[destinationID] = append(recent, now)
+	return true
+}
+
+// queueEmailDigest adds an alert to the next summary mail of a destination
+func queueEmailDigest(destinationID string, email *EmailAlert) {
+	emailDigestOnce.Do(func() { go flushEmailDigests() })
+
+	emailMutex.Lock()
+	defer emailMutex.Unlock()
+	digest, exists := emailDigests[destinationID]
+	if !exists {
+		digest = &emailDigest{since: time.Now()}
+		emailDigests[destinationID] = digest
+	}
+	digest.entries = append(digest.entries, emailDigestEntry{to: email.To, subject: email.Subject, body: email.Body})
+}
+
+// flushEmailDigests sends the summary mails whose interval has passed
+func flushEmailDigests() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for destination, entries := range takeDueEmailDigests(now) {
+			sendEmailDigest(destination, entries)
+		}
+	}
+}
+
+// takeDueEmailDigests removes the digests whose interval has passed and returns their entries, digests
+// of deleted or disabled destinations are dropped. Destinations are looked up without holding emailMutex.
+func takeDueEmailDigests(now time.Time) map[*store.AlertDestination][]emailDigestEntry {
+	emailMutex.Lock()
+	pending := make(map[string]time.Time, len(emailDigests))
+	for id, digest := range emailDigests {
+		pending[id] = digest.since
+	}
+	emailMutex.Unlock()
+
+	due := make(map[*store.AlertDestination][]emailDigestEntry)
+	for id, since := range pending {
+		destination, exists := store.SafeGetAlertDestination(id)
+		drop := !exists || destination.SMTP == nil || !destination.Enabled
+		if !drop && now.Sub(since) < time.Duration(destination.SMTP.DigestIntervalMinutes)*time.Minute {
+			continue
+		}
+
+		// only this goroutine removes digests, alerts queued meanwhile are part of it
+		emailMutex.Lock()
+		digest := emailDigests[id]
+		delete(emailDigests, id)
+		emailMutex.Unlock()
+		if !drop && digest != nil {
+			due[destination] = digest.entries
+		}
+	}
+	return due
+}
+
+// sendEmailDigest mails every recipient a summary of the alerts routed to them
+func sendEmailDigest(destination *store.AlertDestination, entries []emailDigestEntry) {
+	byRecipient := make(map[string][]emailDigestEntry)
+	for _, entry := range entries {
+		for _, address := range entry.to {
+			byRecipient[address] = append(byRecipient[address], entry)
+		}
+	}
+
+	recipients := make([]string, 0, len(byRecipient))
+	for address := range byRecipient {
+		recipients = append(recipients, address)
+	}
+	sort.Strings(recipients)
+
+	for _, address := range recipients {
+		routed := byRecipient[address]
+		var body strings.Builder
+		for i, entry := range routed {
+			fmt.Fprintf(&body, "%d. %s\n%s\n", i+1, entry.subject, entry.body)
+		}
+		subject := fmt.Sprintf("[cam-stream] %d alerts digest", len(routed))
+		message := buildEmailMessage(destination.SMTP.From, []string{address}, subject, body.String(), nil)
+		if err := deliverEmail(destination, []string{address}, message); err != nil {
+			log.Warn(fmt.Sprintf("failed to send alert digest of destination %s to %s: %v", destination.Name, address, err))
+		} else {
+			log.Info(fmt.Sprintf("sent alert digest with %d alerts to %s", len(routed), address))
+		}
+	}
+}
+
+// buildEmailMessage builds a MIME message with a plain text body and an optional JPEG attachment
+func buildEmailMessage(from string, to []string, subject, body string, imageData []byte) []byte {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	part, _ := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	writeBase64Lines(part, []byte(body))
+
+	if len(imageData) > 0 {
+		part, _ = writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"image/jpeg; name=\"alert.jpg\""},
+			"Content-Disposition":       {"attachment; filename=\"alert.jpg\""},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		writeBase64Lines(part, imageData)
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+// writeBase64Lines writes base64 encoded data wrapped at 76 characters as required by MIME
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		w.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	w.Write([]byte(encoded + "\r\n"))
+}
+
+// deliverEmail sends a message through the destination's mail server
+func deliverEmail(destination *store.AlertDestination, to []string, message []byte) error {
+	options := destination.SMTP
+	serverURL, err := url.Parse(destination.URL)
+	if err != nil {
+		return fmt.Errorf("invalid smtp server url: %v", err)
+	}
+	host, port := serverURL.Hostname(), serverURL.Port()
+	if port == "" {
+		port = "25"
+		if serverURL.Scheme == "smtps" {
+			port = "465"
+		}
+	}
+	address := net.JoinHostPort(host, port)
+	tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: options.InsecureSkipVerify}
+	timeout := time.Duration(DefaultHttpTimeoutSecs) * time.Second
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if serverURL.Scheme == "smtps" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server %s: %v", address, err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %v", err)
+	}
+	defer client.Close()
+
+	if serverURL.Scheme == "smtp" && options.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", address)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %v", err)
+		}
+	}
+	if options.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", options.Username, options.Password, host)); err != nil {
+			return fmt.Errorf("smtp authentication failed: %v", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(options.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %v", err)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %v", err)
+	}
+	for _, recipient := range to {
+		rcpt, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %v", recipient, err)
+		}
+		if err := client.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s failed: %v", rcpt.Address, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %v", err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		return fmt.Errorf("failed to write mail: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected mail: %v", err)
+	}
+	return client.Quit()
+}
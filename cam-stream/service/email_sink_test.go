@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bufio"
+	"cam-stream/common/store"
+	"encoding/base64"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testMail is a mail received by the test SMTP server
+type testMail struct {
+	auth string // decoded AUTH PLAIN response
+	from string
+	to   []string
+	data string
+}
+
+// testSMTPServer is an in-process SMTP server accepting every mail
+type testSMTPServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	mails    []testMail
+}
+
+// startTestSMTPServer starts a mail server on a free local port, it stops with the test
+func startTestSMTPServer(t *testing.T) *testSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// serve runs one SMTP session
+func (s *testSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 test ESMTP")
+	var mail testMail
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case command == "EHLO" || command == "HELO":
+			reply("250-test")
+			reply("250 AUTH PLAIN")
+		case command == "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			mail.auth = string(decoded)
+			reply("235 authenticated")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			mail.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			reply("250 ok")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			mail.to = append(mail.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 ok")
+		case command == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			mail.data = data.String()
+			s.mutex.Lock()
+			s.mails = append(s.mails, mail)
+			s.mutex.Unlock()
+			mail = testMail{auth: mail.auth}
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// received returns the mails received so far
+func (s *testSMTPServer) received() []testMail {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]testMail(nil), s.mails...)
+}
+
+// testEmailDestination returns an email destination sending through the server
+func testEmailDestination(id string, server *testSMTPServer) *store.AlertDestination {
+	return &store.AlertDestination{
+		ID:      id,
+		Name:    id,
+		Type:    store.AlertDestinationSMTP,
+		Enabled: true,
+		URL:     "smtp://" + server.listener.Addr().String(),
+		SMTP: &store.SMTPOptions{
+			From:     "cam-stream <cam@plant.local>",
+			To:       []string{"safety@plant.local"},
+			Routes:   []store.EmailRoute{{Models: []string{"fire"}, To: []string{"supervisor@plant.local"}}},
+			Username: "cam",
+			Password: "secret",
+		},
+	}
+}
+
+func TestSendEmailAlert(t *testing.T) {
+	server := startTestSMTPServer(t)
+	destination := testEmailDestination("dest_mail", server)
+
+	alertReq := &AlertRequest{Model: "fire", CameraKKS: "GATE-01", Score: 0.87, Timestamp: "2024-05-01T08:00:00+08:00"}
+	if err := sendEmailAlert(destination, alertReq, sampleImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mails := server.received()
+	if len(mails) != 1 {
+		t.Fatalf("received %d mails, want 1", len(mails))
+	}
+	mail := mails[0]
+	if mail.auth != "\x00cam\x00secret" {
+		t.Errorf("auth %q", mail.auth)
+	}
+	if mail.from != "cam@plant.local" {
+		t.Errorf("mail from %q", mail.from)
+	}
+	if strings.Join(mail.to, ",") != "safety@plant.local,supervisor@plant.local" {
+		t.Errorf("recipients %v, want default and fire route", mail.to)
+	}
+	if !strings.Contains(mail.data, "Subject: [cam-stream] fire alert on GATE-01") {
+		t.Errorf("subject missing in mail:\n%s", mail.data)
+	}
+	if !strings.Contains(mail.data, `filename="alert.jpg"`) {
+		t.Error("alert image is not attached")
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	server := startTestSMTPServer(t)
+	destination := testEmailDestination("dest_digest", server)
+	destination.SMTP.CriticalModels = []string{"fire"}
+	destination.SMTP.DigestIntervalMinutes = 10
+	deleted := testEmailDestination("dest_digest_deleted", server)
+	deleted.SMTP.DigestIntervalMinutes = 10
+	deleted.SMTP.CriticalModels = []string{"fire"}
+
+	store.SafeUpdateDataStore(func() {
+		store.Data.AlertDestinations[destination.ID] = destination
+	})
+	defer store.SafeUpdateDataStore(func() {
+		delete(store.Data.AlertDestinations, destination.ID)
+	})
+
+	for _, camera := range []string{"GATE-01", "GATE-02"} {
+		alertReq := &AlertRequest{Model: "helmet", CameraKKS: camera, Score: 0.6}
+		if err := sendEmailAlert(destination, alertReq, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sendEmailAlert(deleted, &AlertRequest{Model: "helmet", CameraKKS: "GATE-03"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.received()); n != 0 {
+		t.Fatalf("%d mails sent for non-critical alerts, want them queued", n)
+	}
+
+	if due := takeDueEmailDigests(time.Now()); len(due) != 0 {
+		t.Fatalf("%d digests due before their interval", len(due))
+	}
+	due := takeDueEmailDigests(time.Now().Add(11 * time.Minute))
+	if len(due) != 1 || len(due[destination]) != 2 {
+		t.Fatalf("due digests %v, want the 2 alerts of the saved destination", due)
+	}
+	for dest, entries := range due {
+		sendEmailDigest(dest, entries)
+	}
+
+	mails := server.received()
+	if len(mails) != 1 || strings.Join(mails[0].to, ",") != "safety@plant.local" {
+		t.Fatalf("received %+v, want one digest to the default recipient", mails)
+	}
+	if !strings.Contains(mails[0].data, "2 alerts digest") {
+		t.Errorf("digest subject missing in mail:\n%s", mails[0].data)
+	}
+
+	emailMutex.Lock()
+	remaining := len(emailDigests)
+	emailMutex.Unlock()
+	if remaining != 0 {
+		t.Errorf("%d digests left, sent and orphaned digests should be removed", remaining)
+	}
+}
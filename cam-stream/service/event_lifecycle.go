@@ -0,0 +1,244 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event lifecycle states reported to the alert platform
+const (
+	EventStateStarted  = "started"
+	EventStateOngoing  = "ongoing"
+	EventStateResolved = "resolved"
+)
+
+// DetectionEvent is a condition confirmed over several frames of one camera/binding
+type DetectionEvent struct {
+	ID             string             `json:"id"`
+	CameraID       string             `json:"camera_id"`
+	CameraName     string             `json:"camera_name"`
+	ServerID       string             `json:"server_id"`
+	ModelType      string             `json:"model_type"`
+	State          string             `json:"state"`
+	StartedAt      time.Time          `json:"started_at"`
+	LastSeenAt     time.Time          `json:"last_seen_at"`
+	ResolvedAt     time.Time          `json:"resolved_at,omitempty"`
+	PeakConfidence float64            `json:"peak_confidence"`
+	Detections     []common.Detection `json:"detections"` // Latest detections of the event
+	image          []byte             // Latest annotated image
+	lastNotifiedAt time.Time
+	resolveAfter   time.Duration
+}
+
+// Duration returns how long the event has lasted so far
+func (e *DetectionEvent) Duration() time.Duration {
+	if !e.ResolvedAt.IsZero() {
+		return e.ResolvedAt.Sub(e.StartedAt)
+	}
+	return e.LastSeenAt.Sub(e.StartedAt)
+}
+
+// eventTracker holds the pending confirmation and active event of one camera/binding
+type eventTracker struct {
+	pendingFrames   int
+	pendingSince    time.Time
+	pendingLastSeen time.Time
+	active          *DetectionEvent
+}
+
+// Event trackers per camera and inference server binding
+var eventTrackers = make(map[string]*eventTracker)
+var eventTrackersMutex sync.Mutex
+var eventSweeperOnce sync.Once
+
+// observeEventLifecycle feeds the detections of one frame into the event model of a binding.
+// image is the annotated frame, nil when nothing was detected.
+func observeEventLifecycle(cameraConfig *store.CameraConfig, server *store.InferenceServer, binding *store.InferenceServerBinding,
+	detections []common.Detection, image []byte, timestamp time.Time) {
+	lifecycle := binding.Lifecycle
+	if !lifecycle.IsEnabled() {
+		return
+	}
+	eventSweeperOnce.Do(func() { go sweepEvents() })
+
+	resolveAfter := time.Duration(lifecycle.GetResolveAfterSeconds() * float64(time.Second))
+	var notify []DetectionEvent
+
+	eventTrackersMutex.Lock()
+	key := cameraConfig.ID + "/" + server.ID
+	tracker, exists := eventTrackers[key]
+	if !exists {
+		tracker = &eventTracker{}
+		eventTrackers[key] = tracker
+	}
+
+	if len(detections) == 0 {
+		if tracker.active != nil && timestamp.Sub(tracker.active.LastSeenAt) >= resolveAfter {
+			notify = append(notify, resolveEvent(tracker, timestamp))
+		}
+		if tracker.pendingFrames > 0 && timestamp.Sub(tracker.pendingLastSeen) >= resolveAfter {
+			tracker.pendingFrames = 0
+		}
+	} else if tracker.active != nil {
+		event := tracker.active
+		event.LastSeenAt = timestamp
+		event.Detections = detections
+		event.image = image
+		event.resolveAfter = resolveAfter
+		event.PeakConfidence = max(event.PeakConfidence, peakConfidence(detections))
+		interval := time.Duration(lifecycle.UpdateIntervalSeconds * float64(time.Second))
+		if interval > 0 && timestamp.Sub(event.lastNotifiedAt) >= interval {
+			event.State = EventStateOngoing
+			event.lastNotifiedAt = timestamp
+			notify = append(notify, *event)
+		}
+	} else {
+		if tracker.pendingFrames == 0 {
+			tracker.pendingSince = timestamp
+		}
+		tracker.pendingFrames++
+		tracker.pendingLastSeen = timestamp
+
+		confirmDuration := time.Duration(lifecycle.ConfirmSeconds * float64(time.Second))
+		if tracker.pendingFrames >= lifecycle.GetConfirmFrames() && timestamp.Sub(tracker.pendingSince) >= confirmDuration {
+			tracker.active = &DetectionEvent{
+				ID:             "evt_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+				CameraID:       cameraConfig.ID,
+				CameraName:     cameraConfig.Name,
+				ServerID:       server.ID,
+				ModelType:      server.ModelType,
+				State:          EventStateStarted,
+				StartedAt:      tracker.pendingSince,
+				LastSeenAt:     timestamp,
+				PeakConfidence: peakConfidence(detections),
+				Detections:     detections,
+				image:          image,
+				lastNotifiedAt: timestamp,
+				resolveAfter:   resolveAfter,
+			}
+			tracker.pendingFrames = 0
+			notify = append(notify, *tracker.active)
+		}
+	}
+	// queued while holding the tracker lock, so notifications keep the order of the state changes
+	queueEventAlerts(notify)
+	eventTrackersMutex.Unlock()
+}
+
+// resolveEvent closes the active event of a tracker, caller must hold eventTrackersMutex
+func resolveEvent(tracker *eventTracker, timestamp time.Time) DetectionEvent {
+	event := tracker.active
+	event.State = EventStateResolved
+	event.ResolvedAt = timestamp
+	tracker.active = nil
+	return *event
+}
+
+// sweepEvents resolves events whose camera stopped delivering results
+func sweepEvents() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		var notify []DetectionEvent
+		eventTrackersMutex.Lock()
+		for key, tracker := range eventTrackers {
+			if tracker.active != nil && now.Sub(tracker.active.LastSeenAt) >= tracker.active.resolveAfter {
+				notify = append(notify, resolveEvent(tracker, now))
+			}
+			if tracker.active == nil && tracker.pendingFrames == 0 {
+				delete(eventTrackers, key)
+			}
+		}
+		queueEventAlerts(notify)
+		eventTrackersMutex.Unlock()
+	}
+}
+
+// Notifications waiting to be sent per event ID, an event has a queue while one goroutine sends them
+var eventAlertQueues = make(map[string][]DetectionEvent)
+var eventAlertQueuesMutex sync.Mutex
+
+// queueEventAlerts sends the notifications in the background, the notifications of one event are sent
+// one after another so its destinations see started, ongoing and resolved in order
+func queueEventAlerts(events []DetectionEvent) {
+	eventAlertQueuesMutex.Lock()
+	defer eventAlertQueuesMutex.Unlock()
+	for _, event := range events {
+		queue, sending := eventAlertQueues[event.ID]
+		eventAlertQueues[event.ID] = append(queue, event)
+		if !sending {
+			go sendQueuedEventAlerts(event.ID)
+		}
+	}
+}
+
+// sendQueuedEventAlerts sends the queued notifications of an event until its queue is empty
+func sendQueuedEventAlerts(eventID string) {
+	for {
+		eventAlertQueuesMutex.Lock()
+		queue := eventAlertQueues[eventID]
+		if len(queue) == 0 {
+			delete(eventAlertQueues, eventID)
+			eventAlertQueuesMutex.Unlock()
+			return
+		}
+		event := queue[0]
+		eventAlertQueues[eventID] = queue[1:]
+		eventAlertQueuesMutex.Unlock()
+
+		sendEventAlert(&event)
+	}
+}
+
+// peakConfidence returns the highest confidence of the detections
+func peakConfidence(detections []common.Detection) float64 {
+	peak := 0.0
+	for _, det := range detections {
+		peak = max(peak, det.Confidence)
+	}
+	return peak
+}
+
+// sendEventAlert sends a lifecycle notification of an event
+func sendEventAlert(event *DetectionEvent) {
+	log.Info(fmt.Sprintf("event %s %s on camera %s (model: %s, duration: %s)",
+		event.ID, event.State, event.CameraName, event.ModelType, event.Duration().Round(time.Second)))
+
+	alertReq := AlertRequest{
+		Model:           event.ModelType,
+		CameraKKS:       event.CameraName,
+		Score:           event.PeakConfidence,
+		EventID:         event.ID,
+		EventState:      event.State,
+		EventStartedAt:  formatAlertTime(event.StartedAt),
+		DurationSeconds: event.Duration().Seconds(),
+	}
+	if len(event.Detections) > 0 {
+		if width, height, err := imageSize(event.image); err == nil {
+			det := event.Detections[0]
+			alertReq.X1 = float64(det.X1) / float64(width)
+			alertReq.Y1 = float64(det.Y1) / float64(height)
+			alertReq.X2 = float64(det.X2) / float64(width)
+			alertReq.Y2 = float64(det.Y2) / float64(height)
+			alertReq.TrackID = det.TrackID
+		}
+	}
+
+	source := &AlertSource{Detections: event.Detections, Event: event, CapturedAt: event.LastSeenAt}
+	if !event.ResolvedAt.IsZero() {
+		source.CapturedAt = event.ResolvedAt
+	}
+	source.Camera, _ = store.SafeGetCamera(event.CameraID)
+	source.Server, _ = getTargetServer(event.ServerID)
+	if err := postAlertIfConfigured(&alertReq, event.image, source); err != nil {
+		log.Warn(fmt.Sprintf("failed to send %s alert for event %s: %v", event.State, event.ID, err))
+	}
+}
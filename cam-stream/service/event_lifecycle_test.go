@@ -0,0 +1,86 @@
+package service
+
+import (
+	"cam-stream/common/store"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventAlertsKeepLifecycleOrder(t *testing.T) {
+	var mutex sync.Mutex
+	var states []string
+	received := make(chan struct{}, 8)
+	platform := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alertReq AlertRequest
+		json.NewDecoder(r.Body).Decode(&alertReq)
+		// a slow first request would let later notifications overtake it if they were sent in parallel
+		if alertReq.EventState == EventStateStarted {
+			time.Sleep(200 * time.Millisecond)
+		}
+		mutex.Lock()
+		states = append(states, alertReq.EventID+":"+alertReq.EventState)
+		mutex.Unlock()
+		received <- struct{}{}
+	}))
+	defer platform.Close()
+
+	var saved *store.AlertServerConfig
+	store.SafeUpdateDataStore(func() {
+		saved = store.Data.AlertServer
+		store.Data.AlertServer = &store.AlertServerConfig{URL: platform.URL, Enabled: true}
+	})
+	defer store.SafeUpdateDataStore(func() { store.Data.AlertServer = saved })
+
+	started := time.Now()
+	event := DetectionEvent{ID: "evt_order", CameraName: "GATE-01", ModelType: "helmet", StartedAt: started, LastSeenAt: started}
+	var notify []DetectionEvent
+	for _, state := range []string{EventStateStarted, EventStateOngoing} {
+		event.State = state
+		notify = append(notify, event)
+	}
+	queueEventAlerts(notify)
+	event.State = EventStateResolved
+	event.ResolvedAt = started.Add(time.Second)
+	queueEventAlerts([]DetectionEvent{event})
+	queueEventAlerts([]DetectionEvent{{ID: "evt_other", State: EventStateStarted, StartedAt: started, LastSeenAt: started}})
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("platform received %d of 4 alerts", i)
+		}
+	}
+
+	mutex.Lock()
+	var order []string
+	for _, state := range states {
+		if strings.HasPrefix(state, "evt_order:") {
+			order = append(order, strings.TrimPrefix(state, "evt_order:"))
+		}
+	}
+	mutex.Unlock()
+	if strings.Join(order, ",") != "started,ongoing,resolved" {
+		t.Errorf("event alerts arrived as %v", order)
+	}
+
+	// the sending goroutines drop their queues once the last response was read
+	deadline := time.Now().Add(time.Second)
+	for {
+		eventAlertQueuesMutex.Lock()
+		remaining := len(eventAlertQueues)
+		eventAlertQueuesMutex.Unlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d event queues left after sending", remaining)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/store"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Rule event types produced by geometry rules
+const (
+	RuleEventZoneIntrusion = "zone_intrusion"
+	RuleEventLoitering     = "loitering"
+	RuleEventLineCrossing  = "line_crossing"
+	RuleEventOccupancy     = "occupancy"
+)
+
+// ruleTrackForget is how long per-track rule state is kept after the track was last seen
+const ruleTrackForget = 30 * time.Second
+
+// RuleEvent is produced when a rule fires, it is saved and alerted like a detection result
+type RuleEvent struct {
+	RuleID       string             `json:"rule_id"`
+	RuleName     string             `json:"rule_name"`
+	Type         string             `json:"type"`
+	Message      string             `json:"message"`
+	TrackID      int                `json:"track_id,omitempty"`
+	Count        int                `json:"count,omitempty"`
+	DwellSeconds float64            `json:"dwell_seconds,omitempty"`
+	Direction    string             `json:"direction,omitempty"`
+	Detections   []common.Detection `json:"detections"`
+	// Server and model of each detection of composite events, which may come from several bindings.
+	// Empty for geometry rules, their detections come from the evaluated binding.
+	Sources   []RuleEventSource `json:"sources,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	frame     []byte            // Frame the detections were seen on, nil for the evaluated frame
+}
+
+// RuleEventSource is the binding that reported a detection of a rule event
+type RuleEventSource struct {
+	ServerID  string `json:"server_id"` // Server or group ID of the binding
+	ModelType string `json:"model_type"`
+}
+
+// ruleTrackState is the state of a single track with respect to one rule
+type ruleTrackState struct {
+	inside    bool
+	enteredAt time.Time
+	fired     bool
+	hasLast   bool
+	lastX     float64
+	lastY     float64
+	lastSeen  time.Time
+}
+
+// ruleState is the state of one rule for the results of one server
+type ruleState struct {
+	tracks            map[int]*ruleTrackState
+	occupancyExceeded bool
+}
+
+// GeometryRuleEngine evaluates zone, line and occupancy rules of one camera
+type GeometryRuleEngine struct {
+	states map[string]*ruleState
+	mutex  sync.Mutex
+}
+
+// NewGeometryRuleEngine creates a new rule engine
+func NewGeometryRuleEngine() *GeometryRuleEngine {
+	return &GeometryRuleEngine{states: make(map[string]*ruleState)}
+}
+
+// Evaluate checks the tracked detections of one server result against the
+// rules and returns the events that fired. width and height are the frame
+// size used to scale the normalized rule geometry.
+func (e *GeometryRuleEngine) Evaluate(rules []store.GeometryRule, serverID string, detections []common.Detection,
+	width, height int, timestamp time.Time) []RuleEvent {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var events []RuleEvent
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled || (rule.ServerID != "" && rule.ServerID != serverID) {
+			continue
+		}
+
+		key := rule.ID + "/" + serverID
+		state, exists := e.states[key]
+		if !exists {
+			state = &ruleState{tracks: make(map[int]*ruleTrackState)}
+			e.states[key] = state
+		}
+
+		var matching []common.Detection
+		for _, det := range detections {
+			if rule.MatchesClass(det.Class) {
+				matching = append(matching, det)
+			}
+		}
+
+		switch rule.Type {
+		case store.GeometryRuleZone:
+			events = append(events, evaluateZone(rule, state, matching, width, height, timestamp)...)
+		case store.GeometryRuleLine:
+			events = append(events, evaluateLine(rule, state, matching, width, height, timestamp)...)
+		case store.GeometryRuleOccupancy:
+			if event := evaluateOccupancy(rule, state, matching, width, height, timestamp); event != nil {
+				events = append(events, *event)
+			}
+		}
+
+		for trackID, ts := range state.tracks {
+			if timestamp.Sub(ts.lastSeen) > ruleTrackForget {
+				delete(state.tracks, trackID)
+			}
+		}
+	}
+
+	return events
+}
+
+// trackState returns the rule state of a track, creating it if needed
+func (s *ruleState) trackState(trackID int, timestamp time.Time) *ruleTrackState {
+	ts, exists := s.tracks[trackID]
+	if !exists {
+		ts = &ruleTrackState{}
+		s.tracks[trackID] = ts
+	}
+	ts.lastSeen = timestamp
+	return ts
+}
+
+// scalePoints converts normalized rule points to pixel coordinates
+func scalePoints(points []store.Point, width, height int) [][2]float64 {
+	scaled := make([][2]float64, len(points))
+	for i, p := range points {
+		scaled[i] = [2]float64{p.X * float64(width), p.Y * float64(height)}
+	}
+	return scaled
+}
+
+// evaluateZone fires once per track when it stayed inside the zone for the minimum dwell time
+func evaluateZone(rule *store.GeometryRule, state *ruleState, detections []common.Detection,
+	width, height int, timestamp time.Time) []RuleEvent {
+	polygon := scalePoints(rule.Polygon, width, height)
+
+	var events []RuleEvent
+	for _, det := range detections {
+		if det.TrackID == 0 {
+			continue
+		}
+		ts := state.trackState(det.TrackID, timestamp)
+		x, y := det.BottomCenter()
+		if !common.PointInPolygon(x, y, polygon) {
+			ts.inside = false
+			ts.fired = false
+			continue
+		}
+		if !ts.inside {
+			ts.inside = true
+			ts.enteredAt = timestamp
+		}
+
+		dwell := timestamp.Sub(ts.enteredAt).Seconds()
+		if ts.fired || dwell < rule.MinDwellSeconds {
+			continue
+		}
+		ts.fired = true
+
+		eventType := RuleEventZoneIntrusion
+		message := fmt.Sprintf("%s #%d entered zone %s", det.Class, det.TrackID, rule.Name)
+		if rule.MinDwellSeconds > 0 {
+			eventType = RuleEventLoitering
+			message = fmt.Sprintf("%s #%d stayed in zone %s for %.0fs", det.Class, det.TrackID, rule.Name, dwell)
+		}
+		events = append(events, RuleEvent{
+			RuleID:       rule.ID,
+			RuleName:     rule.Name,
+			Type:         eventType,
+			Message:      message,
+			TrackID:      det.TrackID,
+			DwellSeconds: dwell,
+			Detections:   []common.Detection{det},
+			Timestamp:    timestamp,
+		})
+	}
+	return events
+}
+
+// evaluateLine fires when the ground point of a track crosses the tripwire in the configured direction
+func evaluateLine(rule *store.GeometryRule, state *ruleState, detections []common.Detection,
+	width, height int, timestamp time.Time) []RuleEvent {
+	line := scalePoints(rule.Line, width, height)
+
+	var events []RuleEvent
+	for _, det := range detections {
+		if det.TrackID == 0 {
+			continue
+		}
+		ts := state.trackState(det.TrackID, timestamp)
+		x, y := det.BottomCenter()
+		side := common.SideOfLine(line[0][0], line[0][1], line[1][0], line[1][1], x, y)
+		if side == 0 {
+			// exactly on the line, wait until the track leaves it on either side
+			continue
+		}
+		lastX, lastY, hadLast := ts.lastX, ts.lastY, ts.hasLast
+		ts.lastX, ts.lastY, ts.hasLast = x, y, true
+		if !hadLast || !common.SegmentsIntersect([2]float64{lastX, lastY}, [2]float64{x, y}, line[0], line[1]) {
+			continue
+		}
+
+		direction := store.LineDirectionLeftToRight
+		if side < 0 {
+			direction = store.LineDirectionRightToLeft
+		}
+		if rule.Direction != "" && rule.Direction != store.LineDirectionAny && rule.Direction != direction {
+			continue
+		}
+
+		events = append(events, RuleEvent{
+			RuleID:     rule.ID,
+			RuleName:   rule.Name,
+			Type:       RuleEventLineCrossing,
+			Message:    fmt.Sprintf("%s #%d crossed line %s (%s)", det.Class, det.TrackID, rule.Name, direction),
+			TrackID:    det.TrackID,
+			Direction:  direction,
+			Detections: []common.Detection{det},
+			Timestamp:  timestamp,
+		})
+	}
+	return events
+}
+
+// evaluateOccupancy fires when the number of objects inside the zone rises above the maximum
+func evaluateOccupancy(rule *store.GeometryRule, state *ruleState, detections []common.Detection,
+	width, height int, timestamp time.Time) *RuleEvent {
+	polygon := scalePoints(rule.Polygon, width, height)
+
+	var inside []common.Detection
+	for _, det := range detections {
+		x, y := det.BottomCenter()
+		if common.PointInPolygon(x, y, polygon) {
+			inside = append(inside, det)
+		}
+	}
+
+	if len(inside) <= rule.MaxCount {
+		state.occupancyExceeded = false
+		return nil
+	}
+	if state.occupancyExceeded {
+		return nil
+	}
+	state.occupancyExceeded = true
+
+	return &RuleEvent{
+		RuleID:     rule.ID,
+		RuleName:   rule.Name,
+		Type:       RuleEventOccupancy,
+		Message:    fmt.Sprintf("%d objects in zone %s (max %d)", len(inside), rule.Name, rule.MaxCount),
+		Count:      len(inside),
+		Detections: inside,
+		Timestamp:  timestamp,
+	}
+}
+
+// Geometry rule engines per camera
+var ruleEngines = make(map[string]*GeometryRuleEngine)
+var ruleEnginesMutex sync.Mutex
+
+// getGeometryRuleEngine returns the rule engine of a camera, creating it if needed
+func getGeometryRuleEngine(cameraID string) *GeometryRuleEngine {
+	ruleEnginesMutex.Lock()
+	defer ruleEnginesMutex.Unlock()
+	engine, exists := ruleEngines[cameraID]
+	if !exists {
+		engine = NewGeometryRuleEngine()
+		ruleEngines[cameraID] = engine
+	}
+	return engine
+}
+
+// clearGeometryRuleEngine drops the rule state of a deleted camera
+func clearGeometryRuleEngine(cameraID string) {
+	ruleEnginesMutex.Lock()
+	defer ruleEnginesMutex.Unlock()
+	delete(ruleEngines, cameraID)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ruleFrameSize is the frame size of rule tests, rule points are scaled to it
+const ruleFrameSize = 1000
+
+// standing returns a tracked person whose bottom center, the ground point of rules, is at x, y
+func standing(trackID, x, y int) common.Detection {
+	return common.Detection{Class: "person", Confidence: 0.9, TrackID: trackID, X1: x - 20, Y1: y - 80, X2: x + 20, Y2: y}
+}
+
+// centerZone is the square between 0.2 and 0.8 of the frame
+var centerZone = []store.Point{{X: 0.2, Y: 0.2}, {X: 0.8, Y: 0.2}, {X: 0.8, Y: 0.8}, {X: 0.2, Y: 0.8}}
+
+// ruleTester feeds frames of one server to a rule engine
+type ruleTester struct {
+	t      *testing.T
+	engine *GeometryRuleEngine
+	rules  []store.GeometryRule
+	frame  int
+}
+
+func newRuleTester(t *testing.T, rules ...store.GeometryRule) *ruleTester {
+	for i := range rules {
+		rules[i].Enabled = true
+		if rules[i].ID == "" {
+			rules[i].ID = "rule_" + rules[i].Name
+		}
+	}
+	return &ruleTester{t: t, engine: NewGeometryRuleEngine(), rules: rules}
+}
+
+// next evaluates the next frame, frames are 200ms apart
+func (rt *ruleTester) next(detections ...common.Detection) []RuleEvent {
+	return rt.at(frameTime(rt.frame), detections...)
+}
+
+// at evaluates a frame captured at the given time
+func (rt *ruleTester) at(timestamp time.Time, detections ...common.Detection) []RuleEvent {
+	rt.frame++
+	return rt.engine.Evaluate(rt.rules, "srv_1", detections, ruleFrameSize, ruleFrameSize, timestamp)
+}
+
+// expect checks the types of the events of a frame
+func (rt *ruleTester) expect(events []RuleEvent, types ...string) {
+	rt.t.Helper()
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Type)
+	}
+	if strings.Join(got, ",") != strings.Join(types, ",") {
+		rt.t.Fatalf("frame %d: events %v, want %v", rt.frame, got, types)
+	}
+}
+
+func TestZoneIntrusionFiresOncePerVisit(t *testing.T) {
+	rt := newRuleTester(t, store.GeometryRule{Name: "yard", Type: store.GeometryRuleZone, Polygon: centerZone})
+
+	rt.expect(rt.next(standing(1, 100, 500)))
+	events := rt.next(standing(1, 300, 500))
+	rt.expect(events, RuleEventZoneIntrusion)
+	if events[0].TrackID != 1 || events[0].RuleID != "rule_yard" || len(events[0].Detections) != 1 {
+		t.Errorf("event %+v", events[0])
+	}
+	rt.expect(rt.next(standing(1, 400, 500)))
+
+	// leaving and coming back is a new intrusion
+	rt.expect(rt.next(standing(1, 900, 500)))
+	rt.expect(rt.next(standing(1, 500, 500)), RuleEventZoneIntrusion)
+
+	// another track fires on its own
+	rt.expect(rt.next(standing(1, 500, 500), standing(2, 600, 600)), RuleEventZoneIntrusion)
+}
+
+func TestZoneBoundaryAndUntrackedDetections(t *testing.T) {
+	rt := newRuleTester(t, store.GeometryRule{Name: "yard", Type: store.GeometryRuleZone, Polygon: centerZone})
+
+	// untracked detections have no state to fire once on
+	rt.expect(rt.next(standing(0, 500, 500)))
+	// the right edge is outside, the left edge inside
+	rt.expect(rt.next(standing(1, 800, 500)))
+	rt.expect(rt.next(standing(2, 200, 500)), RuleEventZoneIntrusion)
+}
+
+func TestLoiteringNeedsMinimumDwell(t *testing.T) {
+	rt := newRuleTester(t, store.GeometryRule{Name: "gate", Type: store.GeometryRuleZone, Polygon: centerZone, MinDwellSeconds: 1})
+
+	// entering at frame 0, 5 frames of 200ms later the track stayed 1s
+	for i := 0; i < 5; i++ {
+		rt.expect(rt.next(standing(1, 500, 500)))
+	}
+	events := rt.next(standing(1, 500, 500))
+	rt.expect(events, RuleEventLoitering)
+	if events[0].DwellSeconds != 1 {
+		t.Errorf("dwell %v, want 1", events[0].DwellSeconds)
+	}
+	rt.expect(rt.next(standing(1, 500, 500)))
+
+	// stepping out restarts the dwell time
+	rt.expect(rt.next(standing(1, 950, 500)))
+	for i := 0; i < 5; i++ {
+		rt.expect(rt.next(standing(1, 500, 500)))
+	}
+	rt.expect(rt.next(standing(1, 500, 500)), RuleEventLoitering)
+}
+
+func TestLineCrossingDirection(t *testing.T) {
+	// a horizontal line drawn left to right, walking down the image crosses it from left to right
+	line := []store.Point{{X: 0.1, Y: 0.5}, {X: 0.9, Y: 0.5}}
+	rt := newRuleTester(t,
+		store.GeometryRule{Name: "down", Type: store.GeometryRuleLine, Line: line, Direction: store.LineDirectionLeftToRight},
+		store.GeometryRule{Name: "any", Type: store.GeometryRuleLine, Line: line},
+	)
+
+	rt.expect(rt.next(standing(1, 500, 400)))
+	events := rt.next(standing(1, 500, 600))
+	rt.expect(events, RuleEventLineCrossing, RuleEventLineCrossing)
+	for _, event := range events {
+		if event.Direction != store.LineDirectionLeftToRight {
+			t.Errorf("rule %s: direction %q", event.RuleName, event.Direction)
+		}
+	}
+
+	// walking back up only matches the rule without direction
+	events = rt.next(standing(1, 500, 400))
+	rt.expect(events, RuleEventLineCrossing)
+	if events[0].RuleName != "any" || events[0].Direction != store.LineDirectionRightToLeft {
+		t.Errorf("event %+v", events[0])
+	}
+	rt.expect(rt.next(standing(1, 500, 300)))
+}
+
+func TestLineCrossingEdgeCases(t *testing.T) {
+	line := []store.Point{{X: 0.1, Y: 0.5}, {X: 0.9, Y: 0.5}}
+	rt := newRuleTester(t, store.GeometryRule{Name: "line", Type: store.GeometryRuleLine, Line: line})
+
+	// the first position of a track is not a crossing
+	rt.expect(rt.next(standing(1, 500, 600)))
+
+	// stopping on the line is no crossing yet, leaving it on the other side is one
+	rt.expect(rt.next(standing(2, 500, 400)))
+	rt.expect(rt.next(standing(2, 500, 500)))
+	rt.expect(rt.next(standing(2, 500, 600)), RuleEventLineCrossing)
+
+	// stepping back to the side it came from is not
+	rt.expect(rt.next(standing(3, 500, 400)))
+	rt.expect(rt.next(standing(3, 500, 500)))
+	rt.expect(rt.next(standing(3, 500, 450)))
+
+	// passing beside the end of the line is not
+	rt.expect(rt.next(standing(4, 950, 400)))
+	rt.expect(rt.next(standing(4, 950, 600)))
+}
+
+func TestLineCrossingForgetsLostTracks(t *testing.T) {
+	line := []store.Point{{X: 0.1, Y: 0.5}, {X: 0.9, Y: 0.5}}
+	rt := newRuleTester(t, store.GeometryRule{Name: "line", Type: store.GeometryRuleLine, Line: line})
+
+	start := frameTime(0)
+	rt.expect(rt.at(start, standing(1, 500, 400)))
+	// a track seen again within the forget time still crosses
+	rt.expect(rt.at(start.Add(ruleTrackForget/2), standing(1, 500, 600)), RuleEventLineCrossing)
+
+	// the track left the view and its ID comes back on the other side after its state was forgotten
+	rt.expect(rt.at(start.Add(ruleTrackForget), standing(2, 500, 400)))
+	rt.expect(rt.at(start.Add(3 * ruleTrackForget)))
+	rt.expect(rt.at(start.Add(3*ruleTrackForget+time.Second), standing(1, 500, 400)))
+}
+
+func TestOccupancyThreshold(t *testing.T) {
+	rt := newRuleTester(t, store.GeometryRule{Name: "room", Type: store.GeometryRuleOccupancy, Polygon: centerZone, MaxCount: 2})
+
+	two := []common.Detection{standing(1, 300, 300), standing(2, 400, 400)}
+	three := append([]common.Detection{standing(3, 500, 500)}, two...)
+
+	// max_count objects are allowed, objects outside the zone do not count
+	rt.expect(rt.next(append(two, standing(4, 900, 900))...))
+	events := rt.next(three...)
+	rt.expect(events, RuleEventOccupancy)
+	if events[0].Count != 3 || len(events[0].Detections) != 3 {
+		t.Errorf("event counted %d objects with %d boxes", events[0].Count, len(events[0].Detections))
+	}
+
+	// staying above the maximum does not repeat, dropping to it and rising again does
+	rt.expect(rt.next(three...))
+	rt.expect(rt.next(two...))
+	rt.expect(rt.next(three...), RuleEventOccupancy)
+
+	// occupancy counts untracked detections too
+	untracked := []common.Detection{standing(0, 300, 300), standing(0, 400, 400), standing(0, 500, 500)}
+	rt.expect(rt.next(two...))
+	rt.expect(rt.next(untracked...), RuleEventOccupancy)
+}
+
+func TestGeometryRuleFilters(t *testing.T) {
+	rt := newRuleTester(t,
+		store.GeometryRule{Name: "other_server", Type: store.GeometryRuleZone, Polygon: centerZone, ServerID: "srv_2"},
+		store.GeometryRule{Name: "vehicles", Type: store.GeometryRuleZone, Polygon: centerZone, Classes: []string{"car"}},
+		store.GeometryRule{Name: "disabled", Type: store.GeometryRuleZone, Polygon: centerZone},
+	)
+	rt.rules[2].Enabled = false
+
+	rt.expect(rt.next(standing(1, 500, 500)))
+	car := standing(2, 500, 500)
+	car.Class = "car"
+	events := rt.next(car)
+	rt.expect(events, RuleEventZoneIntrusion)
+	if events[0].RuleName != "vehicles" {
+		t.Errorf("rule %s fired", events[0].RuleName)
+	}
+}
+
+func TestGeometryRuleStateIsPerRule(t *testing.T) {
+	rt := newRuleTester(t,
+		store.GeometryRule{Name: "a", Type: store.GeometryRuleZone, Polygon: centerZone},
+		store.GeometryRule{Name: "b", Type: store.GeometryRuleZone, Polygon: centerZone},
+	)
+	rt.expect(rt.next(standing(1, 500, 500)), RuleEventZoneIntrusion, RuleEventZoneIntrusion)
+	rt.expect(rt.next(standing(1, 500, 500)))
+}
+
+func TestValidateCameraConfigRejectsDuplicateRuleIDs(t *testing.T) {
+	exists := func(string) bool { return true }
+	zone := store.GeometryRule{ID: "rule_a", Name: "zone", Type: store.GeometryRuleZone, Polygon: centerZone}
+	composite := store.CompositeRule{ID: "rule_a", Name: "composite", Condition: store.CompositeCondition{Op: store.CompositeOpDetected, Select: &store.DetectionSelector{ModelType: "helmet"}}}
+
+	tests := []struct {
+		name   string
+		camera store.CameraConfig
+		want   string
+	}{
+		{"geometry", store.CameraConfig{GeometryRules: []store.GeometryRule{zone, zone}}, "geometry_rules: rule id \"rule_a\" is used twice"},
+		{"composite", store.CameraConfig{GeometryRules: []store.GeometryRule{zone}, CompositeRules: []store.CompositeRule{composite}},
+			"composite_rules: rule id \"rule_a\" is used twice"},
+		{"generated", store.CameraConfig{GeometryRules: []store.GeometryRule{{Name: "a", Type: store.GeometryRuleZone, Polygon: centerZone},
+			{Name: "b", Type: store.GeometryRuleZone, Polygon: centerZone}}}, ""},
+	}
+	for _, tt := range tests {
+		err := validateCameraConfig(&tt.camera, exists, exists)
+		if tt.want == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.want != "" && (err == nil || err.Error() != tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
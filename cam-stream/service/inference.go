@@ -13,6 +13,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -170,7 +171,8 @@ func (ic *InferenceClient) DetectObjects(imageData []byte, modelType string) ([]
 		img.Height = 1080
 	}
 
-	// Convert inference server results to our Detection format
+	// Convert inference server results to our Detection format, unregistered model types use the defaults
+	model, _ := store.SafeGetModelDefinition(modelType)
 	var detections []common.Detection
 	for _, result := range response.Results {
 		// Use class name from server response, or default to "unknown_class"
@@ -180,29 +182,25 @@ func (ic *InferenceClient) DetectObjects(imageData []byte, modelType string) ([]
 			*className = "unknown_class" // Default class name
 		}
 
-		// TODO: this is really weird.
-		if modelType == string(config.ModelTypeSmoke) && *className == string(config.ModelTypeFire) {
-			log.Warn("filtering out fire class from smoke detection server")
-			continue
-		}
-		if modelType == string(config.ModelTypeFire) && *className == string(config.ModelTypeSmoke) {
-			log.Warn("filtering out smoke class from fire detection server")
+		if model.IsIgnoredClass(*className) {
+			log.Warn(fmt.Sprintf("filtering out %s class from %s detection server", *className, modelType))
 			continue
 		}
 
 		confidence := 0.0
-		// TODO: use enumerate instead.
-		validRegularScore := modelType != string(config.ModelTypeTshirt) && result.Score > 0 && result.Location.Left > 0
-		validTshirtScore := modelType == string(config.ModelTypeTshirt) && result.DetScore != nil && result.ClsScore != nil && *result.DetScore > 0 && *result.ClsScore > 0
-		if validTshirtScore {
-			// Use classification score for tshirt detection confidence
+		switch model.GetScoreStrategy() {
+		case store.ScoreClassification:
+			if result.DetScore == nil || result.ClsScore == nil || *result.DetScore <= 0 || *result.ClsScore <= 0 {
+				continue
+			}
 			confidence = *result.ClsScore
-			log.Info(fmt.Sprintf("tshirt detection scores - det: %.3f, cls: %.3f", *result.DetScore, *result.ClsScore))
-		} else if validRegularScore {
+			log.Info(fmt.Sprintf("%s detection scores - det: %.3f, cls: %.3f", modelType, *result.DetScore, *result.ClsScore))
+		default:
+			if result.Score <= 0 || result.Location.Left <= 0 {
+				// Skip invalid detection result silently
+				continue
+			}
 			confidence = result.Score
-		} else {
-			// Skip invalid detection result silently
-			continue
 		}
 
 		// Python server returns normalized coordinates [0,1]
@@ -256,60 +254,92 @@ type ModelResult struct {
 	// image sent to their platform.
 	DisplayResultImage []byte `json:"display_image"`
 	// used for displayed on debug platform.
-	DisplayDebugImage []byte `json:"debug_img"`
-	OriginalImage     []byte `json:"-"` // Original image without detection boxes (for DEBUG mode)
-	Error             error  `json:"-"`
+	DisplayDebugImage []byte     `json:"debug_img"`
+	OriginalImage     []byte     `json:"-"` // Original image without detection boxes (for DEBUG mode)
+	RuleEvent         *RuleEvent `json:"rule_event,omitempty"`
+	CapturedAt        time.Time  `json:"captured_at"` // Capture time of the frame
+	Error             error      `json:"-"`
 }
 
-func ProcessFrameWithAsyncInference(frameData []byte, cameraConfig *store.CameraConfig, outputDir string) {
+// ProcessFrameWithAsyncInference runs all bindings of a camera on a frame captured at capturedAt
+func ProcessFrameWithAsyncInference(frameData []byte, capturedAt time.Time, cameraConfig *store.CameraConfig, outputDir string) {
 	// Launch independent goroutines for each inference server
 	for _, binding := range cameraConfig.InferenceServerBindings {
-		// Use thread-safe access to get server information
-		server, exists := store.SafeGetInferenceServer(binding.ServerID)
+		// Use thread-safe access to get server information, group bindings get the group's stand-in
+		server, exists := getTargetServer(binding.TargetID())
 		if !exists {
-			log.Warn(fmt.Sprintf("inference server %s not found for camera %s", binding.ServerID, cameraConfig.ID))
+			log.Warn(fmt.Sprintf("inference server %s not found for camera %s", binding.TargetID(), cameraConfig.ID))
 			continue
 		}
 		if !server.Enabled {
 			log.Warn(fmt.Sprintf("skipping disabled inference server %s", server.Name))
 			continue
 		}
+		// Suspended until the health prober sees it (or a group member) recover, logged on state changes only
+		if !isTargetAvailable(&binding, server) {
+			continue
+		}
 
 		// For fall detection, skip frame-based processing since we now use active polling
 		if server.ModelType == string(config.ModelTypeFall) {
 			continue
 		}
 
+		schedule := scheduleStatus(cameraConfig, &binding, capturedAt)
+		if !schedule.InferenceActive {
+			continue
+		}
+
 		// Launch independent async processing for each server
-		go processInferenceServerAsync(frameData, server, &binding, cameraConfig, outputDir)
+		go processInferenceServerAsync(frameData, capturedAt, server, &binding, cameraConfig, outputDir, schedule.AlertsActive)
 	}
 }
 
-// processInferenceServerAsync handles the complete pipeline for a single inference server asynchronously
-func processInferenceServerAsync(frameData []byte, server *store.InferenceServer, binding *store.InferenceServerBinding,
-	cameraConfig *store.CameraConfig, outputDir string) {
+// processInferenceServerAsync handles the complete pipeline for a single inference server asynchronously,
+// results are always saved but alerts are only sent while alertsActive is set
+func processInferenceServerAsync(frameData []byte, timestamp time.Time, server *store.InferenceServer,
+	binding *store.InferenceServerBinding, cameraConfig *store.CameraConfig, outputDir string, alertsActive bool) {
 	// Create frame data copies for this goroutine to avoid race conditions
 	frameDataCopy := make([]byte, len(frameData))
 	copy(frameDataCopy, frameData)
-	frameDataCopy2 := make([]byte, len(frameData))
-	copy(frameDataCopy2, frameData)
 
-	detections := getResultFromInferenceServer(frameDataCopy, server, binding)
+	detections, err := getResultFromInferenceServer(frameDataCopy, cameraConfig.ID, server, binding)
+	if err != nil {
+		// a failed request says nothing about the scene, keep tracks, rules and events as they are
+		log.Warn(fmt.Sprintf("skipping frame of camera %s: %v", cameraConfig.Name, err))
+		return
+	}
+	// feed empty results too so tracks of vanished objects expire
+	detections, inOrder := getTracker(cameraConfig.ID, server.ID).Update(detections, timestamp)
+	if !inOrder {
+		// tracks, rules and events already moved past this frame
+		log.Warn(fmt.Sprintf("dropping result of camera %s on %s, a newer frame finished first", cameraConfig.Name, server.Name))
+		return
+	}
+	updateOverlay(cameraConfig.ID, server, detections)
+	if len(cameraConfig.GeometryRules) > 0 {
+		processGeometryRules(frameDataCopy, detections, server, binding, cameraConfig, outputDir, timestamp, alertsActive)
+	}
+	if len(cameraConfig.CompositeRules) > 0 {
+		processCompositeRules(frameDataCopy, detections, server, binding, cameraConfig, outputDir, timestamp, alertsActive)
+	}
+	if len(detections) == 0 || !alertsActive {
+		// muted bindings resolve their events like empty frames
+		observeEventLifecycle(cameraConfig, server, binding, nil, nil, timestamp)
+	}
 	if len(detections) == 0 {
 		return
 	}
-
-	// Draw detections on image copy (without confidence labels)
-	displayedImage, err := common.DrawDetectionsWithServerInfo(frameDataCopy, detections, cameraConfig.Name, false, server.Name)
-	if err != nil {
-		log.Warn(fmt.Sprintf("failed to draw results for model %q: %v", server.ModelType, err))
+	if binding.RulesOnly {
 		return
 	}
-	// Draw debug image with confidence labels and server info
-	// TODO: temporarily controlled by `globalDebugMode`.
-	debugImage, err := common.DrawDetectionsWithServerInfo(frameDataCopy2, detections, cameraConfig.Name, config.GlobalDebugMode, server.Name)
+
+	// Draw the displayed image without labels and the debug image with labels from one decode
+	// TODO: debug labels temporarily controlled by `globalDebugMode`.
+	displayedImage, debugImage, err := common.DrawDetectionImages(frameDataCopy, detections,
+		detectionStyles(server, detections, config.GlobalDebugMode), resultBanner(cameraConfig.Name, timestamp))
 	if err != nil {
-		log.Warn(fmt.Sprintf("failed to draw debug image for model %q: %v", server.ModelType, err))
+		log.Warn(fmt.Sprintf("failed to draw results for model %q: %v", server.ModelType, err))
 		return
 	}
 
@@ -321,58 +351,228 @@ func processInferenceServerAsync(frameData []byte, server *store.InferenceServer
 
 	modelResult := &ModelResult{
 		ModelType:          server.ModelType,
-		ServerID:           binding.ServerID,
+		ServerID:           binding.TargetID(),
 		Detections:         detections,
 		DisplayResultImage: displayedImage,
 		DisplayDebugImage:  debugImage,
 		OriginalImage:      originalImageCopy,
+		CapturedAt:         timestamp,
 		Error:              nil,
 	}
 
+	// lifecycle events replace per-detection alerts
+	if alertsActive {
+		observeEventLifecycle(cameraConfig, server, binding, detections, displayedImage, timestamp)
+	}
+
 	// save result and send alerts at the same time.
 	go func() {
-		saveModelResult(cameraConfig.Name, modelResult, outputDir)
+		imagePath := saveModelResult(cameraConfig, modelResult, outputDir)
+		if !alertsActive || binding.Lifecycle.IsEnabled() {
+			return
+		}
 		alertImageData := make([]byte, len(modelResult.DisplayResultImage))
 		copy(alertImageData, modelResult.DisplayResultImage)
-		sendDetectionAlerts(alertImageData, modelResult.Detections, cameraConfig.Name, modelResult.ModelType)
+		sendDetectionAlerts(alertImageData, modelResult.Detections, &AlertSource{
+			Camera:     cameraConfig,
+			Server:     server,
+			Detections: modelResult.Detections,
+			ImagePath:  imagePath,
+			CapturedAt: modelResult.CapturedAt,
+		})
 	}()
 
 }
 
-// saveModelResult saves a single model result to file
-func saveModelResult(cameraName string, result *ModelResult, outputDir string) {
+// processGeometryRules evaluates the camera's geometry rules and saves and alerts every fired event
+func processGeometryRules(frameData []byte, detections []common.Detection, server *store.InferenceServer,
+	binding *store.InferenceServerBinding, cameraConfig *store.CameraConfig, outputDir string, timestamp time.Time, alertsActive bool) {
+	imgCfg, err := jpeg.DecodeConfig(bytes.NewReader(frameData))
+	if err != nil {
+		log.Warn(fmt.Sprintf("failed to decode image config for geometry rules: %v", err))
+		return
+	}
+
+	events := getGeometryRuleEngine(cameraConfig.ID).Evaluate(cameraConfig.GeometryRules, server.ID, detections,
+		imgCfg.Width, imgCfg.Height, timestamp)
+	handleRuleEvents(frameData, events, server, binding, cameraConfig, outputDir, alertsActive)
+}
+
+// processCompositeRules feeds the result of a binding into the camera's composite rules
+// and saves and alerts every rule that started to hold, drawn on the current frame
+func processCompositeRules(frameData []byte, detections []common.Detection, server *store.InferenceServer,
+	binding *store.InferenceServerBinding, cameraConfig *store.CameraConfig, outputDir string, timestamp time.Time, alertsActive bool) {
+	events := getCompositeRuleEngine(cameraConfig.ID).Evaluate(cameraConfig.CompositeRules, server.ID, server.ModelType,
+		detections, timestamp, frameData)
+	handleRuleEvents(frameData, events, server, binding, cameraConfig, outputDir, alertsActive)
+}
+
+// handleRuleEvents draws, saves and alerts the events fired by rules
+func handleRuleEvents(frameData []byte, events []RuleEvent, server *store.InferenceServer,
+	binding *store.InferenceServerBinding, cameraConfig *store.CameraConfig, outputDir string, alertsActive bool) {
+	for i := range events {
+		event := &events[i]
+		log.Info(fmt.Sprintf("rule event on camera %s: %s", cameraConfig.Name, event.Message))
+		frameData := frameData
+		if event.frame != nil {
+			frameData = event.frame
+		}
+
+		// composite events are filed and alerted under the binding of their first detection
+		source, serverID := server, binding.TargetID()
+		if len(event.Sources) > 0 {
+			source = ruleEventSourceServer(event.Sources[0])
+			serverID = source.ID
+		}
+
+		displayedImage, debugImage, err := common.DrawDetectionImages(frameData, event.Detections,
+			ruleEventStyles(event, server), resultBanner(cameraConfig.Name, event.Timestamp))
+		if err != nil {
+			log.Warn(fmt.Sprintf("failed to draw rule event %q: %v", event.RuleName, err))
+			continue
+		}
+
+		var originalImageCopy []byte
+		if config.GlobalDebugMode {
+			originalImageCopy = make([]byte, len(frameData))
+			copy(originalImageCopy, frameData)
+		}
+
+		modelResult := &ModelResult{
+			ModelType:          source.ModelType,
+			ServerID:           serverID,
+			Detections:         event.Detections,
+			DisplayResultImage: displayedImage,
+			DisplayDebugImage:  debugImage,
+			OriginalImage:      originalImageCopy,
+			RuleEvent:          event,
+			CapturedAt:         event.Timestamp,
+		}
+
+		go func() {
+			imagePath := saveModelResult(cameraConfig, modelResult, outputDir)
+			if !alertsActive {
+				return
+			}
+			sendRuleEventAlert(modelResult.DisplayResultImage, modelResult.RuleEvent, &AlertSource{
+				Camera:     cameraConfig,
+				Server:     source,
+				Detections: modelResult.Detections,
+				RuleEvent:  modelResult.RuleEvent,
+				ImagePath:  imagePath,
+				CapturedAt: modelResult.CapturedAt,
+			})
+		}()
+	}
+}
+
+// ruleEventSourceServer returns the server or group stand-in of a rule event source with the model type
+// of the detection, a stand-in of that model when the binding target was deleted since
+func ruleEventSourceServer(src RuleEventSource) *store.InferenceServer {
+	server, exists := getTargetServer(src.ServerID)
+	if !exists {
+		return &store.InferenceServer{ID: src.ServerID, Name: src.ServerID, ModelType: src.ModelType, Enabled: true}
+	}
+	source := *server
+	source.ModelType = src.ModelType
+	return &source
+}
+
+// ruleEventStyles resolves the drawing styles of the detections of a rule event, detections of composite
+// events are styled by the model that reported them
+func ruleEventStyles(event *RuleEvent, server *store.InferenceServer) []common.BoxStyle {
+	if len(event.Sources) != len(event.Detections) {
+		return detectionStyles(server, event.Detections, config.GlobalDebugMode)
+	}
+	styles := make([]common.BoxStyle, len(event.Detections))
+	for i := range event.Detections {
+		source := ruleEventSourceServer(event.Sources[i])
+		styles[i] = detectionStyles(source, event.Detections[i:i+1], config.GlobalDebugMode)[0]
+	}
+	return styles
+}
+
+// saveModelResult saves a single model result to file and returns the image path relative to outputDir,
+// empty if nothing was saved
+func saveModelResult(camera *store.CameraConfig, result *ModelResult, outputDir string) string {
 	// For fall detection, ensure exactly one detection
 	if result.ModelType == string(config.ModelTypeFall) && len(result.Detections) != 1 {
 		log.Warn(fmt.Sprintf("fall detection ModelResult should contain exactly one detection, got %d detections, skipping", len(result.Detections)))
-		return
+		return ""
 	}
 
 	// Generate filename and paths
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("%s_%s_detection.jpg", timestamp, result.ModelType)
+	capturedAt := result.CapturedAt
+	if capturedAt.IsZero() {
+		capturedAt = time.Now()
+	}
+	filename := resultFileName(capturedAt, camera.ID, result.ModelType, "detection")
+	if result.RuleEvent != nil {
+		filename = resultFileName(capturedAt, camera.ID, result.ModelType, result.RuleEvent.Type)
+	}
 	serverDir := fmt.Sprintf("%s/%s", outputDir, result.ServerID)
 
 	if err := os.MkdirAll(serverDir, 0755); err != nil {
 		log.Warn(fmt.Sprintf("failed to create directory for server %s: %v", result.ServerID, err))
-		return
+		return ""
 	}
 
-	filePath := fmt.Sprintf("%s/%s", serverDir, filename)
-	if err := os.WriteFile(filePath, result.DisplayDebugImage, 0644); err != nil {
+	filename, err := createResultImage(serverDir, filename, result.DisplayDebugImage)
+	if err != nil {
 		log.Warn(fmt.Sprintf("failed to save detection image for model %s: %v", result.ModelType, err))
-		return
+		return ""
 	}
+	filePath := fmt.Sprintf("%s/%s", serverDir, filename)
 
 	log.Info(fmt.Sprintf("saved detection image for camera %s, model %s to %s (detections: %d)",
-		cameraName, result.ModelType, filePath, len(result.Detections)))
+		camera.Name, result.ModelType, filePath, len(result.Detections)))
+
+	// Save detections including track IDs next to the image
+	saveResultMetadata(camera, result, filePath)
 
 	// Save debug data if enabled
 	saveDebugDataAsync(result, filename)
+
+	return result.ServerID + "/" + filename
 }
 
-// saveDebugDataAsync saves original image and YOLO labels for DEBUG mode
+// resultFileTime formats the time prefix of result file names in the configured time zone, with milliseconds
+func resultFileTime(t time.Time) string {
+	return strings.Replace(config.InTimezone(t).Format("20060102_150405.000"), ".", "_", 1)
+}
+
+// ResultMetadata is stored as JSON next to each saved detection image
+type ResultMetadata struct {
+	CameraID   string             `json:"camera_id,omitempty"` // Missing in results saved by older versions
+	CameraName string             `json:"camera_name"`
+	ModelType  string             `json:"model_type"`
+	ServerID   string             `json:"server_id"`
+	Detections []common.Detection `json:"detections"`
+	RuleEvent  *RuleEvent         `json:"rule_event,omitempty"`
+	CapturedAt time.Time          `json:"captured_at"`
+	Verdicts   []DetectionVerdict `json:"verdicts,omitempty"` // Operator verdicts on the detections
+}
+
+// saveResultMetadata writes the detections of a saved image to <image>.json
+func saveResultMetadata(camera *store.CameraConfig, result *ModelResult, imagePath string) {
+	metadata := ResultMetadata{
+		CameraID:   camera.ID,
+		CameraName: camera.Name,
+		ModelType:  result.ModelType,
+		ServerID:   result.ServerID,
+		Detections: result.Detections,
+		RuleEvent:  result.RuleEvent,
+		CapturedAt: config.InTimezone(result.CapturedAt),
+	}
+	metadataPath := strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+	if err := writeResultMetadata(metadataPath, &metadata); err != nil {
+		log.Warn(fmt.Sprintf("failed to save result metadata: %v", err))
+	}
+}
+
+// saveDebugDataAsync saves original image and YOLO labels for DEBUG mode, the originals are the frames of dataset exports
 func saveDebugDataAsync(result *ModelResult, filename string) {
-	if config.GlobalDebugMode || result.OriginalImage == nil {
+	if !config.GlobalDebugMode || result.OriginalImage == nil {
 		return
 	}
 
@@ -409,11 +609,26 @@ func saveYoloLabelsAsync(result *ModelResult, debugDir, filename string) {
 
 	w := float64(imgCfg.Width)
 	h := float64(imgCfg.Height)
-	classIdx := config.GetClassIndexFromModelType(result.ModelType)
+	model, _ := store.SafeGetModelDefinition(result.ModelType)
 
 	// Build YOLO format lines
 	lines := make([]string, 0, len(result.Detections))
-	for _, det := range result.Detections {
+	for i, det := range result.Detections {
+		// detections of composite events are indexed by the model that reported them
+		modelType := result.ModelType
+		detModel := model
+		if result.RuleEvent != nil && len(result.RuleEvent.Sources) == len(result.Detections) {
+			modelType = result.RuleEvent.Sources[i].ModelType
+			detModel, _ = store.SafeGetModelDefinition(modelType)
+		}
+
+		// A label file without the box would mark the object as background
+		classIndex := detModel.ClassIndex(det.Class)
+		if classIndex < 0 {
+			log.Warn(fmt.Sprintf("not saving yolo label for %s, model %s does not list class %q", filename, modelType, det.Class))
+			return
+		}
+
 		// Convert pixel coordinates to normalized YOLO format
 		cx := (float64(det.X1) + float64(det.X2)) / 2.0 / w
 		cy := (float64(det.Y1) + float64(det.Y2)) / 2.0 / h
@@ -442,7 +657,7 @@ func saveYoloLabelsAsync(result *ModelResult, debugDir, filename string) {
 			bh = 1
 		}
 
-		lines = append(lines, fmt.Sprintf("%d %.6f %.6f %.6f %.6f", classIdx, cx, cy, bw, bh))
+		lines = append(lines, fmt.Sprintf("%d %.6f %.6f %.6f %.6f", classIndex, cx, cy, bw, bh))
 	}
 
 	// Save label file
@@ -457,24 +672,46 @@ func saveYoloLabelsAsync(result *ModelResult, debugDir, filename string) {
 	}
 }
 
-// This function never returns nil !!!
-func getResultFromInferenceServer(frameData []byte, server *store.InferenceServer, binding *store.InferenceServerBinding) []common.Detection {
-	client, err := NewInferenceClient(server.URL)
-	if err != nil {
-		log.Warn(fmt.Sprintf("failed to create client for server %s: %v", server.Name, err))
-		return []common.Detection{}
-	}
-
+// The detections are never nil without error. An error means no server answered, which callers
+// must not treat like an empty frame.
+// Group bindings send the frame to the member picked by the group's balancing and fail over to
+// the next healthy member when the request fails.
+func getResultFromInferenceServer(frameData []byte, cameraID string, server *store.InferenceServer,
+	binding *store.InferenceServerBinding) ([]common.Detection, error) {
 	// Process based on model type
 	if server.ModelType == string(config.ModelTypeFall) {
-		return []common.Detection{}
+		return []common.Detection{}, nil
 	}
-	detections, err := client.DetectObjects(frameData, server.ModelType)
-	if err != nil {
-		log.Warn(fmt.Sprintf("inference failed for server %s: %v", server.Name, err))
-		return detections
+
+	var lastErr error
+
+	for _, candidate := range inferenceCandidates(binding, server, cameraID) {
+		client, err := NewInferenceClient(candidate.URL)
+		if err != nil {
+			log.Warn(fmt.Sprintf("failed to create client for server %s: %v", candidate.Name, err))
+			lastErr = err
+			continue
+		}
+		beginInferenceRequest(candidate.ID)
+		start := time.Now()
+		detections, err := client.DetectObjects(frameData, server.ModelType)
+		endInferenceRequest(candidate.ID)
+		recordInferenceResult(candidate, time.Since(start), err)
+		if err != nil {
+			log.Warn(fmt.Sprintf("inference failed for server %s: %v", candidate.Name, err))
+			lastErr = err
+			continue
+		}
+		return filterByThreshold(detections, binding), nil
 	}
+	if lastErr == nil {
+		return nil, fmt.Errorf("no healthy server available for %s", server.Name)
+	}
+	return nil, fmt.Errorf("inference failed on %s: %v", server.Name, lastErr)
+}
 
+// filterByThreshold keeps the detections reaching the binding's threshold
+func filterByThreshold(detections []common.Detection, binding *store.InferenceServerBinding) []common.Detection {
 	// Check if any detection meets threshold
 	retDetections := []common.Detection{}
 	for _, detection := range detections {
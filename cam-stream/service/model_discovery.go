@@ -0,0 +1,228 @@
+package service
+
+import (
+	"cam-stream/common/config"
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	apiv1 "cam-stream/generated-go/api/v1"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// modelDiscoveryTimeout bounds a discovery request
+const modelDiscoveryTimeout = 5 * time.Second
+
+// errDiscoveryUnsupported means the host answered but does not offer a model list
+var errDiscoveryUnsupported = errors.New("server does not support model discovery")
+
+// DiscoveredModel is a model offered by an inference host
+type DiscoveredModel struct {
+	ModelType   string `json:"model_type"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	URL         string `json:"url,omitempty"` // Inference endpoint, only reported by HTTP hosts
+	// Existing inference server with this URL and model type, empty if not configured yet
+	ConfiguredServerID string `json:"configured_server_id,omitempty"`
+	Registered         bool   `json:"registered"` // Whether the model type is in the model registry
+}
+
+// DiscoveryResult lists the models of an inference host
+type DiscoveryResult struct {
+	Address       string            `json:"address"`
+	Protocol      string            `json:"protocol"` // "http" or "grpc"
+	ServerVersion string            `json:"server_version,omitempty"`
+	Models        []DiscoveredModel `json:"models"`
+}
+
+// findModel returns the discovered model of a type, nil if the host does not offer it
+func (r *DiscoveryResult) findModel(modelType string) *DiscoveredModel {
+	for i := range r.Models {
+		if r.Models[i].ModelType == modelType {
+			return &r.Models[i]
+		}
+	}
+	return nil
+}
+
+// modelTypes returns the offered model types for error messages
+func (r *DiscoveryResult) modelTypes() string {
+	var types []string
+	for _, model := range r.Models {
+		types = append(types, model.ModelType)
+	}
+	return strings.Join(types, ", ")
+}
+
+// httpModelList is the response of GET /models on HTTP inference hosts
+type httpModelList struct {
+	ServerVersion string `json:"server_version"`
+	Models        []struct {
+		ModelType   string `json:"model_type"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Version     string `json:"version"`
+		Path        string `json:"path"`
+	} `json:"models"`
+}
+
+// discoverModels lists the models of a host, "grpc://host:port" calls ListModels and
+// http(s) addresses, or bare host:port, call GET /models
+func discoverModels(address string) (*DiscoveryResult, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid address %q", address)
+	}
+
+	var result *DiscoveryResult
+	switch u.Scheme {
+	case "grpc":
+		result, err = discoverGRPCModels(u.Host)
+	case "http", "https":
+		result, err = discoverHTTPModels(u.Scheme + "://" + u.Host)
+	default:
+		return nil, fmt.Errorf("unsupported address scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Address = address
+	store.SafeReadDataStore(func() {
+		for i := range result.Models {
+			model := &result.Models[i]
+			_, model.Registered = store.Data.Models[model.ModelType]
+			for _, server := range store.Data.InferenceServers {
+				if server.ModelType == model.ModelType && model.URL != "" && sameURL(server.URL, model.URL) {
+					model.ConfiguredServerID = server.ID
+					break
+				}
+			}
+		}
+	})
+	return result, nil
+}
+
+// discoverHTTPModels calls GET /models on an HTTP inference host
+func discoverHTTPModels(baseURL string) (*DiscoveryResult, error) {
+	client := &http.Client{Timeout: modelDiscoveryTimeout}
+	resp, err := client.Get(baseURL + "/models")
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %v", baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model list: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, errDiscoveryUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model list returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var list httpModelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse model list: %v", err)
+	}
+
+	result := &DiscoveryResult{Protocol: "http", ServerVersion: list.ServerVersion, Models: []DiscoveredModel{}}
+	for _, model := range list.Models {
+		discovered := DiscoveredModel{
+			ModelType:   model.ModelType,
+			Name:        model.Name,
+			Description: model.Description,
+			Version:     model.Version,
+		}
+		if model.Path != "" {
+			discovered.URL = baseURL + "/" + strings.TrimPrefix(model.Path, "/")
+		}
+		result.Models = append(result.Models, discovered)
+	}
+	return result, nil
+}
+
+// discoverGRPCModels calls ListModels on a gRPC inference host
+func discoverGRPCModels(target string) (*DiscoveryResult, error) {
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc client for %s: %v", target, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelDiscoveryTimeout)
+	defer cancel()
+	resp, err := apiv1.NewModelInferenceServiceClient(conn).ListModels(ctx, &apiv1.ListModelsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models on %s: %v", target, err)
+	}
+
+	result := &DiscoveryResult{Protocol: "grpc", ServerVersion: resp.GetServerVersion(), Models: []DiscoveredModel{}}
+	for _, model := range resp.GetModels() {
+		result.Models = append(result.Models, DiscoveredModel{
+			ModelType:   grpcModelType(model.GetType()),
+			Name:        model.GetName(),
+			Description: model.GetDescription(),
+			Version:     model.GetVersion(),
+		})
+	}
+	return result, nil
+}
+
+// grpcModelType converts a protobuf model type to the model type of inference servers, e.g. MODEL_TYPE_HELMET to helmet
+func grpcModelType(modelType apiv1.ModelType) string {
+	return strings.ToLower(strings.TrimPrefix(modelType.String(), "MODEL_TYPE_"))
+}
+
+// sameURL compares inference URLs ignoring a trailing slash
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// validateServerModel checks that the host of an inference server offers its model type at its URL.
+// Discovery is advisory: servers of the generic "other" type, hosts without model discovery and hosts
+// that cannot be reached now are accepted with a warning, only a host listing other models is an error.
+func validateServerModel(server *store.InferenceServer) error {
+	u, err := url.Parse(server.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid inference server url %q", server.URL)
+	}
+	if server.ModelType == string(config.ModelTypeOther) {
+		return nil
+	}
+
+	result, err := discoverModels(u.Scheme + "://" + u.Host)
+	if errors.Is(err, errDiscoveryUnsupported) {
+		log.Warn(fmt.Sprintf("inference server %s does not support model discovery, model type %q not validated",
+			server.URL, server.ModelType))
+		return nil
+	}
+	if err != nil {
+		log.Warn(fmt.Sprintf("model discovery on %s failed, model type %q not validated: %v", server.URL, server.ModelType, err))
+		return nil
+	}
+
+	model := result.findModel(server.ModelType)
+	if model == nil {
+		return fmt.Errorf("model type %q is not offered by %s, available: %s", server.ModelType, u.Host, result.modelTypes())
+	}
+	if model.URL != "" && !sameURL(model.URL, server.URL) {
+		return fmt.Errorf("model type %q is served at %s, not %s", server.ModelType, model.URL, server.URL)
+	}
+	return nil
+}
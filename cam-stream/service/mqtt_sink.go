@@ -0,0 +1,188 @@
+package service
+
+import (
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttConnectTimeout bounds the initial broker connection, later drops reconnect in the background
+const mqttConnectTimeout = 10 * time.Second
+
+// mqttClientEntry is the client of one destination and the settings it was created with. Its mutex
+// serializes connecting, so a slow broker only holds up alerts to its own destination.
+type mqttClientEntry struct {
+	mutex    sync.Mutex
+	client   mqtt.Client
+	settings string
+	closed   bool
+}
+
+// MQTT clients per alert destination, the map mutex is never held while connecting
+var mqttClients = make(map[string]*mqttClientEntry)
+var mqttClientsMutex sync.Mutex
+
+// getMQTTClient returns the connected client of a destination, reconnecting when its settings changed
+func getMQTTClient(destination *store.AlertDestination) (mqtt.Client, error) {
+	for {
+		mqttClientsMutex.Lock()
+		entry, exists := mqttClients[destination.ID]
+		if !exists {
+			entry = &mqttClientEntry{}
+			mqttClients[destination.ID] = entry
+		}
+		mqttClientsMutex.Unlock()
+
+		// entries closed meanwhile are no longer in the map, the next one has the new settings
+		if client, closed, err := entry.connect(destination); !closed {
+			return client, err
+		}
+	}
+}
+
+// connect returns the connected client of the entry, connecting with the destination's settings if needed.
+// closed reports that the destination was updated or deleted while waiting.
+func (entry *mqttClientEntry) connect(destination *store.AlertDestination) (client mqtt.Client, closed bool, err error) {
+	settings := fmt.Sprintf("%s|%+v", destination.URL, *destination.MQTT)
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+
+	if entry.closed {
+		return nil, true, nil
+	}
+	if entry.client != nil {
+		if entry.settings == settings {
+			return entry.client, false, nil
+		}
+		go entry.client.Disconnect(250)
+		entry.client = nil
+	}
+
+	opts, err := newMQTTClientOptions(destination)
+	if err != nil {
+		return nil, false, err
+	}
+	client = mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(mqttConnectTimeout) {
+		client.Disconnect(0)
+		return nil, false, fmt.Errorf("timed out connecting to mqtt broker %s", destination.URL)
+	}
+	if err := token.Error(); err != nil {
+		return nil, false, fmt.Errorf("failed to connect to mqtt broker %s: %v", destination.URL, err)
+	}
+
+	entry.client, entry.settings = client, settings
+	return client, false, nil
+}
+
+// newMQTTClientOptions builds the paho client options of a destination
+func newMQTTClientOptions(destination *store.AlertDestination) (*mqtt.ClientOptions, error) {
+	options := destination.MQTT
+	clientID := options.ClientID
+	if clientID == "" {
+		clientID = "cam-stream-" + destination.ID
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(destination.URL).
+		SetClientID(clientID).
+		SetUsername(options.Username).
+		SetPassword(options.Password).
+		SetConnectTimeout(mqttConnectTimeout).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Warn(fmt.Sprintf("mqtt destination %s lost connection, reconnecting: %v", destination.Name, err))
+		}).
+		SetOnConnectHandler(func(_ mqtt.Client) {
+			log.Info(fmt.Sprintf("mqtt destination %s connected to %s", destination.Name, destination.URL))
+		})
+
+	if options.CACertFile != "" || options.InsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+		if options.CACertFile != "" {
+			pem, err := os.ReadFile(options.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read mqtt ca certificate: %v", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", options.CACertFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	return opts, nil
+}
+
+// closeMQTTClient disconnects the client of an updated or deleted destination, the next alert
+// connects with the new settings
+func closeMQTTClient(destinationID string) {
+	mqttClientsMutex.Lock()
+	entry, exists := mqttClients[destinationID]
+	delete(mqttClients, destinationID)
+	mqttClientsMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	// wait for a running connect in the background, the entry is no longer handed out
+	go func() {
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+		entry.closed = true
+		if entry.client != nil {
+			entry.client.Disconnect(250)
+			entry.client = nil
+		}
+	}()
+}
+
+// mqttTopicEscaper keeps topic values from adding levels or wildcards
+var mqttTopicEscaper = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+// mqttTopic fills in the placeholders of a topic pattern
+func mqttTopic(pattern string, alertReq *AlertRequest, source *AlertSource) string {
+	cameraID, serverID := "", ""
+	if source != nil && source.Camera != nil {
+		cameraID = source.Camera.ID
+	}
+	if source != nil && source.Server != nil {
+		serverID = source.Server.ID
+	}
+	return strings.NewReplacer(
+		"{camera}", mqttTopicEscaper.Replace(alertReq.CameraKKS),
+		"{camera_id}", mqttTopicEscaper.Replace(cameraID),
+		"{model}", mqttTopicEscaper.Replace(alertReq.Model),
+		"{server}", mqttTopicEscaper.Replace(serverID),
+	).Replace(pattern)
+}
+
+// publishMQTTAlert publishes an alert payload to the destination's broker
+func publishMQTTAlert(destination *store.AlertDestination, payload []byte, alertReq *AlertRequest, source *AlertSource) error {
+	client, err := getMQTTClient(destination)
+	if err != nil {
+		return err
+	}
+
+	topic := mqttTopic(destination.MQTT.Topic, alertReq, source)
+	token := client.Publish(topic, destination.MQTT.QoS, destination.MQTT.Retain, payload)
+	if !token.WaitTimeout(time.Duration(DefaultHttpTimeoutSecs) * time.Second) {
+		return fmt.Errorf("timed out publishing to mqtt topic %s", topic)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to publish to mqtt topic %s: %v", topic, err)
+	}
+	return nil
+}
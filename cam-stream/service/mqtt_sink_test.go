@@ -0,0 +1,220 @@
+package service
+
+import (
+	"cam-stream/common/store"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// testBroker is an in-process MQTT broker that acknowledges connects and records publishes
+type testBroker struct {
+	listener  net.Listener
+	mutex     sync.Mutex
+	connects  []*packets.ConnectPacket
+	published []*packets.PublishPacket
+	received  chan struct{}
+}
+
+// startTestBroker starts a broker on a free local port, it stops with the test
+func startTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := &testBroker{listener: listener, received: make(chan struct{}, 16)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn)
+		}
+	}()
+	return broker
+}
+
+// url returns the broker address as destination URL
+func (b *testBroker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// serve answers the packets of one client connection
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			b.mutex.Lock()
+			b.connects = append(b.connects, p)
+			b.mutex.Unlock()
+			ack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+			ack.ReturnCode = packets.Accepted
+			err = ack.Write(conn)
+		case *packets.PublishPacket:
+			b.mutex.Lock()
+			b.published = append(b.published, p)
+			b.mutex.Unlock()
+			b.received <- struct{}{}
+			if p.Qos == 1 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				err = ack.Write(conn)
+			}
+		case *packets.PingreqPacket:
+			err = packets.NewControlPacket(packets.Pingresp).Write(conn)
+		case *packets.DisconnectPacket:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// waitPublished waits for the nth publish and returns it
+func (b *testBroker) waitPublished(t *testing.T, n int) *packets.PublishPacket {
+	t.Helper()
+	for {
+		b.mutex.Lock()
+		if len(b.published) >= n {
+			defer b.mutex.Unlock()
+			return b.published[n-1]
+		}
+		b.mutex.Unlock()
+		select {
+		case <-b.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("broker did not receive publish %d", n)
+		}
+	}
+}
+
+// connectCount returns how often clients connected
+func (b *testBroker) connectCount() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.connects)
+}
+
+// testMQTTDestination returns a QoS 1 destination publishing to the broker
+func testMQTTDestination(id, url string) *store.AlertDestination {
+	return &store.AlertDestination{
+		ID:      id,
+		Name:    id,
+		Type:    store.AlertDestinationMQTT,
+		Enabled: true,
+		URL:     url,
+		MQTT:    &store.MQTTOptions{Topic: "tianwan/{camera}/{model}", QoS: 1, Username: "cam", Password: "secret"},
+	}
+}
+
+func TestPublishMQTTAlert(t *testing.T) {
+	broker := startTestBroker(t)
+	destination := testMQTTDestination("dest_publish", broker.url())
+	defer closeMQTTClient(destination.ID)
+
+	alertReq := &AlertRequest{Model: "helmet", CameraKKS: "unit1/gate#2"}
+	if err := publishMQTTAlert(destination, []byte(`{"score":0.9}`), alertReq, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	published := broker.waitPublished(t, 1)
+	if published.TopicName != "tianwan/unit1_gate_2/helmet" {
+		t.Errorf("topic %q, want camera name escaped to one level", published.TopicName)
+	}
+	if string(published.Payload) != `{"score":0.9}` || published.Qos != 1 {
+		t.Errorf("payload %q with qos %d", published.Payload, published.Qos)
+	}
+	broker.mutex.Lock()
+	connect := broker.connects[0]
+	broker.mutex.Unlock()
+	if connect.ClientIdentifier != "cam-stream-dest_publish" || connect.Username != "cam" || string(connect.Password) != "secret" {
+		t.Errorf("connected as %q, user %q", connect.ClientIdentifier, connect.Username)
+	}
+}
+
+func TestMQTTClientReconnectsOnChangedSettings(t *testing.T) {
+	broker := startTestBroker(t)
+	destination := testMQTTDestination("dest_reconnect", broker.url())
+	defer closeMQTTClient(destination.ID)
+	alertReq := &AlertRequest{Model: "helmet", CameraKKS: "gate"}
+
+	for i := 1; i <= 2; i++ {
+		if err := publishMQTTAlert(destination, []byte("{}"), alertReq, nil); err != nil {
+			t.Fatal(err)
+		}
+		broker.waitPublished(t, i)
+	}
+	if n := broker.connectCount(); n != 1 {
+		t.Fatalf("%d connects for unchanged settings, want 1", n)
+	}
+
+	changed := *destination
+	changed.MQTT = &store.MQTTOptions{Topic: "alerts/{model}", QoS: 1}
+	if err := publishMQTTAlert(&changed, []byte("{}"), alertReq, nil); err != nil {
+		t.Fatal(err)
+	}
+	if published := broker.waitPublished(t, 3); published.TopicName != "alerts/helmet" {
+		t.Errorf("topic %q after settings change", published.TopicName)
+	}
+	if n := broker.connectCount(); n != 2 {
+		t.Errorf("%d connects after settings change, want 2", n)
+	}
+}
+
+func TestMQTTSlowBrokerDoesNotBlockOtherDestinations(t *testing.T) {
+	// a broker that accepts connections but never acknowledges them
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conns []net.Conn
+	var connsMutex sync.Mutex
+	defer func() {
+		silent.Close()
+		connsMutex.Lock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		connsMutex.Unlock()
+	}()
+	go func() {
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			connsMutex.Lock()
+			conns = append(conns, conn)
+			connsMutex.Unlock()
+		}
+	}()
+
+	slow := testMQTTDestination("dest_slow", "tcp://"+silent.Addr().String())
+	defer closeMQTTClient(slow.ID)
+	go publishMQTTAlert(slow, []byte("{}"), &AlertRequest{Model: "helmet"}, nil)
+	time.Sleep(200 * time.Millisecond)
+
+	broker := startTestBroker(t)
+	destination := testMQTTDestination("dest_fast", broker.url())
+	defer closeMQTTClient(destination.ID)
+
+	start := time.Now()
+	if err := publishMQTTAlert(destination, []byte("{}"), &AlertRequest{Model: "helmet"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("publish waited %v for the connect of another destination", elapsed)
+	}
+}
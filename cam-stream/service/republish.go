@@ -0,0 +1,115 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/config"
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	"cam-stream/rtsp"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// overlayEntry holds the latest detections of one inference server
+type overlayEntry struct {
+	detections []common.Detection
+	styles     []common.BoxStyle // styles[i] belongs to detections[i]
+	updatedAt  time.Time
+}
+
+// Latest detections per camera and server, drawn on republished frames
+var overlayCache = make(map[string]map[string]overlayEntry)
+var overlayMutex sync.RWMutex
+
+// updateOverlay replaces the latest detections of a server for a camera
+func updateOverlay(cameraID string, server *store.InferenceServer, detections []common.Detection) {
+	styles := detectionStyles(server, detections, false)
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+	if overlayCache[cameraID] == nil {
+		overlayCache[cameraID] = make(map[string]overlayEntry)
+	}
+	overlayCache[cameraID][server.ID] = overlayEntry{detections: detections, styles: styles, updatedAt: time.Now()}
+}
+
+// clearOverlay drops the cached detections of a deleted camera
+func clearOverlay(cameraID string) {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+	delete(overlayCache, cameraID)
+}
+
+// latestOverlay returns all detections of a camera younger than maxAge with their styles
+func latestOverlay(cameraID string, maxAge time.Duration) ([]common.Detection, []common.BoxStyle) {
+	overlayMutex.RLock()
+	defer overlayMutex.RUnlock()
+	var detections []common.Detection
+	var styles []common.BoxStyle
+	for _, entry := range overlayCache[cameraID] {
+		if time.Since(entry.updatedAt) <= maxAge {
+			detections = append(detections, entry.detections...)
+			styles = append(styles, entry.styles...)
+		}
+	}
+	return detections, styles
+}
+
+// annotatedPublisher pushes frames of one capture session with overlays to the configured server
+type annotatedPublisher struct {
+	cameraID    string
+	publisher   *rtsp.FFmpegStreamPublisher
+	lastAttempt time.Time
+}
+
+// publish draws the latest overlays on a copy of the frame and queues it for encoding.
+// The encoder is (re)started lazily and at most once per retry interval.
+func (ap *annotatedPublisher) publish(cfg *store.RepublishConfig, frame *rtsp.RawFrame, frameRate int) {
+	if cfg == nil || !cfg.Enabled {
+		ap.stop()
+		return
+	}
+
+	if ap.publisher != nil && (!ap.publisher.IsRunning() || !ap.publisher.Matches(cfg.URL, frame.Width, frame.Height)) {
+		ap.stop()
+	}
+
+	if ap.publisher == nil {
+		if time.Since(ap.lastAttempt) < time.Duration(config.RetryTimeSecond)*time.Second {
+			return
+		}
+		ap.lastAttempt = time.Now()
+
+		publisher := rtsp.NewFFmpegStreamPublisher(cfg.URL, frame.Width, frame.Height, frameRate, cfg.GetBitrateKbps())
+		if err := publisher.Start(); err != nil {
+			log.Warn(fmt.Sprintf("failed to start republishing camera %s to %s: %v", ap.cameraID, cfg.URL, err))
+			return
+		}
+		ap.publisher = publisher
+		store.SafeUpdateCameraHealth(ap.cameraID, func(health *store.CameraHealthState) {
+			health.Republishing = true
+		})
+		log.Info(fmt.Sprintf("republishing camera %s to %s", ap.cameraID, cfg.URL))
+	}
+
+	annotated := make([]byte, len(frame.Data))
+	copy(annotated, frame.Data)
+	overlay, styles := latestOverlay(ap.cameraID, time.Duration(cfg.GetOverlayTTLMillis())*time.Millisecond)
+	common.DrawDetectionsRGB24(annotated, frame.Width, frame.Height, overlay, styles)
+
+	if err := ap.publisher.WriteFrame(annotated); err != nil {
+		log.Warn(fmt.Sprintf("failed to republish frame for camera %s: %v", ap.cameraID, err))
+	}
+}
+
+// stop stops the encoder if running
+func (ap *annotatedPublisher) stop() {
+	if ap.publisher == nil {
+		return
+	}
+	ap.publisher.Stop()
+	ap.publisher = nil
+	store.SafeUpdateCameraHealth(ap.cameraID, func(health *store.CameraHealthState) {
+		health.Republishing = false
+	})
+}
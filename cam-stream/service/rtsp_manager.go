@@ -6,6 +6,7 @@ import (
 	"cam-stream/common/log"
 	"cam-stream/common/store"
 	"cam-stream/rtsp"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
@@ -26,11 +27,16 @@ type CameraStream struct {
 	ID          string
 	URL         string
 	Name        string
+	Options     *store.FFmpegOptions
 	isRunning   bool
 	stopChannel chan struct{}
+	done        chan struct{} // Closed when the stream has stopped its proxy
 	mutex       sync.RWMutex
 }
 
+// streamStopTimeout bounds how long a restart waits for the old stream to stop
+const streamStopTimeout = 30 * time.Second
+
 func NewRTSPManager() *RTSPManager {
 	outputDir := config.OutputDir
 	os.MkdirAll(outputDir, 0755)
@@ -54,7 +60,9 @@ func (m *RTSPManager) StartCamera(camera *store.CameraConfig) error {
 		ID:          camera.ID,
 		URL:         camera.RTSPUrl,
 		Name:        camera.Name,
+		Options:     camera.FFmpegOptions,
 		stopChannel: make(chan struct{}),
+		done:        make(chan struct{}),
 	}
 
 	log.Info(fmt.Sprintf("starting camera: %s", camera.Name))
@@ -70,7 +78,11 @@ func (m *RTSPManager) StartCamera(camera *store.CameraConfig) error {
 			stream.isRunning = false
 			stream.mutex.Unlock()
 			m.ProxyMgr.StopProxy(stream.ID)
+			store.SafeUpdateCameraHealth(stream.ID, func(health *store.CameraHealthState) {
+				health.Connected = false
+			})
 			log.Info(fmt.Sprintf("camera stopped: %s", stream.Name))
+			close(stream.done)
 		}()
 
 		// retry loop
@@ -85,6 +97,11 @@ func (m *RTSPManager) StartCamera(camera *store.CameraConfig) error {
 				if err == nil {
 					continue
 				}
+				store.SafeUpdateCameraHealth(stream.ID, func(health *store.CameraHealthState) {
+					health.Connected = false
+					health.Reconnects++
+					health.LastError = err.Error()
+				})
 				log.Warn(fmt.Sprintf("camera %s connection lost: %v, retrying in %ds",
 					stream.ID, err, config.RetryTimeSecond))
 				select {
@@ -104,13 +121,23 @@ func (m *RTSPManager) StartCamera(camera *store.CameraConfig) error {
 // connectAndCaptureWithProxy connects to FFmpeg proxy and captures frames
 func (m *RTSPManager) connectAndCaptureWithProxy(stream *CameraStream) error {
 	// start FFmpeg proxy
-	proxy, err := m.ProxyMgr.StartProxy(stream.ID, stream.URL)
+	proxy, err := m.ProxyMgr.StartProxy(stream.ID, stream.URL, stream.Options)
 	if err != nil {
 		return fmt.Errorf("failed to start FFmpeg proxy: %v", err)
 	}
 
 	log.Info(fmt.Sprintf("FFmpeg proxy started for camera: %s", stream.Name))
+	if info := proxy.GetStreamInfo(); info != nil {
+		store.SafeUpdateCameraHealth(stream.ID, func(health *store.CameraHealthState) {
+			health.Width = info.Width
+			health.Height = info.Height
+			health.Codec = info.Codec
+			health.FPS = info.FPS
+		})
+	}
 	lastFrameTime := time.Now()
+	publisher := &annotatedPublisher{cameraID: stream.ID}
+	defer publisher.stop()
 
 	for {
 		select {
@@ -122,8 +149,27 @@ func (m *RTSPManager) connectAndCaptureWithProxy(stream *CameraStream) error {
 			// get frame data
 			rawFrame, err := proxy.GetFrameTimeout(time.Duration(config.DefaultGetFrameTimeout) * time.Second)
 			if err != nil {
+				var changed *rtsp.StreamChangedError
+				if errors.As(err, &changed) {
+					// restart the decoder right away with the new stream parameters
+					m.handleStreamChange(stream, changed)
+					return nil
+				}
 				return fmt.Errorf("failed to get frame: %v", err)
 			}
+			store.SafeUpdateCameraHealth(stream.ID, func(health *store.CameraHealthState) {
+				health.Connected = true
+				health.LastFrameAt = rawFrame.Timestamp
+			})
+
+			cameraConfig, exists := store.SafeGetCamera(stream.ID)
+			if !exists || cameraConfig == nil {
+				log.Warn(fmt.Sprintf("camera config not available for stream %s (%s), skipping frame processing", stream.ID, stream.Name))
+				continue
+			}
+
+			// republish every frame with the latest overlays, independent of inference rate
+			publisher.publish(cameraConfig.Republish, rawFrame, proxy.FrameRate())
 
 			// frame rate control
 			if time.Since(lastFrameTime) < config.GlobalFrameInterval {
@@ -131,6 +177,10 @@ func (m *RTSPManager) connectAndCaptureWithProxy(stream *CameraStream) error {
 			}
 			lastFrameTime = time.Now()
 
+			if len(cameraConfig.InferenceServerBindings) <= 0 {
+				continue
+			}
+
 			// TODO: why cant we just process raw frame?
 			jpegData, err := m.rawFrameToJPEG(rawFrame)
 			if err != nil {
@@ -138,23 +188,28 @@ func (m *RTSPManager) connectAndCaptureWithProxy(stream *CameraStream) error {
 				continue
 			}
 
-			// process a single frame.
-			cameraConfig, exists := store.SafeGetCamera(stream.ID)
-			if !exists || cameraConfig == nil {
-				log.Warn(fmt.Sprintf("camera config not available for stream %s (%s), skipping frame processing", stream.ID, stream.Name))
-				continue
-			}
-
-			if len(cameraConfig.InferenceServerBindings) <= 0 {
-				continue
-			}
-
-			ProcessFrameWithAsyncInference(jpegData, cameraConfig, m.OutputDir)
+			ProcessFrameWithAsyncInference(jpegData, rawFrame.Timestamp, cameraConfig, m.OutputDir)
 
 		}
 	}
 }
 
+// handleStreamChange records a stream parameter change and stops the
+// outdated proxy so the capture loop starts a new one.
+func (m *RTSPManager) handleStreamChange(stream *CameraStream, changed *rtsp.StreamChangedError) {
+	log.Warn(fmt.Sprintf("camera %s (%s) %v, restarting decoder", stream.Name, stream.ID, changed))
+	store.SafeUpdateCameraHealth(stream.ID, func(health *store.CameraHealthState) {
+		health.AddStreamChange(store.StreamChangeRecord{
+			DetectedAt: time.Now(),
+			From:       changed.Previous.String(),
+			To:         changed.Current.String(),
+		})
+	})
+	if err := m.ProxyMgr.StopProxy(stream.ID); err != nil {
+		log.Warn(fmt.Sprintf("failed to stop proxy for camera %s: %v", stream.ID, err))
+	}
+}
+
 // rawFrameToJPEG converts raw RGB24 frame to JPEG bytes
 func (m *RTSPManager) rawFrameToJPEG(frame *rtsp.RawFrame) ([]byte, error) {
 	// Create RGBA image from raw RGB24 data
@@ -202,6 +257,28 @@ func (m *RTSPManager) StopCamera(cameraID string) error {
 	return nil
 }
 
+// RestartCamera restarts a started camera with its new URL and ffmpeg options, cameras that are
+// not started stay stopped. It waits for the old stream to stop its proxy, the new stream would
+// otherwise pick up the running proxy with the old settings.
+func (m *RTSPManager) RestartCamera(camera *store.CameraConfig) error {
+	m.Mutex.Lock()
+	stream, exists := m.Cameras[camera.ID]
+	if !exists {
+		m.Mutex.Unlock()
+		return nil
+	}
+	close(stream.stopChannel)
+	delete(m.Cameras, camera.ID)
+	m.Mutex.Unlock()
+
+	select {
+	case <-stream.done:
+	case <-time.After(streamStopTimeout):
+		return fmt.Errorf("camera %s did not stop within %v", camera.ID, streamStopTimeout)
+	}
+	return m.StartCamera(camera)
+}
+
 func (m *RTSPManager) StopAll() {
 	m.Mutex.Lock()
 	defer m.Mutex.Unlock()
@@ -0,0 +1,454 @@
+package service
+
+import (
+	"archive/zip"
+	"cam-stream/common/config"
+	"cam-stream/common/log"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unsafeFileNameChars matches characters replaced in IDs that become part of file names
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// maxResultFileSequence bounds the suffixes tried for results saved under the same name
+const maxResultFileSequence = 100
+
+// resultFileName returns the name of a saved result image, kind is "detection" or the rule event type.
+// The camera ID keeps results of cameras sharing a server in the same millisecond apart.
+func resultFileName(capturedAt time.Time, cameraID, modelType, kind string) string {
+	return fmt.Sprintf("%s_%s_%s_%s.jpg", resultFileTime(capturedAt),
+		unsafeFileNameChars.ReplaceAllString(cameraID, "_"), modelType, kind)
+}
+
+// createResultImage writes a new result image to dir and returns its file name. The metadata, thumbnails
+// and DEBUG original are named after the image, so a name that is taken gets a sequence suffix instead
+// of overwriting the earlier result.
+func createResultImage(dir, filename string, data []byte) (string, error) {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	for seq := 1; seq <= maxResultFileSequence; seq++ {
+		name := filename
+		if seq > 1 {
+			name = fmt.Sprintf("%s_%d%s", base, seq, filepath.Ext(filename))
+		}
+		path := filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return "", err
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("%d results are already saved as %s", maxResultFileSequence, filename)
+}
+
+// resultFilter selects saved results, empty fields match everything
+type resultFilter struct {
+	ServerID   string
+	CameraID   string
+	CameraName string // Matches results saved without camera ID
+	ModelType  string
+	From       time.Time
+	To         time.Time
+	MinScore   float64 // Results need a detection with at least this confidence
+}
+
+// matches reports whether a saved result passes the filter
+func (f *resultFilter) matches(summary *resultSummary) bool {
+	if f.CameraID != "" {
+		if summary.CameraID != "" && summary.CameraID != f.CameraID {
+			return false
+		}
+		if summary.CameraID == "" && (f.CameraName == "" || summary.CameraName != f.CameraName) {
+			return false
+		}
+	}
+	if f.ModelType != "" && summary.ModelType != f.ModelType {
+		return false
+	}
+	if !f.From.IsZero() && summary.CapturedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && summary.CapturedAt.After(f.To) {
+		return false
+	}
+	if f.MinScore > 0 && summary.MaxScore < f.MinScore {
+		return false
+	}
+	return true
+}
+
+// resultSummary is the part of the metadata of a saved result that result lists filter and show
+type resultSummary struct {
+	CameraID   string
+	CameraName string
+	ModelType  string
+	RuleEvent  string // Rule event type, empty for detections
+	CapturedAt time.Time
+	Detections int
+	MaxScore   float64
+	Reviewed   int
+	// Metadata file the summary was read from, zero for images without metadata
+	modTime time.Time
+	size    int64
+}
+
+// summary returns the summary of the metadata
+func (m *ResultMetadata) summary() *resultSummary {
+	summary := &resultSummary{
+		CameraID:   m.CameraID,
+		CameraName: m.CameraName,
+		ModelType:  m.ModelType,
+		CapturedAt: m.CapturedAt,
+		Detections: len(m.Detections),
+		MaxScore:   m.maxScore(),
+		Reviewed:   len(m.Verdicts),
+	}
+	if m.RuleEvent != nil {
+		summary.RuleEvent = m.RuleEvent.Type
+	}
+	return summary
+}
+
+// maxScore returns the highest detection confidence of a result
+func (m *ResultMetadata) maxScore() float64 {
+	score := 0.0
+	for _, det := range m.Detections {
+		if det.Confidence > score {
+			score = det.Confidence
+		}
+	}
+	return score
+}
+
+// resultServerIDs returns the output directories the filter selects, one per server or group
+func resultServerIDs(outputDir string, filter *resultFilter) ([]string, error) {
+	if filter.ServerID != "" {
+		return []string{filter.ServerID}, nil
+	}
+	entries, err := os.ReadDir(outputDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read output directory: %v", err)
+	}
+	var serverIDs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			serverIDs = append(serverIDs, entry.Name())
+		}
+	}
+	return serverIDs, nil
+}
+
+// walkResults calls fn with the metadata of every saved result matching the filter, imageName is
+// the result image in the server's output directory
+func walkResults(outputDir string, filter *resultFilter, fn func(serverID, imageName string, metadata *ResultMetadata)) error {
+	serverIDs, err := resultServerIDs(outputDir, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, serverID := range serverIDs {
+		entries, err := os.ReadDir(filepath.Join(outputDir, serverID))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read results of server %s: %v", serverID, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+				continue
+			}
+			metadata, err := readResultMetadata(filepath.Join(outputDir, serverID, entry.Name()))
+			if err != nil {
+				log.Warn(fmt.Sprintf("skipping result %s/%s: %v", serverID, entry.Name(), err))
+				continue
+			}
+			if filter.matches(metadata.summary()) {
+				fn(serverID, strings.TrimSuffix(entry.Name(), ".json")+".jpg", metadata)
+			}
+		}
+	}
+	return nil
+}
+
+// resultSummaries caches the summaries of saved results by server ID and image name, so listing a page
+// only reads metadata files that changed since the last listing. Writes and deletes of results forget
+// their summary and bump the generation of the server, a listing that raced with one is not cached.
+var (
+	resultSummaries          = make(map[string]map[string]*resultSummary)
+	resultSummaryGenerations = make(map[string]int)
+	resultSummariesMutex     sync.Mutex
+)
+
+// forgetResultSummary drops the cached summary of the result with the given metadata file
+func forgetResultSummary(metadataPath string) {
+	serverID := filepath.Base(filepath.Dir(metadataPath))
+	base := strings.TrimSuffix(filepath.Base(metadataPath), filepath.Ext(metadataPath))
+	resultSummariesMutex.Lock()
+	defer resultSummariesMutex.Unlock()
+	if summaries := resultSummaries[serverID]; summaries != nil {
+		delete(summaries, base+".jpg")
+		delete(summaries, base+".jpeg")
+	}
+	resultSummaryGenerations[serverID]++
+}
+
+// serverResultSummaries returns the summaries of the result images of a server. Cached summaries are
+// used while their metadata file keeps its time and size, summaries of removed images are dropped.
+func serverResultSummaries(outputDir, serverID string) (map[string]*resultSummary, map[string]os.FileInfo, error) {
+	dir := filepath.Join(outputDir, serverID)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images := make(map[string]os.FileInfo)
+	metadataFiles := make(map[string]os.FileInfo)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if ext == ".json" {
+			metadataFiles[strings.TrimSuffix(name, ext)] = info
+		} else {
+			images[name] = info
+		}
+	}
+
+	cached := make(map[string]*resultSummary, len(images))
+	resultSummariesMutex.Lock()
+	generation := resultSummaryGenerations[serverID]
+	for name := range images {
+		if summary := resultSummaries[serverID][name]; summary != nil {
+			cached[name] = summary
+		}
+	}
+	resultSummariesMutex.Unlock()
+
+	summaries := make(map[string]*resultSummary, len(images))
+	for name, image := range images {
+		metadataFile, hasMetadata := metadataFiles[strings.TrimSuffix(name, filepath.Ext(name))]
+		if summary := cached[name]; summary != nil {
+			if hasMetadata && summary.modTime.Equal(metadataFile.ModTime()) && summary.size == metadataFile.Size() {
+				summaries[name] = summary
+				continue
+			}
+			if !hasMetadata && summary.modTime.IsZero() && summary.CapturedAt.Equal(image.ModTime()) {
+				summaries[name] = summary
+				continue
+			}
+		}
+
+		summary := &resultSummary{CapturedAt: image.ModTime()}
+		if hasMetadata {
+			metadata, err := readResultMetadata(filepath.Join(dir, metadataFile.Name()))
+			if err == nil {
+				summary = metadata.summary()
+				summary.modTime = metadataFile.ModTime()
+				summary.size = metadataFile.Size()
+			} else if !os.IsNotExist(err) {
+				log.Warn(fmt.Sprintf("ignoring metadata of %s/%s: %v", serverID, name, err))
+			}
+		}
+		summaries[name] = summary
+	}
+
+	resultSummariesMutex.Lock()
+	if resultSummaryGenerations[serverID] == generation {
+		resultSummaries[serverID] = summaries
+	}
+	resultSummariesMutex.Unlock()
+	return summaries, images, nil
+}
+
+// listResultImages returns the saved result images matching the filter, newest first. Images saved
+// without metadata only have their file time and match filters on server and time alone.
+func listResultImages(outputDir string, filter *resultFilter) ([]ImageInfo, error) {
+	serverIDs, err := resultServerIDs(outputDir, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []ImageInfo{}
+	for _, serverID := range serverIDs {
+		summaries, files, err := serverResultSummaries(outputDir, serverID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read images of server %s: %v", serverID, err)
+		}
+
+		for name, summary := range summaries {
+			if !filter.matches(summary) {
+				continue
+			}
+			images = append(images, ImageInfo{
+				Filename:   name,
+				Size:       files[name].Size(),
+				CreatedAt:  summary.CapturedAt,
+				ServerID:   serverID,
+				CameraID:   summary.CameraID,
+				CameraName: summary.CameraName,
+				ModelType:  summary.ModelType,
+				RuleEvent:  summary.RuleEvent,
+				Detections: summary.Detections,
+				MaxScore:   summary.MaxScore,
+				Reviewed:   summary.Reviewed,
+			})
+		}
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if !images[i].CreatedAt.Equal(images[j].CreatedAt) {
+			return images[i].CreatedAt.After(images[j].CreatedAt)
+		}
+		return images[i].Filename > images[j].Filename
+	})
+	return images, nil
+}
+
+// ResultRef names a saved result image
+type ResultRef struct {
+	ServerID string `json:"server_id"`
+	Filename string `json:"filename"`
+}
+
+// resultImagePath returns a saved result image, rejecting names outside the output directory
+func resultImagePath(outputDir, serverID, filename string) (string, error) {
+	for _, name := range []string{serverID, filename} {
+		if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			return "", fmt.Errorf("invalid name %q", name)
+		}
+	}
+	if ext := strings.ToLower(filepath.Ext(filename)); ext != ".jpg" && ext != ".jpeg" {
+		return "", fmt.Errorf("%s is not a result image", filename)
+	}
+	return filepath.Join(outputDir, serverID, filename), nil
+}
+
+// resultMetadataPath returns the metadata file of a saved result image
+func resultMetadataPath(outputDir, serverID, filename string) (string, error) {
+	imagePath, err := resultImagePath(outputDir, serverID, filename)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json", nil
+}
+
+// readResultMetadata reads the metadata file of a saved result
+func readResultMetadata(path string) (*ResultMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var metadata ResultMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid result metadata: %v", err)
+	}
+	return &metadata, nil
+}
+
+// writeResultMetadata replaces the metadata file of a saved result
+func writeResultMetadata(path string, metadata *ResultMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result metadata: %v", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write result metadata: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace result metadata: %v", err)
+	}
+	forgetResultSummary(path)
+	return nil
+}
+
+// deleteResult removes a saved result image with its metadata, thumbnails and the DEBUG original and labels
+func deleteResult(outputDir string, ref ResultRef) error {
+	imagePath, err := resultImagePath(outputDir, ref.ServerID, ref.Filename)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(imagePath); err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(ref.Filename, filepath.Ext(ref.Filename))
+	forgetResultSummary(filepath.Join(outputDir, ref.ServerID, base+".json"))
+	feedbackMutex.Lock()
+	defer feedbackMutex.Unlock()
+	for _, path := range []string{
+		filepath.Join(outputDir, ref.ServerID, base+".json"),
+		filepath.Join(config.DebugDir, ref.ServerID, ref.Filename),
+		filepath.Join(config.DebugDir, ref.ServerID, base+".txt"),
+	} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warn(fmt.Sprintf("failed to remove %s: %v", path, err))
+		}
+	}
+	removeThumbnails(ref.ServerID, ref.Filename)
+	return nil
+}
+
+// writeResultArchive writes the result images and their metadata as a zip, as <server ID>/<file name>
+func writeResultArchive(w io.Writer, outputDir string, refs []ResultRef) error {
+	zw := zip.NewWriter(w)
+	for _, ref := range refs {
+		imagePath, err := resultImagePath(outputDir, ref.ServerID, ref.Filename)
+		if err != nil {
+			return err
+		}
+		if err := addZipImage(zw, ref.ServerID+"/"+ref.Filename, imagePath); err != nil {
+			return err
+		}
+
+		metadataPath := strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+		data, err := os.ReadFile(metadataPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read metadata of %s: %v", ref.Filename, err)
+		}
+		if err := addZipFile(zw, ref.ServerID+"/"+filepath.Base(metadataPath), data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"cam-stream/common/store"
+	"time"
+)
+
+// ScheduleStatus tells whether inference and alerts are currently allowed by the schedules
+type ScheduleStatus struct {
+	InferenceActive bool `json:"inference_active"`
+	AlertsActive    bool `json:"alerts_active"`
+}
+
+// CameraScheduleStatus is the schedule state of a camera and each of its bindings
+type CameraScheduleStatus struct {
+	ScheduleStatus
+	Bindings map[string]ScheduleStatus `json:"bindings,omitempty"` // keyed by server or group ID
+}
+
+// CameraView is a camera as returned by the camera API, including its runtime schedule state
+type CameraView struct {
+	*store.CameraConfig
+	ScheduleStatus CameraScheduleStatus `json:"schedule_status"`
+}
+
+// applySchedule restricts status according to an attached schedule
+func applySchedule(status ScheduleStatus, binding *store.ScheduleBinding, now time.Time) ScheduleStatus {
+	if binding == nil || store.IsScheduleActive(binding, now) {
+		return status
+	}
+	status.AlertsActive = false
+	if binding.GetMode() == store.ScheduleModeInference {
+		status.InferenceActive = false
+	}
+	return status
+}
+
+// scheduleStatus returns the schedule state of a binding, the camera
+// schedule and the binding schedule both have to allow an action
+func scheduleStatus(camera *store.CameraConfig, binding *store.InferenceServerBinding, now time.Time) ScheduleStatus {
+	status := applySchedule(ScheduleStatus{InferenceActive: true, AlertsActive: true}, camera.Schedule, now)
+	return applySchedule(status, binding.Schedule, now)
+}
+
+// newCameraView builds the API representation of a camera
+func newCameraView(camera *store.CameraConfig) CameraView {
+	now := time.Now()
+	view := CameraView{
+		CameraConfig: camera,
+		ScheduleStatus: CameraScheduleStatus{
+			ScheduleStatus: applySchedule(ScheduleStatus{InferenceActive: true, AlertsActive: true}, camera.Schedule, now),
+		},
+	}
+	if len(camera.InferenceServerBindings) > 0 {
+		view.ScheduleStatus.Bindings = make(map[string]ScheduleStatus)
+		for i := range camera.InferenceServerBindings {
+			binding := &camera.InferenceServerBindings[i]
+			view.ScheduleStatus.Bindings[binding.TargetID()] = scheduleStatus(camera, binding, now)
+		}
+	}
+	return view
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"cam-stream/common/config"
+	"cam-stream/common/store"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// ServerGroupMember is the runtime state of a group member as returned by the API
+type ServerGroupMember struct {
+	ServerID string `json:"server_id"`
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Enabled  bool   `json:"enabled"`
+	Status   string `json:"status"`   // health status, "missing" for deleted servers
+	Inflight int    `json:"inflight"` // running inference requests
+}
+
+// ServerGroupView is a server group as returned by the API, including the state of its members
+type ServerGroupView struct {
+	*store.ServerGroup
+	Members        []ServerGroupMember `json:"members"`
+	HealthyMembers int                 `json:"healthy_members"`
+}
+
+// Running inference requests per server, used by least-inflight balancing
+var inflightRequests = make(map[string]int)
+
+// Round-robin positions per group
+var roundRobinCounters = make(map[string]uint64)
+var balancerMutex sync.Mutex
+
+// beginInferenceRequest counts a request to a server as running
+func beginInferenceRequest(serverID string) {
+	balancerMutex.Lock()
+	defer balancerMutex.Unlock()
+	inflightRequests[serverID]++
+}
+
+// endInferenceRequest counts a request to a server as finished
+func endInferenceRequest(serverID string) {
+	balancerMutex.Lock()
+	defer balancerMutex.Unlock()
+	if inflightRequests[serverID] <= 1 {
+		delete(inflightRequests, serverID)
+		return
+	}
+	inflightRequests[serverID]--
+}
+
+// getInflightRequests returns the number of running requests to a server
+func getInflightRequests(serverID string) int {
+	balancerMutex.Lock()
+	defer balancerMutex.Unlock()
+	return inflightRequests[serverID]
+}
+
+// groupServer returns the inference server standing for a group in the pipeline,
+// results, trackers, rules and alerts of group bindings use the group ID and name
+func groupServer(group *store.ServerGroup) *store.InferenceServer {
+	return &store.InferenceServer{
+		ID:          group.ID,
+		Name:        group.Name,
+		ModelType:   group.ModelType,
+		Description: group.Description,
+		Enabled:     true,
+		CreatedAt:   group.CreatedAt,
+		UpdatedAt:   group.UpdatedAt,
+	}
+}
+
+// getTargetServer returns the inference server or the stand-in of the server group with the given ID
+func getTargetServer(id string) (*store.InferenceServer, bool) {
+	if server, exists := store.SafeGetInferenceServer(id); exists {
+		return server, true
+	}
+	if group, exists := store.SafeGetServerGroup(id); exists {
+		return groupServer(group), true
+	}
+	return nil, false
+}
+
+// healthyGroupMembers returns the enabled members of a group that are not down, in configured order
+func healthyGroupMembers(group *store.ServerGroup) []*store.InferenceServer {
+	var members []*store.InferenceServer
+	for _, id := range group.ServerIDs {
+		server, exists := store.SafeGetInferenceServer(id)
+		if exists && server.Enabled && isServerAvailable(server) {
+			members = append(members, server)
+		}
+	}
+	return members
+}
+
+// isTargetAvailable reports whether a binding can send frames, groups need at least one healthy member
+func isTargetAvailable(binding *store.InferenceServerBinding, server *store.InferenceServer) bool {
+	if binding.GroupID == "" {
+		return isServerAvailable(server)
+	}
+	group, exists := store.SafeGetServerGroup(binding.GroupID)
+	return exists && len(healthyGroupMembers(group)) > 0
+}
+
+// inferenceCandidates returns the servers to try for a frame in order, the first one gets the
+// request and the others take over when it fails. Single server bindings only have their server.
+func inferenceCandidates(binding *store.InferenceServerBinding, server *store.InferenceServer, cameraID string) []*store.InferenceServer {
+	if binding.GroupID == "" {
+		return []*store.InferenceServer{server}
+	}
+	group, exists := store.SafeGetServerGroup(binding.GroupID)
+	if !exists {
+		return nil
+	}
+	members := healthyGroupMembers(group)
+	if len(members) <= 1 {
+		return members
+	}
+
+	switch group.GetBalance() {
+	case store.BalanceLeastInflight:
+		inflight := make(map[string]int, len(members))
+		for _, member := range members {
+			inflight[member.ID] = getInflightRequests(member.ID)
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			return inflight[members[i].ID] < inflight[members[j].ID]
+		})
+	case store.BalanceConsistentHash:
+		// rendezvous hashing, a camera only moves when its member goes down
+		sort.SliceStable(members, func(i, j int) bool {
+			return memberWeight(cameraID, members[i].ID) > memberWeight(cameraID, members[j].ID)
+		})
+	default:
+		balancerMutex.Lock()
+		start := int(roundRobinCounters[group.ID] % uint64(len(members)))
+		roundRobinCounters[group.ID]++
+		balancerMutex.Unlock()
+		members = append(members[start:], members[:start]...)
+	}
+	return members
+}
+
+// memberWeight is the rendezvous hashing weight of a server for a camera
+func memberWeight(cameraID, serverID string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(cameraID + "/" + serverID))
+	return h.Sum64()
+}
+
+// clearServerGroupBalancer forgets the round-robin position of a deleted group
+func clearServerGroupBalancer(groupID string) {
+	balancerMutex.Lock()
+	defer balancerMutex.Unlock()
+	delete(roundRobinCounters, groupID)
+}
+
+// validateServerGroup checks a group and its members, getServer resolves members against the
+// current data store or imported data. An empty model type is taken from the members.
+func validateServerGroup(group *store.ServerGroup, getServer func(id string) (*store.InferenceServer, bool)) error {
+	if err := group.Validate(); err != nil {
+		return err
+	}
+	for _, id := range group.ServerIDs {
+		server, exists := getServer(id)
+		if !exists {
+			return fmt.Errorf("inference server %q not found", id)
+		}
+		if group.ModelType == "" {
+			group.ModelType = server.ModelType
+		}
+		if server.ModelType != group.ModelType {
+			return fmt.Errorf("inference server %s has model type %q, group has %q", server.Name, server.ModelType, group.ModelType)
+		}
+	}
+	if group.ModelType == string(config.ModelTypeFall) {
+		return fmt.Errorf("fall detection servers run tasks per camera and cannot be grouped")
+	}
+	return nil
+}
+
+// newServerGroupView builds the API representation of a server group
+func newServerGroupView(group *store.ServerGroup) ServerGroupView {
+	view := ServerGroupView{ServerGroup: group, Members: []ServerGroupMember{}}
+	for _, id := range group.ServerIDs {
+		member := ServerGroupMember{ServerID: id, Status: "missing"}
+		if server, exists := store.SafeGetInferenceServer(id); exists {
+			health, _ := store.SafeGetServerHealth(id)
+			member.Name, member.URL, member.Enabled = server.Name, server.URL, server.Enabled
+			member.Status = health.Status
+			member.Inflight = getInflightRequests(id)
+			if server.Enabled && isServerAvailable(server) {
+				view.HealthyMembers++
+			}
+		}
+		view.Members = append(view.Members, member)
+	}
+	return view
+}
+
+// serverGroupsWithMember returns the groups the server is a member of, callers hold the data store lock
+func serverGroupsWithMember(serverID string) []*store.ServerGroup {
+	var groups []*store.ServerGroup
+	for _, group := range store.Data.ServerGroups {
+		for _, id := range group.ServerIDs {
+			if id == serverID {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// cameraUsingGroup returns a camera with a binding to the group, callers hold the data store lock
+func cameraUsingGroup(groupID string) (*store.CameraConfig, bool) {
+	for _, camera := range store.Data.Cameras {
+		for _, binding := range camera.InferenceServerBindings {
+			if binding.GroupID == groupID {
+				return camera, true
+			}
+		}
+	}
+	return nil, false
+}
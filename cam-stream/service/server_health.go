@@ -0,0 +1,191 @@
+package service
+
+import (
+	"cam-stream/common/log"
+	"cam-stream/common/store"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// serverHealthTick is how often the prober looks for servers due for a probe
+const serverHealthTick = time.Second
+
+// inferenceLatencySmoothing weights the latest request in the inference latency moving average
+const inferenceLatencySmoothing = 0.2
+
+// InferenceServerView is an inference server as returned by the API, including its runtime health
+type InferenceServerView struct {
+	*store.InferenceServer
+	Health           store.InferenceServerHealth `json:"health"`
+	DefaultThreshold float64                     `json:"default_threshold"` // Proposed threshold of new bindings from the model registry
+}
+
+// newInferenceServerView builds the API representation of an inference server
+func newInferenceServerView(server *store.InferenceServer) InferenceServerView {
+	health, _ := store.SafeGetServerHealth(server.ID)
+	view := InferenceServerView{InferenceServer: server, Health: health}
+	if model, exists := store.SafeGetModelDefinition(server.ModelType); exists {
+		view.DefaultThreshold = model.DefaultThreshold
+	}
+	return view
+}
+
+// Servers with a probe in flight
+var probingServers = make(map[string]bool)
+var probingServersMutex sync.Mutex
+var serverHealthProberOnce sync.Once
+
+// StartServerHealthProber starts the background prober of all enabled inference servers
+func StartServerHealthProber() {
+	serverHealthProberOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(serverHealthTick)
+			defer ticker.Stop()
+			for range ticker.C {
+				probeDueServers()
+			}
+		}()
+	})
+}
+
+// probeDueServers starts a probe for every server whose interval has passed
+func probeDueServers() {
+	var servers []*store.InferenceServer
+	store.SafeReadDataStore(func() {
+		for _, server := range store.Data.InferenceServers {
+			if server.Enabled && server.HealthCheck.IsEnabled() {
+				servers = append(servers, server)
+			}
+		}
+	})
+
+	now := time.Now()
+	for _, server := range servers {
+		health, _ := store.SafeGetServerHealth(server.ID)
+		if now.Sub(health.LastProbeAt) < server.HealthCheck.GetInterval() {
+			continue
+		}
+
+		probingServersMutex.Lock()
+		if probingServers[server.ID] {
+			probingServersMutex.Unlock()
+			continue
+		}
+		probingServers[server.ID] = true
+		probingServersMutex.Unlock()
+
+		go func() {
+			defer func() {
+				probingServersMutex.Lock()
+				delete(probingServers, server.ID)
+				probingServersMutex.Unlock()
+			}()
+			probeInferenceServer(server)
+		}()
+	}
+}
+
+// probeInferenceServer sends a health request to a server and records the result
+func probeInferenceServer(server *store.InferenceServer) {
+	start := time.Now()
+	err := checkInferenceServer(server)
+	latency := time.Since(start)
+
+	store.SafeUpdateServerHealth(server.ID, func(health *store.InferenceServerHealth) {
+		health.LastProbeAt = start
+		health.AddOutcome(err != nil)
+		if err != nil {
+			markServerFailure(server, health, err)
+			return
+		}
+		health.ProbeLatencyMs = float64(latency.Microseconds()) / 1000
+		health.ConsecutiveFailures = 0
+		health.ConsecutiveSuccesses++
+		if health.Status != store.ServerHealthDown {
+			health.Status = store.ServerHealthUp
+		} else if health.ConsecutiveSuccesses >= server.HealthCheck.GetSuccessThreshold() {
+			log.Info(fmt.Sprintf("inference server %s recovered after %s, resuming inference",
+				server.Name, time.Since(health.DownSince).Round(time.Second)))
+			health.Status = store.ServerHealthUp
+			health.DownSince = time.Time{}
+			health.LastError = ""
+		}
+	})
+}
+
+// checkInferenceServer performs one health request. A configured health URL has to answer 2xx,
+// the inference URL only has to answer at all without a server error since it expects POST requests.
+func checkInferenceServer(server *store.InferenceServer) error {
+	probeURL := server.URL
+	if server.HealthCheck != nil && server.HealthCheck.URL != "" {
+		probeURL = server.HealthCheck.URL
+	}
+
+	client := &http.Client{
+		Timeout:   server.HealthCheck.GetTimeout(),
+		Transport: &http.Transport{DisableKeepAlives: true},
+	}
+	resp, err := client.Get(probeURL)
+	if err != nil {
+		return fmt.Errorf("health request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if probeURL != server.URL && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("inference server returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// recordInferenceResult feeds the outcome of an inference request into the server health,
+// failed requests count towards marking the server down like failed probes
+func recordInferenceResult(server *store.InferenceServer, latency time.Duration, err error) {
+	store.SafeUpdateServerHealth(server.ID, func(health *store.InferenceServerHealth) {
+		health.AddOutcome(err != nil)
+		if err != nil {
+			markServerFailure(server, health, err)
+			return
+		}
+		latencyMs := float64(latency.Microseconds()) / 1000
+		if health.InferenceLatencyMs == 0 {
+			health.InferenceLatencyMs = latencyMs
+		} else {
+			health.InferenceLatencyMs += inferenceLatencySmoothing * (latencyMs - health.InferenceLatencyMs)
+		}
+		health.ConsecutiveFailures = 0
+		if health.Status == store.ServerHealthUnknown {
+			health.Status = store.ServerHealthUp
+		}
+	})
+}
+
+// markServerFailure counts a failed request and suspends the server once the threshold is reached
+func markServerFailure(server *store.InferenceServer, health *store.InferenceServerHealth, err error) {
+	health.LastError = err.Error()
+	health.ConsecutiveSuccesses = 0
+	health.ConsecutiveFailures++
+	if health.Status == store.ServerHealthDown || !server.HealthCheck.IsEnabled() ||
+		health.ConsecutiveFailures < server.HealthCheck.GetFailureThreshold() {
+		return
+	}
+	log.Warn(fmt.Sprintf("inference server %s is down after %d consecutive failures, suspending inference: %v",
+		server.Name, health.ConsecutiveFailures, err))
+	health.Status = store.ServerHealthDown
+	health.DownSince = time.Now()
+}
+
+// isServerAvailable reports whether frames should be sent to a server, false while it is down
+func isServerAvailable(server *store.InferenceServer) bool {
+	if !server.HealthCheck.IsEnabled() {
+		return true
+	}
+	health, _ := store.SafeGetServerHealth(server.ID)
+	return health.Status != store.ServerHealthDown
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"cam-stream/common"
+	"cam-stream/common/config"
+	"cam-stream/common/log"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Thumbnail sizes, the longer side in pixels. Only these are served so the cache stays bounded.
+var thumbnailSizes = []int{160, 320, 640}
+
+// defaultThumbnailSize is the size of the image viewer grid
+const defaultThumbnailSize = 320
+
+// thumbnailMaxAge is how long browsers may use a thumbnail before revalidating its ETag
+const thumbnailMaxAge = 24 * time.Hour
+
+// thumbnailPath returns the cached thumbnail of a result image
+func thumbnailPath(serverID, filename string, size int) string {
+	return filepath.Join(config.ThumbnailDir, serverID, strconv.Itoa(size), filename)
+}
+
+// thumbnailETag identifies a thumbnail by the file it was made from
+func thumbnailETag(source os.FileInfo, size int) string {
+	return fmt.Sprintf(`"%x-%x-%d"`, source.ModTime().UnixNano(), source.Size(), size)
+}
+
+// ensureThumbnail returns the cached thumbnail of a result image, creating it when it is missing
+// or older than the image
+func ensureThumbnail(imagePath, serverID, filename string, size int, source os.FileInfo) (string, error) {
+	path := thumbnailPath(serverID, filename, size)
+	if info, err := os.Stat(path); err == nil && !info.ModTime().Before(source.ModTime()) {
+		return path, nil
+	}
+
+	src, err := os.Open(imagePath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	data, err := common.MakeThumbnail(src, size)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create thumbnail directory: %v", err)
+	}
+	// concurrent requests for the same thumbnail each write their own file, the last rename wins
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".thumbnail-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create thumbnail: %v", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to save thumbnail: %v", err)
+	}
+	return path, nil
+}
+
+// removeThumbnails deletes the cached thumbnails of a result image
+func removeThumbnails(serverID, filename string) {
+	for _, size := range thumbnailSizes {
+		path := thumbnailPath(serverID, filename, size)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warn(fmt.Sprintf("failed to remove %s: %v", path, err))
+		}
+	}
+}
+
+// parseThumbnailSize reads the size query parameter, empty means the default size
+func parseThumbnailSize(value string) (int, error) {
+	if value == "" {
+		return defaultThumbnailSize, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err == nil {
+		for _, allowed := range thumbnailSizes {
+			if size == allowed {
+				return size, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("size must be one of %v", thumbnailSizes)
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"cam-stream/common"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Process noise of the constant velocity model (pixels/s^2)
+	trackerProcessNoise = 50.0
+	// Measurement noise of detector boxes (pixels^2)
+	trackerMeasurementNoise = 25.0
+)
+
+// TrackerConfig configures association and expiry of tracks
+type TrackerConfig struct {
+	IoUThreshold float64       // Minimum IoU between prediction and detection to match
+	MaxMissed    int           // Updates a track may go unmatched before it is dropped
+	MaxIdle      time.Duration // Time a track may go unmatched before it is dropped
+}
+
+// DefaultTrackerConfig returns default tracker configuration
+func DefaultTrackerConfig() TrackerConfig {
+	return TrackerConfig{
+		IoUThreshold: 0.3,
+		MaxMissed:    5,
+		MaxIdle:      10 * time.Second,
+	}
+}
+
+// kalman1D is a constant velocity Kalman filter for a single coordinate
+type kalman1D struct {
+	pos float64
+	vel float64
+	p   [2][2]float64 // state covariance
+}
+
+func newKalman1D(pos float64) kalman1D {
+	return kalman1D{
+		pos: pos,
+		// velocity is unknown at start
+		p: [2][2]float64{{trackerMeasurementNoise, 0}, {0, 1000}},
+	}
+}
+
+// predict advances the state by dt seconds
+func (k *kalman1D) predict(dt float64) {
+	k.pos += k.vel * dt
+
+	// P = F P F^T + Q with F = [[1, dt], [0, 1]] and white noise acceleration Q
+	p00 := k.p[0][0] + dt*(k.p[0][1]+k.p[1][0]) + dt*dt*k.p[1][1]
+	p01 := k.p[0][1] + dt*k.p[1][1]
+	p10 := k.p[1][0] + dt*k.p[1][1]
+	p11 := k.p[1][1]
+	q := trackerProcessNoise
+	k.p[0][0] = p00 + q*dt*dt*dt*dt/4
+	k.p[0][1] = p01 + q*dt*dt*dt/2
+	k.p[1][0] = p10 + q*dt*dt*dt/2
+	k.p[1][1] = p11 + q*dt*dt
+}
+
+// update corrects the state with a position measurement
+func (k *kalman1D) update(measurement float64) {
+	residual := measurement - k.pos
+	s := k.p[0][0] + trackerMeasurementNoise
+	k0 := k.p[0][0] / s
+	k1 := k.p[1][0] / s
+
+	k.pos += k0 * residual
+	k.vel += k1 * residual
+
+	p00, p01 := k.p[0][0], k.p[0][1]
+	k.p[0][0] = (1 - k0) * p00
+	k.p[0][1] = (1 - k0) * p01
+	k.p[1][0] -= k1 * p00
+	k.p[1][1] -= k1 * p01
+}
+
+// track is a single tracked object, filtered on box center and size
+type track struct {
+	id         int
+	class      string
+	cx, cy     kalman1D
+	w, h       kalman1D
+	hits       int
+	missed     int
+	lastUpdate time.Time
+}
+
+func newTrack(id int, det common.Detection, timestamp time.Time) *track {
+	cx, cy := det.Center()
+	return &track{
+		id:         id,
+		class:      det.Class,
+		cx:         newKalman1D(cx),
+		cy:         newKalman1D(cy),
+		w:          newKalman1D(float64(det.X2 - det.X1)),
+		h:          newKalman1D(float64(det.Y2 - det.Y1)),
+		hits:       1,
+		lastUpdate: timestamp,
+	}
+}
+
+// predictedBox returns the expected box at timestamp without changing the filter state
+func (t *track) predictedBox(timestamp time.Time) common.Detection {
+	dt := trackerElapsed(t.lastUpdate, timestamp)
+	cx := t.cx.pos + t.cx.vel*dt
+	cy := t.cy.pos + t.cy.vel*dt
+	w := t.w.pos + t.w.vel*dt
+	h := t.h.pos + t.h.vel*dt
+	return common.Detection{
+		Class: t.class,
+		X1:    int(cx - w/2),
+		Y1:    int(cy - h/2),
+		X2:    int(cx + w/2),
+		Y2:    int(cy + h/2),
+	}
+}
+
+// update advances the filters to timestamp and corrects them with det
+func (t *track) update(det common.Detection, timestamp time.Time) {
+	dt := trackerElapsed(t.lastUpdate, timestamp)
+	cx, cy := det.Center()
+	for _, f := range []struct {
+		k *kalman1D
+		z float64
+	}{
+		{&t.cx, cx},
+		{&t.cy, cy},
+		{&t.w, float64(det.X2 - det.X1)},
+		{&t.h, float64(det.Y2 - det.Y1)},
+	} {
+		f.k.predict(dt)
+		f.k.update(f.z)
+	}
+	t.hits++
+	t.missed = 0
+	if timestamp.After(t.lastUpdate) {
+		t.lastUpdate = timestamp
+	}
+}
+
+// trackerElapsed returns seconds between two updates, results may arrive out of order
+func trackerElapsed(from, to time.Time) float64 {
+	dt := to.Sub(from).Seconds()
+	if dt < 0 {
+		return 0
+	}
+	return dt
+}
+
+// Tracker assigns stable track IDs to detections across frames (SORT-style:
+// constant velocity Kalman prediction plus greedy IoU association per class)
+type Tracker struct {
+	config    TrackerConfig
+	tracks    []*track
+	nextID    int
+	lastFrame time.Time // Capture time of the latest frame, older frames are dropped
+	mutex     sync.Mutex
+}
+
+// NewTracker creates a new tracker
+func NewTracker(config TrackerConfig) *Tracker {
+	return &Tracker{
+		config: config,
+		nextID: 1,
+	}
+}
+
+// Update matches detections of one frame to existing tracks and returns copies of the detections
+// annotated with track ID, age and velocity. Frames are inferred concurrently and can finish out of
+// order, a frame captured before the latest one is dropped and Update returns false.
+func (t *Tracker) Update(detections []common.Detection, timestamp time.Time) ([]common.Detection, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if timestamp.Before(t.lastFrame) {
+		return nil, false
+	}
+	t.lastFrame = timestamp
+
+	type candidate struct {
+		trackIdx int
+		detIdx   int
+		iou      float64
+	}
+
+	var candidates []candidate
+	for ti, tr := range t.tracks {
+		predicted := tr.predictedBox(timestamp)
+		for di, det := range detections {
+			if det.Class != tr.class {
+				continue
+			}
+			if iou := common.IoU(predicted, det); iou >= t.config.IoUThreshold {
+				candidates = append(candidates, candidate{trackIdx: ti, detIdx: di, iou: iou})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].iou > candidates[j].iou })
+
+	result := make([]common.Detection, len(detections))
+	copy(result, detections)
+	trackMatched := make([]bool, len(t.tracks))
+	detMatched := make([]bool, len(detections))
+
+	for _, c := range candidates {
+		if trackMatched[c.trackIdx] || detMatched[c.detIdx] {
+			continue
+		}
+		trackMatched[c.trackIdx] = true
+		detMatched[c.detIdx] = true
+		tr := t.tracks[c.trackIdx]
+		tr.update(detections[c.detIdx], timestamp)
+		annotateDetection(&result[c.detIdx], tr)
+	}
+
+	// age unmatched tracks and drop expired ones
+	alive := t.tracks[:0]
+	for i, tr := range t.tracks {
+		if !trackMatched[i] {
+			tr.missed++
+			if tr.missed > t.config.MaxMissed || timestamp.Sub(tr.lastUpdate) > t.config.MaxIdle {
+				continue
+			}
+		}
+		alive = append(alive, tr)
+	}
+	t.tracks = alive
+
+	// start new tracks for unmatched detections
+	for i, det := range detections {
+		if detMatched[i] {
+			continue
+		}
+		tr := newTrack(t.nextID, det, timestamp)
+		t.nextID++
+		t.tracks = append(t.tracks, tr)
+		annotateDetection(&result[i], tr)
+	}
+
+	return result, true
+}
+
+// annotateDetection copies track identity and motion into a detection
+func annotateDetection(det *common.Detection, tr *track) {
+	det.TrackID = tr.id
+	det.TrackAge = tr.hits
+	det.VelocityX = tr.cx.vel
+	det.VelocityY = tr.cy.vel
+}
+
+// Trackers per camera and inference server binding
+var trackers = make(map[string]*Tracker)
+var trackersMutex sync.Mutex
+
+// getTracker returns the tracker of a camera/server binding, creating it if needed
+func getTracker(cameraID, serverID string) *Tracker {
+	trackersMutex.Lock()
+	defer trackersMutex.Unlock()
+	key := cameraID + "/" + serverID
+	tracker, exists := trackers[key]
+	if !exists {
+		tracker = NewTracker(DefaultTrackerConfig())
+		trackers[key] = tracker
+	}
+	return tracker
+}
+
+// clearTrackers drops all trackers of a deleted camera
+func clearTrackers(cameraID string) {
+	trackersMutex.Lock()
+	defer trackersMutex.Unlock()
+	prefix := cameraID + "/"
+	for key := range trackers {
+		if strings.HasPrefix(key, prefix) {
+			delete(trackers, key)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"cam-stream/common"
+	"testing"
+	"time"
+)
+
+// box returns a detection of class with its top left corner at x, y
+func box(class string, x, y, w, h int) common.Detection {
+	return common.Detection{Class: class, Confidence: 0.9, X1: x, Y1: y, X2: x + w, Y2: y + h}
+}
+
+// frameTime returns the capture time of frame i at 5 FPS
+func frameTime(i int) time.Time {
+	return time.Unix(1700000000, 0).Add(time.Duration(i) * 200 * time.Millisecond)
+}
+
+func TestTrackerKeepsIDOfMovingBox(t *testing.T) {
+	tracker := NewTracker(DefaultTrackerConfig())
+
+	var id int
+	for i := 0; i < 20; i++ {
+		result, ok := tracker.Update([]common.Detection{box("person", 100+8*i, 200, 60, 120)}, frameTime(i))
+		if !ok || len(result) != 1 {
+			t.Fatalf("frame %d: got %d detections, ok=%v", i, len(result), ok)
+		}
+		if i == 0 {
+			id = result[0].TrackID
+		}
+		if result[0].TrackID != id {
+			t.Fatalf("frame %d: track ID changed from %d to %d", i, id, result[0].TrackID)
+		}
+		if result[0].TrackAge != i+1 {
+			t.Errorf("frame %d: track age %d, want %d", i, result[0].TrackAge, i+1)
+		}
+	}
+
+	// 8 pixels per 200ms
+	result, _ := tracker.Update([]common.Detection{box("person", 260, 200, 60, 120)}, frameTime(20))
+	if v := result[0].VelocityX; v < 30 || v > 50 {
+		t.Errorf("velocity x %.1f, want about 40 px/s", v)
+	}
+}
+
+func TestTrackerSeparatesClasses(t *testing.T) {
+	tracker := NewTracker(DefaultTrackerConfig())
+
+	tracker.Update([]common.Detection{box("helmet", 100, 100, 50, 50)}, frameTime(0))
+	result, _ := tracker.Update([]common.Detection{box("smoke", 100, 100, 50, 50)}, frameTime(1))
+	if result[0].TrackID != 2 {
+		t.Errorf("box of another class got track %d, want new track 2", result[0].TrackID)
+	}
+}
+
+func TestTrackerExpiresMissedTracks(t *testing.T) {
+	config := DefaultTrackerConfig()
+	tracker := NewTracker(config)
+
+	first, _ := tracker.Update([]common.Detection{box("person", 100, 100, 50, 100)}, frameTime(0))
+
+	// a short gap keeps the track
+	for i := 1; i <= config.MaxMissed; i++ {
+		tracker.Update(nil, frameTime(i))
+	}
+	result, _ := tracker.Update([]common.Detection{box("person", 100, 100, 50, 100)}, frameTime(config.MaxMissed+1))
+	if result[0].TrackID != first[0].TrackID {
+		t.Fatalf("track %d lost after %d missed frames, got %d", first[0].TrackID, config.MaxMissed, result[0].TrackID)
+	}
+
+	// one more missed frame than allowed drops it
+	next := config.MaxMissed + 2
+	for i := 0; i <= config.MaxMissed; i++ {
+		tracker.Update(nil, frameTime(next))
+		next++
+	}
+	result, _ = tracker.Update([]common.Detection{box("person", 100, 100, 50, 100)}, frameTime(next))
+	if result[0].TrackID == first[0].TrackID {
+		t.Errorf("track %d survived %d missed frames", first[0].TrackID, config.MaxMissed+1)
+	}
+}
+
+func TestTrackerExpiresIdleTracks(t *testing.T) {
+	config := DefaultTrackerConfig()
+	tracker := NewTracker(config)
+
+	first, _ := tracker.Update([]common.Detection{box("person", 100, 100, 50, 100)}, frameTime(0))
+	tracker.Update(nil, frameTime(0).Add(config.MaxIdle+time.Second))
+	result, _ := tracker.Update([]common.Detection{box("person", 100, 100, 50, 100)}, frameTime(0).Add(config.MaxIdle+2*time.Second))
+	if result[0].TrackID == first[0].TrackID {
+		t.Errorf("track %d survived %v without detections", first[0].TrackID, config.MaxIdle)
+	}
+}
+
+func TestTrackerFollowsCrossingBoxes(t *testing.T) {
+	tracker := NewTracker(DefaultTrackerConfig())
+
+	// two persons walk past each other, the boxes overlap for a few frames in the middle
+	var leftID, rightID int
+	for i := 0; i <= 20; i++ {
+		detections := []common.Detection{
+			box("person", 100+20*i, 200, 60, 120), // walking right
+			box("person", 500-20*i, 230, 60, 120), // walking left
+		}
+		result, ok := tracker.Update(detections, frameTime(i))
+		if !ok {
+			t.Fatalf("frame %d dropped", i)
+		}
+		if i == 0 {
+			leftID, rightID = result[0].TrackID, result[1].TrackID
+			continue
+		}
+		if result[0].TrackID != leftID || result[1].TrackID != rightID {
+			t.Fatalf("frame %d: tracks swapped or lost, got %d and %d, want %d and %d",
+				i, result[0].TrackID, result[1].TrackID, leftID, rightID)
+		}
+	}
+}
+
+func TestTrackerDropsOutOfOrderFrames(t *testing.T) {
+	tracker := NewTracker(DefaultTrackerConfig())
+
+	if _, ok := tracker.Update([]common.Detection{box("person", 100, 100, 50, 100)}, frameTime(2)); !ok {
+		t.Fatal("first frame dropped")
+	}
+	if _, ok := tracker.Update([]common.Detection{box("person", 90, 100, 50, 100)}, frameTime(1)); ok {
+		t.Error("older frame was not dropped")
+	}
+	result, ok := tracker.Update([]common.Detection{box("person", 110, 100, 50, 100)}, frameTime(3))
+	if !ok || result[0].TrackID != 1 || result[0].TrackAge != 2 {
+		t.Errorf("newer frame: ok=%v track %d age %d, want track 1 age 2", ok, result[0].TrackID, result[0].TrackAge)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -115,14 +116,33 @@ func (ws *WebServer) Start() error {
 	api := router.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/cameras", ws.handleAPICameras).Methods("GET", "POST", "OPTIONS")
 	api.HandleFunc("/cameras/{id}", ws.handleAPICameraByID).Methods("GET", "PUT", "DELETE", "OPTIONS")
+	api.HandleFunc("/cameras/{id}/health", ws.handleAPICameraHealth).Methods("GET", "OPTIONS")
 
 	// Inference Server API Routes
 	api.HandleFunc("/inference-servers", ws.handleAPIInferenceServers).Methods("GET", "POST", "OPTIONS")
+	api.HandleFunc("/inference-servers/discover", ws.handleAPIDiscoverModels).Methods("POST", "OPTIONS")
+	api.HandleFunc("/inference-servers/discover/create", ws.handleAPIDiscoverCreateServers).Methods("POST", "OPTIONS")
 	api.HandleFunc("/inference-servers/{id}", ws.handleAPIInferenceServerByID).Methods("GET", "PUT", "DELETE", "OPTIONS")
+	api.HandleFunc("/server-groups", ws.handleAPIServerGroups).Methods("GET", "POST", "OPTIONS")
+	api.HandleFunc("/server-groups/{id}", ws.handleAPIServerGroupByID).Methods("GET", "PUT", "DELETE", "OPTIONS")
+
+	// Model Registry API Routes
+	api.HandleFunc("/models", ws.handleAPIModels).Methods("GET", "POST", "OPTIONS")
+	api.HandleFunc("/models/{name}", ws.handleAPIModelByName).Methods("GET", "PUT", "DELETE", "OPTIONS")
 
 	// Alert Server API Routes
 	api.HandleFunc("/alert-server", ws.handleAPIAlertServer).Methods("GET", "PUT", "OPTIONS")
 
+	// Alert destination API routes, preview routes first so "preview" is not taken as an ID
+	api.HandleFunc("/alert-destinations", ws.handleAPIAlertDestinations).Methods("GET", "POST", "OPTIONS")
+	api.HandleFunc("/alert-destinations/preview", ws.handleAPIAlertDestinationPreview).Methods("POST", "OPTIONS")
+	api.HandleFunc("/alert-destinations/{id}/preview", ws.handleAPIAlertDestinationPreview).Methods("GET", "OPTIONS")
+	api.HandleFunc("/alert-destinations/{id}", ws.handleAPIAlertDestinationByID).Methods("GET", "PUT", "DELETE", "OPTIONS")
+
+	// Schedule API routes
+	api.HandleFunc("/schedules", ws.handleAPISchedules).Methods("GET", "POST", "OPTIONS")
+	api.HandleFunc("/schedules/{id}", ws.handleAPIScheduleByID).Methods("GET", "PUT", "DELETE", "OPTIONS")
+
 	api.HandleFunc("/status", ws.handleAPIStatus).Methods("GET", "OPTIONS")
 	api.HandleFunc("/debug", ws.handleAPIDebug).Methods("GET", "OPTIONS")
 	api.HandleFunc("/ping", ws.handleAPIPing).Methods("GET", "OPTIONS")
@@ -130,6 +150,15 @@ func (ws *WebServer) Start() error {
 	// Image servers API routes (by inference server ID)
 	api.HandleFunc("/image-servers", ws.handleAPIImageServers).Methods("GET", "OPTIONS")
 	api.HandleFunc("/server-images/{serverId}", ws.handleAPIServerImages).Methods("GET", "OPTIONS")
+	api.HandleFunc("/server-images/{serverId}/{filename}/feedback", ws.handleAPIResultFeedback).Methods("GET", "PUT", "DELETE", "OPTIONS")
+	api.HandleFunc("/feedback/stats", ws.handleAPIFeedbackStats).Methods("GET", "OPTIONS")
+	api.HandleFunc("/results", ws.handleAPIResults).Methods("GET", "OPTIONS")
+	api.HandleFunc("/thumbnails/{serverId}/{filename}", ws.handleAPIThumbnail).Methods("GET", "OPTIONS")
+	api.HandleFunc("/results/delete", ws.handleAPIResultsDelete).Methods("POST", "OPTIONS")
+	api.HandleFunc("/results/download", ws.handleAPIResultsDownload).Methods("POST", "OPTIONS")
+
+	// Training dataset API Routes
+	api.HandleFunc("/datasets/export", ws.handleAPIDatasetExport).Methods("GET", "OPTIONS")
 
 	// Config import/export routes
 	api.HandleFunc("/config/export", ws.handleAPIConfigExport).Methods("GET", "OPTIONS")
@@ -187,6 +216,135 @@ func generateInferenceServerID(modelType string) string {
 	return fmt.Sprintf("inf_%s_%s", sanitizedModelType, uuidPart)
 }
 
+// generateAlertDestinationID generates a unique ID for alert destinations
+func generateAlertDestinationID() string {
+	return "dst_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// generateServerGroupID generates a unique ID for server groups
+func generateServerGroupID() string {
+	return "grp_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// generateScheduleID generates a unique ID for schedules
+func generateScheduleID() string {
+	return "sch_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// validateServerModelType checks that the model type of a server is registered,
+// modelExists resolves it against the current data store or imported data
+func validateServerModelType(server *store.InferenceServer, modelExists func(name string) bool) error {
+	if !modelExists(server.ModelType) {
+		return fmt.Errorf("model type %q is not registered, add it under /api/models first", server.ModelType)
+	}
+	return nil
+}
+
+// removeCameraBindings drops the bindings matching remove from all cameras. Readers use cameras outside
+// the lock, so changed cameras are replaced by copies. Callers hold the data store lock.
+func removeCameraBindings(remove func(binding *store.InferenceServerBinding) bool) {
+	for cameraID, camera := range store.Data.Cameras {
+		bindings := make([]store.InferenceServerBinding, 0, len(camera.InferenceServerBindings))
+		for i := range camera.InferenceServerBindings {
+			if !remove(&camera.InferenceServerBindings[i]) {
+				bindings = append(bindings, camera.InferenceServerBindings[i])
+			}
+		}
+		if len(bindings) != len(camera.InferenceServerBindings) {
+			updated := *camera
+			updated.InferenceServerBindings = bindings
+			updated.UpdatedAt = time.Now()
+			store.Data.Cameras[cameraID] = &updated
+		}
+	}
+}
+
+// validateCameraConfig validates the advanced settings of a camera and assigns missing rule IDs,
+// scheduleExists and groupExists resolve references against the current data store or imported data
+func validateCameraConfig(camera *store.CameraConfig, scheduleExists, groupExists func(id string) bool) error {
+	if err := camera.FFmpegOptions.Validate(); err != nil {
+		return fmt.Errorf("ffmpeg_options: %v", err)
+	}
+	if err := camera.Republish.Validate(); err != nil {
+		return fmt.Errorf("republish: %v", err)
+	}
+	if err := camera.Schedule.Validate(scheduleExists); err != nil {
+		return fmt.Errorf("schedule: %v", err)
+	}
+	for _, binding := range camera.InferenceServerBindings {
+		if binding.ServerID != "" && binding.GroupID != "" {
+			return fmt.Errorf("binding %s: server_id and group_id are mutually exclusive", binding.ServerID)
+		}
+		if binding.GroupID != "" && !groupExists(binding.GroupID) {
+			return fmt.Errorf("binding: server group %q not found", binding.GroupID)
+		}
+		if err := binding.Lifecycle.Validate(); err != nil {
+			return fmt.Errorf("binding %s: %v", binding.TargetID(), err)
+		}
+		if err := binding.Schedule.Validate(scheduleExists); err != nil {
+			return fmt.Errorf("binding %s: schedule: %v", binding.TargetID(), err)
+		}
+	}
+	// rule engines keep their dwell, crossing and condition state by rule ID
+	ruleIDs := make(map[string]bool)
+	for i := range camera.GeometryRules {
+		rule := &camera.GeometryRules[i]
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("geometry_rules: %v", err)
+		}
+		if rule.ID == "" {
+			rule.ID = "rule_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+		}
+		if ruleIDs[rule.ID] {
+			return fmt.Errorf("geometry_rules: rule id %q is used twice", rule.ID)
+		}
+		ruleIDs[rule.ID] = true
+	}
+	for i := range camera.CompositeRules {
+		rule := &camera.CompositeRules[i]
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("composite_rules: %v", err)
+		}
+		if rule.ID == "" {
+			rule.ID = "rule_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+		}
+		if ruleIDs[rule.ID] {
+			return fmt.Errorf("composite_rules: rule id %q is used twice", rule.ID)
+		}
+		ruleIDs[rule.ID] = true
+	}
+	return nil
+}
+
+// mergeCameraUpdate applies the top level fields of a camera update request to a copy of the camera,
+// fields missing in the request keep their current values
+func mergeCameraUpdate(camera *store.CameraConfig, body io.Reader) (*store.CameraConfig, error) {
+	var update map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&update); err != nil {
+		return nil, err
+	}
+	current, err := json.Marshal(camera)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(current, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range update {
+		fields[name] = value
+	}
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var updated store.CameraConfig
+	if err := json.Unmarshal(merged, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
@@ -206,11 +364,15 @@ func (ws *WebServer) handleAPICameras(w http.ResponseWriter, r *http.Request) {
 				cameraList = append(cameraList, camera)
 			}
 		})
+		cameraViews := make([]CameraView, 0, len(cameraList))
+		for _, camera := range cameraList {
+			cameraViews = append(cameraViews, newCameraView(camera))
+		}
 
 		response := APIResponse{
 			Success: true,
 			Message: "Cameras retrieved successfully",
-			Data:    cameraList,
+			Data:    cameraViews,
 		}
 		json.NewEncoder(w).Encode(response)
 
@@ -237,6 +399,17 @@ func (ws *WebServer) handleAPICameras(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err := validateCameraConfig(&newCamera, store.ScheduleExists, store.ServerGroupExists); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid camera configuration",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
 		if newCamera.ID == "" {
 			newCamera.ID = generateCameraID()
 		}
@@ -275,7 +448,7 @@ func (ws *WebServer) handleAPICameras(w http.ResponseWriter, r *http.Request) {
 		response := APIResponse{
 			Success: true,
 			Message: "Camera created successfully",
-			Data:    &newCamera,
+			Data:    newCameraView(&newCamera),
 		}
 
 		w.WriteHeader(http.StatusCreated)
@@ -305,13 +478,14 @@ func (ws *WebServer) handleAPICameraByID(w http.ResponseWriter, r *http.Request)
 		response := APIResponse{
 			Success: true,
 			Message: "Camera retrieved successfully",
-			Data:    camera,
+			Data:    newCameraView(camera),
 		}
 		json.NewEncoder(w).Encode(response)
 
 	case "PUT":
-		var updatedCamera store.CameraConfig
-		if err := json.NewDecoder(r.Body).Decode(&updatedCamera); err != nil {
+		// Fields missing in the request keep their values, the camera form only knows some of them
+		updatedCamera, err := mergeCameraUpdate(camera, r.Body)
+		if err != nil {
 			response := APIResponse{
 				Success: false,
 				Message: "Invalid request body",
@@ -322,12 +496,23 @@ func (ws *WebServer) handleAPICameraByID(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		if err := validateCameraConfig(updatedCamera, store.ScheduleExists, store.ServerGroupExists); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid camera configuration",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
 		updatedCamera.ID = id
 		updatedCamera.CreatedAt = camera.CreatedAt
 		updatedCamera.UpdatedAt = time.Now()
 
 		store.SafeUpdateDataStore(func() {
-			store.Data.Cameras[id] = &updatedCamera
+			store.Data.Cameras[id] = updatedCamera
 		})
 
 		if err := store.SaveDataStore(); err != nil {
@@ -336,10 +521,21 @@ func (ws *WebServer) handleAPICameraByID(w http.ResponseWriter, r *http.Request)
 
 		log.Info(fmt.Sprintf("updated camera: %s", id))
 
+		// the stream reads its URL and ffmpeg options once when it starts
+		message := "Camera updated successfully"
+		if ws.RtspManager != nil && (updatedCamera.RTSPUrl != camera.RTSPUrl || !updatedCamera.FFmpegOptions.Equal(camera.FFmpegOptions)) {
+			message = "Camera updated successfully, restarting its stream"
+			go func() {
+				if err := ws.RtspManager.RestartCamera(updatedCamera); err != nil {
+					log.Warn(fmt.Sprintf("failed to restart RTSP stream for camera %s: %v", id, err))
+				}
+			}()
+		}
+
 		response := APIResponse{
 			Success: true,
-			Message: "Camera updated successfully",
-			Data:    &updatedCamera,
+			Message: message,
+			Data:    newCameraView(updatedCamera),
 		}
 		json.NewEncoder(w).Encode(response)
 
@@ -362,6 +558,11 @@ func (ws *WebServer) handleAPICameraByID(w http.ResponseWriter, r *http.Request)
 		store.SafeUpdateDataStore(func() {
 			delete(store.Data.Cameras, id)
 		})
+		store.SafeDeleteCameraHealth(id)
+		clearOverlay(id)
+		clearTrackers(id)
+		clearGeometryRuleEngine(id)
+		clearCompositeRuleEngine(id)
 
 		if err := store.SaveDataStore(); err != nil {
 			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
@@ -377,6 +578,25 @@ func (ws *WebServer) handleAPICameraByID(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// handleAPICameraHealth returns the runtime stream health of a camera
+func (ws *WebServer) handleAPICameraHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+	if _, exists := store.SafeGetCamera(id); !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "Camera not found"})
+		return
+	}
+
+	health, exists := store.SafeGetCameraHealth(id)
+	if !exists {
+		health = store.CameraHealthState{CameraID: id}
+	}
+
+	json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "Camera health retrieved successfully", Data: health})
+}
+
 func (ws *WebServer) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -482,7 +702,16 @@ func (ws *WebServer) handleAPIPing(w http.ResponseWriter, r *http.Request) {
 type ImageInfo struct {
 	Filename  string    `json:"filename"`
 	Size      int64     `json:"size"`
-	CreatedAt time.Time `json:"created_at"`
+	CreatedAt time.Time `json:"created_at"` // Capture time, file time of images without metadata
+	// Saved result metadata, empty for images without metadata
+	ServerID   string  `json:"server_id,omitempty"`
+	CameraID   string  `json:"camera_id,omitempty"`
+	CameraName string  `json:"camera_name,omitempty"`
+	ModelType  string  `json:"model_type,omitempty"`
+	RuleEvent  string  `json:"rule_event,omitempty"` // Rule event type
+	Detections int     `json:"detections"`
+	MaxScore   float64 `json:"max_score"`
+	Reviewed   int     `json:"reviewed"` // Detections with an operator verdict
 }
 
 type ImageListResponse struct {
@@ -498,49 +727,6 @@ type ImageServer struct {
 	Name string `json:"name"`
 }
 
-func getImagesFromDirectory(dir string) ([]ImageInfo, error) {
-	var images []ImageInfo
-
-	// Check if directory exists
-	if _, err := os.Stat(dir); err != nil {
-		if os.IsNotExist(err) {
-			return images, nil
-		}
-		return nil, fmt.Errorf("failed to stat dir: %w", err)
-	}
-
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %v", err)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		filename := entry.Name()
-		lowerName := strings.ToLower(filename)
-		if !strings.HasSuffix(lowerName, ".jpg") && !strings.HasSuffix(lowerName, ".jpeg") {
-			continue
-		}
-
-		path := filepath.Join(dir, filename)
-		info, err := os.Stat(path)
-		if err != nil {
-			return nil, fmt.Errorf("stat %s: %w", path, err)
-		}
-
-		images = append(images, ImageInfo{
-			Filename:  filename,
-			Size:      info.Size(),
-			CreatedAt: info.ModTime(),
-		})
-	}
-
-	return images, nil
-}
-
 func fileExists(filename string) bool {
 	_, err := os.Stat(filename)
 	return !os.IsNotExist(err)
@@ -562,8 +748,8 @@ func (ws *WebServer) handleAPIImageServers(w http.ResponseWriter, r *http.Reques
 	for _, e := range entries {
 		if e.IsDir() {
 			id := e.Name()
-			// only include servers that exist in dataStore (not deleted)
-			server, exists := store.SafeGetInferenceServer(id)
+			// only include servers and groups that exist in dataStore (not deleted)
+			server, exists := getTargetServer(id)
 			if exists && server != nil {
 				name := server.Name
 				if name == "" {
@@ -581,82 +767,1476 @@ func (ws *WebServer) handleAPIImageServers(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "image servers retrieved successfully", Data: servers})
 }
 
-// handleAPIServerImages returns paginated images for a given serverId directory
+// handleAPIServerImages returns paginated images for a given serverId directory, filtered like parseResultFilter
 func (ws *WebServer) handleAPIServerImages(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+	query.Set("server_id", mux.Vars(r)["serverId"])
+	ws.writeResultImages(w, r, query)
+}
 
-	vars := mux.Vars(r)
-	serverId := vars["serverId"]
+// handleAPIResults returns paginated images of all servers, filtered like parseResultFilter
+func (ws *WebServer) handleAPIResults(w http.ResponseWriter, r *http.Request) {
+	ws.writeResultImages(w, r, r.URL.Query())
+}
+
+// writeResultImages writes a page of the result images matching the query, newest first
+func (ws *WebServer) writeResultImages(w http.ResponseWriter, r *http.Request, query url.Values) {
+	w.Header().Set("Content-Type", "application/json")
 
 	// parse pagination
 	// TODO: hard coding
 	page := 1
 	limit := 24
-	if p := r.URL.Query().Get("page"); p != "" {
+	if p := query.Get("page"); p != "" {
 		if v, err := strconv.Atoi(p); err == nil && v > 0 {
 			page = v
 		}
 	}
-	if l := r.URL.Query().Get("limit"); l != "" {
+	if l := query.Get("limit"); l != "" {
 		if v, err := strconv.Atoi(l); err == nil && v > 0 && v <= 100 {
 			limit = v
 		}
-	}
+	}
+
+	filter, err := parseResultFilter(query)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "invalid filter", Error: err.Error()})
+		return
+	}
+
+	images, err := listResultImages(ws.OutputDir, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "failed to read server images", Error: err.Error()})
+		return
+	}
+
+	totalCount := len(images)
+	totalPages := (totalCount + limit - 1) / limit
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	start := (page - 1) * limit
+	end := start + limit
+	if start >= totalCount {
+		images = []ImageInfo{}
+	} else {
+		if end > totalCount {
+			end = totalCount
+		}
+		images = images[start:end]
+	}
+
+	json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "images retrieved successfully", Data: ImageListResponse{Images: images, TotalCount: totalCount, TotalPages: totalPages, CurrentPage: page}})
+}
+
+// maxResultSelection bounds the results of a bulk delete or download
+const maxResultSelection = 1000
+
+// decodeResultSelection reads {"results": [{"server_id", "filename"}]} of bulk operations
+func (ws *WebServer) decodeResultSelection(r *http.Request) ([]ResultRef, error) {
+	var request struct {
+		Results []ResultRef `json:"results"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if len(request.Results) == 0 {
+		return nil, fmt.Errorf("no results selected")
+	}
+	if len(request.Results) > maxResultSelection {
+		return nil, fmt.Errorf("at most %d results can be selected", maxResultSelection)
+	}
+	for _, ref := range request.Results {
+		if _, err := resultImagePath(ws.OutputDir, ref.ServerID, ref.Filename); err != nil {
+			return nil, err
+		}
+	}
+	return request.Results, nil
+}
+
+// handleAPIResultsDelete deletes the selected result images with their metadata and DEBUG originals
+func (ws *WebServer) handleAPIResultsDelete(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	refs, err := ws.decodeResultSelection(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "invalid selection", Error: err.Error()})
+		return
+	}
+
+	type failedResult struct {
+		ResultRef
+		Error string `json:"error"`
+	}
+	var deleteResponse struct {
+		Deleted int            `json:"deleted"`
+		Failed  []failedResult `json:"failed"`
+	}
+	deleteResponse.Failed = []failedResult{}
+	for _, ref := range refs {
+		if err := deleteResult(ws.OutputDir, ref); err != nil {
+			deleteResponse.Failed = append(deleteResponse.Failed, failedResult{ResultRef: ref, Error: err.Error()})
+			continue
+		}
+		deleteResponse.Deleted++
+	}
+
+	log.Info(fmt.Sprintf("deleted %d saved results, %d failed", deleteResponse.Deleted, len(deleteResponse.Failed)))
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: len(deleteResponse.Failed) == 0,
+		Message: fmt.Sprintf("%d results deleted", deleteResponse.Deleted),
+		Data:    deleteResponse,
+	})
+}
+
+// handleAPIResultsDownload sends the selected result images and their metadata as a zip
+func (ws *WebServer) handleAPIResultsDownload(w http.ResponseWriter, r *http.Request) {
+	refs, err := ws.decodeResultSelection(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "invalid selection", Error: err.Error()})
+		return
+	}
+	for _, ref := range refs {
+		imagePath, _ := resultImagePath(ws.OutputDir, ref.ServerID, ref.Filename)
+		if _, err := os.Stat(imagePath); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "result not found", Error: err.Error()})
+			return
+		}
+	}
+
+	timestamp := config.InTimezone(time.Now()).Format("2006-01-02_15-04-05")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=results_%s.zip", timestamp))
+	if err := writeResultArchive(w, ws.OutputDir, refs); err != nil {
+		// the zip is already being sent, the client gets a truncated archive
+		log.Warn(fmt.Sprintf("failed to send result archive: %v", err))
+	}
+}
+
+// handleAPIThumbnail serves a cached thumbnail of a result image, ?size= picks the longer side
+func (ws *WebServer) handleAPIThumbnail(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	writeError := func(status int, message string, err error) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: message, Error: err.Error()})
+	}
+
+	size, err := parseThumbnailSize(r.URL.Query().Get("size"))
+	if err != nil {
+		writeError(http.StatusBadRequest, "invalid thumbnail size", err)
+		return
+	}
+	imagePath, err := resultImagePath(ws.OutputDir, vars["serverId"], vars["filename"])
+	if err != nil {
+		writeError(http.StatusBadRequest, "invalid result", err)
+		return
+	}
+	source, err := os.Stat(imagePath)
+	if err != nil {
+		writeError(http.StatusNotFound, "image not found", err)
+		return
+	}
+
+	path, err := ensureThumbnail(imagePath, vars["serverId"], vars["filename"], size, source)
+	if err != nil {
+		log.Warn(fmt.Sprintf("failed to create thumbnail of %s: %v", imagePath, err))
+		writeError(http.StatusInternalServerError, "failed to create thumbnail", err)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		writeError(http.StatusInternalServerError, "failed to read thumbnail", err)
+		return
+	}
+	defer f.Close()
+
+	// ServeContent answers revalidations with 304 from the ETag
+	w.Header().Set("ETag", thumbnailETag(source, size))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(thumbnailMaxAge.Seconds())))
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeContent(w, r, vars["filename"], source.ModTime(), f)
+}
+
+// parseResultFilter reads the saved result filter from the query parameters server_id, camera_id,
+// model_type, min_score and the RFC 3339 times from and to
+func parseResultFilter(query url.Values) (*resultFilter, error) {
+	filter := &resultFilter{ServerID: query.Get("server_id"), CameraID: query.Get("camera_id"), ModelType: query.Get("model_type")}
+	if filter.ServerID != "" && (filter.ServerID != filepath.Base(filter.ServerID) || strings.HasPrefix(filter.ServerID, ".")) {
+		return nil, fmt.Errorf("invalid server_id %q", filter.ServerID)
+	}
+	if camera, exists := store.SafeGetCamera(filter.CameraID); exists {
+		filter.CameraName = camera.Name
+	}
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if v := query.Get(param.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s, expected RFC 3339 time: %v", param.name, err)
+			}
+			*param.value = t
+		}
+	}
+	if v := query.Get("min_score"); v != "" {
+		score, err := strconv.ParseFloat(v, 64)
+		if err != nil || score < 0 || score > 1 {
+			return nil, fmt.Errorf("min_score must be between 0 and 1")
+		}
+		filter.MinScore = score
+	}
+	return filter, nil
+}
+
+// handleAPIDatasetExport bundles the original frames and labels of saved results into a zip,
+// filtered like parseResultFilter and selected by their verdicts with include
+func (ws *WebServer) handleAPIDatasetExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	badRequest := func(message string, err error) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		response := APIResponse{Success: false, Message: message}
+		if err != nil {
+			response.Error = err.Error()
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = DatasetFormatYOLO
+	}
+	if format != DatasetFormatYOLO && format != DatasetFormatVOC && format != DatasetFormatCOCO {
+		badRequest(fmt.Sprintf("unsupported format %q, expected yolo, voc or coco", format), nil)
+		return
+	}
+
+	include := query.Get("include")
+	if include == "" {
+		include = DatasetIncludeAll
+	}
+	if include != DatasetIncludeAll && include != DatasetIncludeConfirmed && include != DatasetIncludeHardNegatives {
+		badRequest(fmt.Sprintf("unsupported include %q, expected all, confirmed or hard_negatives", include), nil)
+		return
+	}
+
+	filter, err := parseResultFilter(query)
+	if err != nil {
+		badRequest("invalid filter", err)
+		return
+	}
+
+	valRatio := defaultValRatio
+	if v := query.Get("val_ratio"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil || ratio < 0 || ratio >= 1 {
+			badRequest("val_ratio must be at least 0 and below 1", err)
+			return
+		}
+		valRatio = ratio
+	}
+
+	classes, err := datasetClasses()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "model class indices are not unique", Error: err.Error()})
+		return
+	}
+	samples, skipped, err := collectDatasetSamples(ws.OutputDir, filter, include, valRatio, classes)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "failed to collect dataset samples", Error: err.Error()})
+		return
+	}
+	if len(samples) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("no samples found (%d results skipped), original frames are only saved in DEBUG mode", skipped),
+		})
+		return
+	}
+
+	timestamp := config.InTimezone(time.Now()).Format("2006-01-02_15-04-05")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=dataset_%s_%s.zip", format, timestamp))
+	w.Header().Set("X-Dataset-Samples", strconv.Itoa(len(samples)))
+	w.Header().Set("X-Dataset-Skipped", strconv.Itoa(skipped))
+
+	if err := writeDataset(w, format, samples, classes); err != nil {
+		// the zip is already being sent, the client gets a truncated archive
+		log.Warn(fmt.Sprintf("failed to export dataset: %v", err))
+		return
+	}
+	log.Info(fmt.Sprintf("exported %s dataset with %d samples (%d skipped)", format, len(samples), skipped))
+}
+
+// handleAPIResultFeedback reads, updates or clears the operator verdicts on the detections of a saved result
+func (ws *WebServer) handleAPIResultFeedback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	path, err := resultMetadataPath(ws.OutputDir, vars["serverId"], vars["filename"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "invalid result", Error: err.Error()})
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "result metadata not found", Error: err.Error()})
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		metadata, err := readResultMetadata(path)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "failed to read result metadata", Error: err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "feedback retrieved successfully", Data: metadata})
+
+	case "PUT":
+		var request struct {
+			Verdicts []DetectionVerdict `json:"verdicts"`
+			Replace  bool               `json:"replace"` // drop verdicts on detections not in the request
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "invalid JSON", Error: err.Error()})
+			return
+		}
+		if len(request.Verdicts) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "at least one verdict is required"})
+			return
+		}
+
+		metadata, err := updateResultVerdicts(path, request.Verdicts, request.Replace)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "failed to save feedback", Error: err.Error()})
+			return
+		}
+		log.Info(fmt.Sprintf("saved %d verdicts on result %s/%s", len(request.Verdicts), vars["serverId"], vars["filename"]))
+		json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "feedback saved successfully", Data: metadata})
+
+	case "DELETE":
+		metadata, err := updateResultVerdicts(path, nil, true)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "failed to clear feedback", Error: err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "feedback cleared successfully", Data: metadata})
+	}
+}
+
+// handleAPIFeedbackStats returns the precision of saved detections per camera and model
+func (ws *WebServer) handleAPIFeedbackStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseResultFilter(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "invalid filter", Error: err.Error()})
+		return
+	}
+	stats, err := computeFeedbackStats(ws.OutputDir, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "failed to compute feedback statistics", Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "feedback statistics retrieved successfully", Data: stats})
+}
+
+// Inference Server API Handlers
+func (ws *WebServer) handleAPIInferenceServers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		var serverList []InferenceServerView
+		store.SafeReadDataStore(func() {
+			for _, server := range store.Data.InferenceServers {
+				serverList = append(serverList, newInferenceServerView(server))
+			}
+		})
+
+		response := APIResponse{
+			Success: true,
+			Message: "Inference servers retrieved successfully",
+			Data:    serverList,
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "POST":
+		var newServer store.InferenceServer
+		if err := json.NewDecoder(r.Body).Decode(&newServer); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if newServer.Name == "" || newServer.URL == "" {
+			response := APIResponse{
+				Success: false,
+				Message: "Name and URL are required",
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := newServer.HealthCheck.Validate(); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid health check configuration",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// Set default model type if not provided
+		if newServer.ModelType == "" {
+			newServer.ModelType = string(config.ModelTypeOther)
+		}
+
+		if err := validateServerModelType(&newServer, store.ModelDefinitionExists); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid model type",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// Catch mistyped URLs and model types before frames fail
+		if err := validateServerModel(&newServer); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Inference server validation failed",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if newServer.ID == "" {
+			newServer.ID = generateInferenceServerID(newServer.ModelType)
+		}
+
+		newServer.CreatedAt = time.Now()
+		newServer.UpdatedAt = time.Now()
+		newServer.Enabled = true
+
+		store.SafeUpdateDataStore(func() {
+			store.Data.InferenceServers[newServer.ID] = &newServer
+		})
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("created inference server: %s (%s)", newServer.ID, newServer.Name))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Inference server created successfully",
+			Data:    newInferenceServerView(&newServer),
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (ws *WebServer) handleAPIInferenceServerByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	server, exists := store.SafeGetInferenceServer(id)
+	if !exists {
+		response := APIResponse{
+			Success: false,
+			Message: "Inference server not found",
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		response := APIResponse{
+			Success: true,
+			Message: "Inference server retrieved successfully",
+			Data:    newInferenceServerView(server),
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "PUT":
+		var updatedServer store.InferenceServer
+		if err := json.NewDecoder(r.Body).Decode(&updatedServer); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := updatedServer.HealthCheck.Validate(); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid health check configuration",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if updatedServer.ModelType != server.ModelType {
+			// all members of a group serve the group's model
+			var groups []*store.ServerGroup
+			store.SafeReadDataStore(func() {
+				groups = serverGroupsWithMember(id)
+			})
+			if len(groups) > 0 {
+				response := APIResponse{
+					Success: false,
+					Message: fmt.Sprintf("Inference server is a member of group %s with model type %s, remove it from the group first",
+						groups[0].Name, groups[0].ModelType),
+				}
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			if err := validateServerModelType(&updatedServer, store.ModelDefinitionExists); err != nil {
+				response := APIResponse{
+					Success: false,
+					Message: "Invalid model type",
+					Error:   err.Error(),
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		// Disabling or renaming a server works while its host is down
+		if updatedServer.Enabled && (updatedServer.URL != server.URL || updatedServer.ModelType != server.ModelType) {
+			if err := validateServerModel(&updatedServer); err != nil {
+				response := APIResponse{
+					Success: false,
+					Message: "Inference server validation failed",
+					Error:   err.Error(),
+				}
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+		}
+
+		updatedServer.ID = id
+		updatedServer.CreatedAt = server.CreatedAt
+		updatedServer.UpdatedAt = time.Now()
+
+		store.SafeUpdateDataStore(func() {
+			store.Data.InferenceServers[id] = &updatedServer
+		})
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		// URL or health settings may have changed, start over with the next probe
+		store.SafeDeleteServerHealth(id)
+
+		log.Info(fmt.Sprintf("updated inference server: %s", id))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Inference server updated successfully",
+			Data:    newInferenceServerView(&updatedServer),
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "DELETE":
+		var conflict string
+		store.SafeUpdateDataStore(func() {
+			groups := serverGroupsWithMember(id)
+			// a group in use must keep a member
+			for _, group := range groups {
+				if len(group.ServerIDs) > 1 {
+					continue
+				}
+				if camera, used := cameraUsingGroup(group.ID); used {
+					conflict = fmt.Sprintf("Inference server is the last member of group %s used by camera %s", group.Name, camera.Name)
+					return
+				}
+			}
+
+			removeCameraBindings(func(binding *store.InferenceServerBinding) bool {
+				return binding.ServerID == id
+			})
+
+			// Leave the groups the server was a member of, groups are read without lock so store copies
+			for _, group := range groups {
+				updated := *group
+				updated.ServerIDs = make([]string, 0, len(group.ServerIDs))
+				for _, serverID := range group.ServerIDs {
+					if serverID != id {
+						updated.ServerIDs = append(updated.ServerIDs, serverID)
+					}
+				}
+				updated.UpdatedAt = time.Now()
+				store.Data.ServerGroups[group.ID] = &updated
+			}
+
+			delete(store.Data.InferenceServers, id)
+		})
+		if conflict != "" {
+			response := APIResponse{
+				Success: false,
+				Message: conflict,
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		store.SafeDeleteServerHealth(id)
+
+		log.Info(fmt.Sprintf("deleted inference server: %s", id))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Inference server deleted successfully",
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// DiscoverRequest asks for the models of an inference host
+type DiscoverRequest struct {
+	Address    string   `json:"address"`               // http://host:port or grpc://host:port
+	ModelTypes []string `json:"model_types,omitempty"` // Create: only these models, empty means all
+}
+
+// handleAPIDiscoverModels lists the models an inference host offers
+func (ws *WebServer) handleAPIDiscoverModels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req DiscoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Address == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "address is required"})
+		return
+	}
+
+	result, err := discoverModels(req.Address)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "Model discovery failed", Error: err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "Models discovered successfully", Data: result})
+}
+
+// handleAPIDiscoverCreateServers creates an inference server for every discovered model that is not configured yet
+func (ws *WebServer) handleAPIDiscoverCreateServers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req DiscoverRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Address == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "address is required"})
+		return
+	}
+
+	result, err := discoverModels(req.Address)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "Model discovery failed", Error: err.Error()})
+		return
+	}
+
+	wanted := make(map[string]bool)
+	for _, modelType := range req.ModelTypes {
+		if result.findModel(modelType) == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIResponse{Success: false,
+				Message: fmt.Sprintf("model type %q is not offered, available: %s", modelType, result.modelTypes())})
+			return
+		}
+		if !store.ModelDefinitionExists(modelType) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIResponse{Success: false,
+				Message: fmt.Sprintf("model type %q is not registered, add it under /api/models first", modelType)})
+			return
+		}
+		wanted[modelType] = true
+	}
+
+	// check every model before creating any server so a failed request creates nothing
+	host := strings.TrimPrefix(strings.TrimPrefix(result.Address, "http://"), "https://")
+	var newServers []*store.InferenceServer
+	for _, model := range result.Models {
+		if (len(wanted) > 0 && !wanted[model.ModelType]) || model.ConfiguredServerID != "" {
+			continue
+		}
+		if !model.Registered {
+			log.Warn(fmt.Sprintf("skipping unregistered model type %q offered by %s", model.ModelType, result.Address))
+			continue
+		}
+		if model.URL == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(APIResponse{Success: false,
+				Message: fmt.Sprintf("%s does not report inference urls, create servers manually", result.Protocol)})
+			return
+		}
+
+		newServer := &store.InferenceServer{
+			ID:          generateInferenceServerID(model.ModelType),
+			Name:        fmt.Sprintf("%s@%s", model.ModelType, host),
+			URL:         model.URL,
+			ModelType:   model.ModelType,
+			Description: strings.TrimSpace(model.Name + " " + model.Version),
+			Enabled:     true,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		newServers = append(newServers, newServer)
+	}
+
+	store.SafeUpdateDataStore(func() {
+		for _, newServer := range newServers {
+			store.Data.InferenceServers[newServer.ID] = newServer
+		}
+	})
+	created := []InferenceServerView{}
+	for _, newServer := range newServers {
+		created = append(created, newInferenceServerView(newServer))
+		log.Info(fmt.Sprintf("created inference server: %s (%s) from discovery", newServer.ID, newServer.Name))
+	}
+
+	if len(created) > 0 {
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIResponse{Success: true,
+		Message: fmt.Sprintf("%d inference servers created", len(created)), Data: created})
+}
+
+// Server Group API Handlers
+func (ws *WebServer) handleAPIServerGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		var groups []*store.ServerGroup
+		store.SafeReadDataStore(func() {
+			for _, group := range store.Data.ServerGroups {
+				groups = append(groups, group)
+			}
+		})
+		groupList := []ServerGroupView{}
+		for _, group := range groups {
+			groupList = append(groupList, newServerGroupView(group))
+		}
+
+		response := APIResponse{
+			Success: true,
+			Message: "Server groups retrieved successfully",
+			Data:    groupList,
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "POST":
+		var newGroup store.ServerGroup
+		if err := json.NewDecoder(r.Body).Decode(&newGroup); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := validateServerGroup(&newGroup, store.SafeGetInferenceServer); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid server group",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if newGroup.ID == "" {
+			newGroup.ID = generateServerGroupID()
+		}
+		newGroup.CreatedAt = time.Now()
+		newGroup.UpdatedAt = time.Now()
+
+		store.SafeUpdateDataStore(func() {
+			store.Data.ServerGroups[newGroup.ID] = &newGroup
+		})
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("created server group: %s (%s)", newGroup.ID, newGroup.Name))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Server group created successfully",
+			Data:    newServerGroupView(&newGroup),
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (ws *WebServer) handleAPIServerGroupByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	group, exists := store.SafeGetServerGroup(id)
+	if !exists {
+		response := APIResponse{
+			Success: false,
+			Message: "Server group not found",
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		response := APIResponse{
+			Success: true,
+			Message: "Server group retrieved successfully",
+			Data:    newServerGroupView(group),
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "PUT":
+		var updatedGroup store.ServerGroup
+		if err := json.NewDecoder(r.Body).Decode(&updatedGroup); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := validateServerGroup(&updatedGroup, store.SafeGetInferenceServer); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid server group",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		updatedGroup.ID = id
+		updatedGroup.CreatedAt = group.CreatedAt
+		updatedGroup.UpdatedAt = time.Now()
+
+		store.SafeUpdateDataStore(func() {
+			store.Data.ServerGroups[id] = &updatedGroup
+		})
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("updated server group: %s", id))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Server group updated successfully",
+			Data:    newServerGroupView(&updatedGroup),
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "DELETE":
+		// Remove the bindings targeting the group, like deleting a server does
+		store.SafeUpdateDataStore(func() {
+			removeCameraBindings(func(binding *store.InferenceServerBinding) bool {
+				return binding.GroupID == id
+			})
+
+			delete(store.Data.ServerGroups, id)
+		})
+		clearServerGroupBalancer(id)
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("deleted server group: %s", id))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Server group deleted successfully",
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// Model Registry API Handlers
+func (ws *WebServer) handleAPIModels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		modelList := []*store.ModelDefinition{}
+		store.SafeReadDataStore(func() {
+			for _, model := range store.Data.Models {
+				modelList = append(modelList, model)
+			}
+		})
+		sort.Slice(modelList, func(i, j int) bool {
+			return modelList[i].Name < modelList[j].Name
+		})
+
+		response := APIResponse{
+			Success: true,
+			Message: "Models retrieved successfully",
+			Data:    modelList,
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "POST":
+		var newModel store.ModelDefinition
+		if err := json.NewDecoder(r.Body).Decode(&newModel); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := newModel.Validate(); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid model",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		newModel.CreatedAt = time.Now()
+		newModel.UpdatedAt = time.Now()
+
+		var conflict error
+		store.SafeUpdateDataStore(func() {
+			if _, exists := store.Data.Models[newModel.Name]; exists {
+				conflict = fmt.Errorf("model %s already exists", newModel.Name)
+				return
+			}
+			if conflict = store.ClassIndexConflict(store.Data.Models, &newModel); conflict == nil {
+				store.Data.Models[newModel.Name] = &newModel
+			}
+		})
+		if conflict != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Model conflicts with registered models",
+				Error:   conflict.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("registered model: %s (%s)", newModel.Name, newModel.DisplayName))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Model created successfully",
+			Data:    &newModel,
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (ws *WebServer) handleAPIModelByName(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := mux.Vars(r)["name"]
+
+	model, exists := store.SafeGetModelDefinition(name)
+	if !exists {
+		response := APIResponse{
+			Success: false,
+			Message: "Model not found",
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		response := APIResponse{
+			Success: true,
+			Message: "Model retrieved successfully",
+			Data:    model,
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "PUT":
+		var updatedModel store.ModelDefinition
+		if err := json.NewDecoder(r.Body).Decode(&updatedModel); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// the name is the model type of inference servers and cannot change
+		updatedModel.Name = name
+		if err := updatedModel.Validate(); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid model",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		updatedModel.CreatedAt = model.CreatedAt
+		updatedModel.UpdatedAt = time.Now()
+
+		var conflict error
+		store.SafeUpdateDataStore(func() {
+			if conflict = store.ClassIndexConflict(store.Data.Models, &updatedModel); conflict == nil {
+				store.Data.Models[name] = &updatedModel
+			}
+		})
+		if conflict != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Model conflicts with registered models",
+				Error:   conflict.Error(),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("updated model: %s", name))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Model updated successfully",
+			Data:    &updatedModel,
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "DELETE":
+		var users []string
+		store.SafeReadDataStore(func() {
+			for _, server := range store.Data.InferenceServers {
+				if server.ModelType == name {
+					users = append(users, server.Name)
+				}
+			}
+		})
+		if len(users) > 0 {
+			response := APIResponse{
+				Success: false,
+				Message: "Model is used by inference servers",
+				Error:   fmt.Sprintf("delete or change the model type of %s first", strings.Join(users, ", ")),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		store.SafeUpdateDataStore(func() {
+			delete(store.Data.Models, name)
+		})
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("deleted model: %s", name))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Model deleted successfully",
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// Alert Server API Handler
+func (ws *WebServer) handleAPIAlertServer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		// Return current alert server configuration
+		var alertConfig *store.AlertServerConfig
+		store.SafeReadDataStore(func() {
+			if store.Data.AlertServer != nil {
+				alertConfig = store.Data.AlertServer
+			} else {
+				// Return default/empty configuration
+				alertConfig = &store.AlertServerConfig{
+					URL:       "",
+					Enabled:   false,
+					UpdatedAt: time.Now(),
+				}
+			}
+		})
+
+		response := APIResponse{
+			Success: true,
+			Message: "Alert server configuration retrieved successfully",
+			Data:    alertConfig,
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "PUT":
+		// Update alert server configuration
+		var updatedConfig store.AlertServerConfig
+		if err := json.NewDecoder(r.Body).Decode(&updatedConfig); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := updatedConfig.Validate(); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid alert server configuration",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		updatedConfig.UpdatedAt = time.Now()
+		store.SafeUpdateDataStore(func() {
+			store.Data.AlertServer = &updatedConfig
+		})
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("updated alert server configuration: URL=%s, Enabled=%t", updatedConfig.URL, updatedConfig.Enabled))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Alert server configuration updated successfully",
+			Data:    &updatedConfig,
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// Alert Destination API Handlers
+func (ws *WebServer) handleAPIAlertDestinations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		var destinationList []*store.AlertDestination
+		store.SafeReadDataStore(func() {
+			for _, destination := range store.Data.AlertDestinations {
+				destinationList = append(destinationList, destination.Masked())
+			}
+		})
+
+		response := APIResponse{
+			Success: true,
+			Message: "Alert destinations retrieved successfully",
+			Data:    destinationList,
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "POST":
+		var newDestination store.AlertDestination
+		if err := json.NewDecoder(r.Body).Decode(&newDestination); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if err := validateAlertDestination(&newDestination); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid alert destination",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if newDestination.ID == "" {
+			newDestination.ID = generateAlertDestinationID()
+		}
+		newDestination.CreatedAt = time.Now()
+		newDestination.UpdatedAt = time.Now()
+
+		store.SafeUpdateDataStore(func() {
+			store.Data.AlertDestinations[newDestination.ID] = &newDestination
+		})
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("created alert destination: %s (%s)", newDestination.ID, newDestination.Name))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Alert destination created successfully",
+			Data:    newDestination.Masked(),
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (ws *WebServer) handleAPIAlertDestinationByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := mux.Vars(r)["id"]
+
+	destination, exists := store.SafeGetAlertDestination(id)
+	if !exists {
+		response := APIResponse{
+			Success: false,
+			Message: "Alert destination not found",
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		response := APIResponse{
+			Success: true,
+			Message: "Alert destination retrieved successfully",
+			Data:    destination.Masked(),
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "PUT":
+		var updatedDestination store.AlertDestination
+		if err := json.NewDecoder(r.Body).Decode(&updatedDestination); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		// forms send the masked secrets of GET responses back unchanged
+		updatedDestination.RestoreSecrets(destination)
+		if err := validateAlertDestination(&updatedDestination); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid alert destination",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		updatedDestination.ID = id
+		updatedDestination.CreatedAt = destination.CreatedAt
+		updatedDestination.UpdatedAt = time.Now()
+
+		store.SafeUpdateDataStore(func() {
+			store.Data.AlertDestinations[id] = &updatedDestination
+		})
+		closeMQTTClient(id)
+		evictAlertTemplates(id)
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
+
+		log.Info(fmt.Sprintf("updated alert destination: %s", id))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Alert destination updated successfully",
+			Data:    updatedDestination.Masked(),
+		}
+		json.NewEncoder(w).Encode(response)
+
+	case "DELETE":
+		store.SafeUpdateDataStore(func() {
+			delete(store.Data.AlertDestinations, id)
+		})
+		closeMQTTClient(id)
+		evictAlertTemplates(id)
+
+		if err := store.SaveDataStore(); err != nil {
+			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
+		}
 
-	dir := filepath.Join(ws.OutputDir, serverId)
-	images, err := getImagesFromDirectory(dir)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "failed to read server images", Error: err.Error()})
-		return
+		log.Info(fmt.Sprintf("deleted alert destination: %s", id))
+
+		response := APIResponse{
+			Success: true,
+			Message: "Alert destination deleted successfully",
+		}
+		json.NewEncoder(w).Encode(response)
 	}
+}
 
-	// sort newest first by created_at (ModTime)
-	sort.Slice(images, func(i, j int) bool { return images[i].CreatedAt.After(images[j].CreatedAt) })
+// handleAPIAlertDestinationPreview renders a sample payload for a saved destination (GET)
+// or for the destination in the request body (POST), e.g. while editing a template
+func (ws *WebServer) handleAPIAlertDestinationPreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	totalCount := len(images)
-	totalPages := (totalCount + limit - 1) / limit
-	if totalPages == 0 {
-		totalPages = 1
+	var destination store.AlertDestination
+	if id, ok := mux.Vars(r)["id"]; ok {
+		saved, exists := store.SafeGetAlertDestination(id)
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "Alert destination not found"})
+			return
+		}
+		destination = *saved
+	} else if err := json.NewDecoder(r.Body).Decode(&destination); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "Invalid request body", Error: err.Error()})
+		return
 	}
 
-	start := (page - 1) * limit
-	end := start + limit
-	if start >= totalCount {
-		images = []ImageInfo{}
-	} else {
-		if end > totalCount {
-			end = totalCount
-		}
-		images = images[start:end]
+	payload, err := previewAlertPayload(&destination)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIResponse{Success: false, Message: "Failed to render payload", Error: err.Error()})
+		return
 	}
 
-	json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "images retrieved successfully", Data: ImageListResponse{Images: images, TotalCount: totalCount, TotalPages: totalPages, CurrentPage: page}})
+	json.NewEncoder(w).Encode(APIResponse{Success: true, Message: "Payload rendered successfully", Data: json.RawMessage(payload)})
 }
 
-// Inference Server API Handlers
-func (ws *WebServer) handleAPIInferenceServers(w http.ResponseWriter, r *http.Request) {
+// Schedule API Handlers
+func (ws *WebServer) handleAPISchedules(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	switch r.Method {
 	case "GET":
-		var serverList []*store.InferenceServer
+		var scheduleList []*store.Schedule
 		store.SafeReadDataStore(func() {
-			for _, server := range store.Data.InferenceServers {
-				serverList = append(serverList, server)
+			for _, schedule := range store.Data.Schedules {
+				scheduleList = append(scheduleList, schedule)
 			}
 		})
 
 		response := APIResponse{
 			Success: true,
-			Message: "Inference servers retrieved successfully",
-			Data:    serverList,
+			Message: "Schedules retrieved successfully",
+			Data:    scheduleList,
 		}
 		json.NewEncoder(w).Encode(response)
 
 	case "POST":
-		var newServer store.InferenceServer
-		if err := json.NewDecoder(r.Body).Decode(&newServer); err != nil {
+		var newSchedule store.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&newSchedule); err != nil {
 			response := APIResponse{
 				Success: false,
 				Message: "Invalid request body",
@@ -667,61 +2247,66 @@ func (ws *WebServer) handleAPIInferenceServers(w http.ResponseWriter, r *http.Re
 			return
 		}
 
-		if newServer.Name == "" || newServer.URL == "" {
+		if err := newSchedule.Validate(); err != nil {
 			response := APIResponse{
 				Success: false,
-				Message: "Name and URL are required",
+				Message: "Invalid schedule",
+				Error:   err.Error(),
 			}
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
 
-		// Set default model type if not provided
-		if newServer.ModelType == "" {
-			newServer.ModelType = string(config.ModelTypeOther)
-		}
-
-		if newServer.ID == "" {
-			newServer.ID = generateInferenceServerID(newServer.ModelType)
+		if newSchedule.ID == "" {
+			newSchedule.ID = generateScheduleID()
 		}
+		newSchedule.CreatedAt = time.Now()
+		newSchedule.UpdatedAt = time.Now()
 
-		newServer.CreatedAt = time.Now()
-		newServer.UpdatedAt = time.Now()
-		newServer.Enabled = true
-
+		// client supplied IDs must not replace an existing schedule, that is what PUT is for
+		exists := false
 		store.SafeUpdateDataStore(func() {
-			store.Data.InferenceServers[newServer.ID] = &newServer
+			if _, exists = store.Data.Schedules[newSchedule.ID]; !exists {
+				store.Data.Schedules[newSchedule.ID] = &newSchedule
+			}
 		})
+		if exists {
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Schedule %s already exists", newSchedule.ID),
+			}
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
 
 		if err := store.SaveDataStore(); err != nil {
 			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
 		}
 
-		log.Info(fmt.Sprintf("created inference server: %s (%s)", newServer.ID, newServer.Name))
+		log.Info(fmt.Sprintf("created schedule: %s (%s)", newSchedule.ID, newSchedule.Name))
 
 		response := APIResponse{
 			Success: true,
-			Message: "Inference server created successfully",
-			Data:    &newServer,
+			Message: "Schedule created successfully",
+			Data:    &newSchedule,
 		}
-
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(response)
 	}
 }
 
-func (ws *WebServer) handleAPIInferenceServerByID(w http.ResponseWriter, r *http.Request) {
+func (ws *WebServer) handleAPIScheduleByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	vars := mux.Vars(r)
-	id := vars["id"]
+	id := mux.Vars(r)["id"]
 
-	server, exists := store.SafeGetInferenceServer(id)
+	schedule, exists := store.SafeGetSchedule(id)
 	if !exists {
 		response := APIResponse{
 			Success: false,
-			Message: "Inference server not found",
+			Message: "Schedule not found",
 		}
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(response)
@@ -732,14 +2317,14 @@ func (ws *WebServer) handleAPIInferenceServerByID(w http.ResponseWriter, r *http
 	case "GET":
 		response := APIResponse{
 			Success: true,
-			Message: "Inference server retrieved successfully",
-			Data:    server,
+			Message: "Schedule retrieved successfully",
+			Data:    schedule,
 		}
 		json.NewEncoder(w).Encode(response)
 
 	case "PUT":
-		var updatedServer store.InferenceServer
-		if err := json.NewDecoder(r.Body).Decode(&updatedServer); err != nil {
+		var updatedSchedule store.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&updatedSchedule); err != nil {
 			response := APIResponse{
 				Success: false,
 				Message: "Invalid request body",
@@ -750,113 +2335,78 @@ func (ws *WebServer) handleAPIInferenceServerByID(w http.ResponseWriter, r *http
 			return
 		}
 
-		updatedServer.ID = id
-		updatedServer.CreatedAt = server.CreatedAt
-		updatedServer.UpdatedAt = time.Now()
+		if err := updatedSchedule.Validate(); err != nil {
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid schedule",
+				Error:   err.Error(),
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		updatedSchedule.ID = id
+		updatedSchedule.CreatedAt = schedule.CreatedAt
+		updatedSchedule.UpdatedAt = time.Now()
 
 		store.SafeUpdateDataStore(func() {
-			store.Data.InferenceServers[id] = &updatedServer
+			store.Data.Schedules[id] = &updatedSchedule
 		})
 
 		if err := store.SaveDataStore(); err != nil {
 			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
 		}
 
-		log.Info(fmt.Sprintf("updated inference server: %s", id))
+		log.Info(fmt.Sprintf("updated schedule: %s", id))
 
 		response := APIResponse{
 			Success: true,
-			Message: "Inference server updated successfully",
-			Data:    &updatedServer,
+			Message: "Schedule updated successfully",
+			Data:    &updatedSchedule,
 		}
 		json.NewEncoder(w).Encode(response)
 
 	case "DELETE":
+		// Detach the schedule from cameras and bindings that use it. Readers hold on to the stored
+		// cameras outside the lock, so changed cameras are replaced by copies instead of modified.
 		store.SafeUpdateDataStore(func() {
-			for _, camera := range store.Data.Cameras {
-				for i, serverBinding := range camera.InferenceServerBindings {
-					if serverBinding.ServerID == id {
-						camera.InferenceServerBindings = append(camera.InferenceServerBindings[:i], camera.InferenceServerBindings[i+1:]...)
-						camera.UpdatedAt = time.Now()
-						break
+			for cameraID, camera := range store.Data.Cameras {
+				var updated *store.CameraConfig
+				copyCamera := func() *store.CameraConfig {
+					if updated == nil {
+						cameraCopy := *camera
+						cameraCopy.InferenceServerBindings = append([]store.InferenceServerBinding(nil), camera.InferenceServerBindings...)
+						cameraCopy.UpdatedAt = time.Now()
+						updated = &cameraCopy
 					}
+					return updated
 				}
-			}
-
-			delete(store.Data.InferenceServers, id)
-		})
-
-		if err := store.SaveDataStore(); err != nil {
-			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
-		}
-
-		log.Info(fmt.Sprintf("deleted inference server: %s", id))
-
-		response := APIResponse{
-			Success: true,
-			Message: "Inference server deleted successfully",
-		}
-		json.NewEncoder(w).Encode(response)
-	}
-}
-
-// Alert Server API Handler
-func (ws *WebServer) handleAPIAlertServer(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	switch r.Method {
-	case "GET":
-		// Return current alert server configuration
-		var alertConfig *store.AlertServerConfig
-		store.SafeReadDataStore(func() {
-			if store.Data.AlertServer != nil {
-				alertConfig = store.Data.AlertServer
-			} else {
-				// Return default/empty configuration
-				alertConfig = &store.AlertServerConfig{
-					URL:       "",
-					Enabled:   false,
-					UpdatedAt: time.Now(),
+				if camera.Schedule != nil && camera.Schedule.ScheduleID == id {
+					copyCamera().Schedule = nil
+				}
+				for i, binding := range camera.InferenceServerBindings {
+					if binding.Schedule != nil && binding.Schedule.ScheduleID == id {
+						copyCamera().InferenceServerBindings[i].Schedule = nil
+					}
+				}
+				if updated != nil {
+					store.Data.Cameras[cameraID] = updated
 				}
 			}
-		})
-
-		response := APIResponse{
-			Success: true,
-			Message: "Alert server configuration retrieved successfully",
-			Data:    alertConfig,
-		}
-		json.NewEncoder(w).Encode(response)
-
-	case "PUT":
-		// Update alert server configuration
-		var updatedConfig store.AlertServerConfig
-		if err := json.NewDecoder(r.Body).Decode(&updatedConfig); err != nil {
-			response := APIResponse{
-				Success: false,
-				Message: "Invalid request body",
-				Error:   err.Error(),
-			}
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(response)
-			return
-		}
 
-		updatedConfig.UpdatedAt = time.Now()
-		store.SafeUpdateDataStore(func() {
-			store.Data.AlertServer = &updatedConfig
+			delete(store.Data.Schedules, id)
 		})
 
 		if err := store.SaveDataStore(); err != nil {
 			log.Warn(fmt.Sprintf("failed to save data store: %v", err))
 		}
 
-		log.Info(fmt.Sprintf("updated alert server configuration: URL=%s, Enabled=%t", updatedConfig.URL, updatedConfig.Enabled))
+		log.Info(fmt.Sprintf("deleted schedule: %s", id))
 
 		response := APIResponse{
 			Success: true,
-			Message: "Alert server configuration updated successfully",
-			Data:    &updatedConfig,
+			Message: "Schedule deleted successfully",
 		}
 		json.NewEncoder(w).Encode(response)
 	}
@@ -937,6 +2487,15 @@ func (ws *WebServer) startFallDetectionResultPolling(task *store.FallDetectionTa
 // processFallResultsFromPolling processes fall detection results from polling
 func (ws *WebServer) processFallResultsFromPolling(results []FallDetectionResultItem, server *store.InferenceServer,
 	camera *store.CameraConfig, binding *store.InferenceServerBinding) {
+	// Results carry no capture time, use the time they were received
+	receivedAt := time.Now()
+
+	// Results outside the schedule are dropped, the task keeps running on the backend
+	schedule := scheduleStatus(camera, binding, receivedAt)
+	if !schedule.InferenceActive {
+		return
+	}
+
 	// Process each fall detection result independently and save immediately
 	for _, result := range results {
 		// Decode the image from backend
@@ -978,17 +2537,14 @@ func (ws *WebServer) processFallResultsFromPolling(results []FallDetectionResult
 		}
 
 		// Draw detection on the original image
-		drawnImage, err := common.DrawDetections(imageData, []common.Detection{detection}, camera.Name, false)
+		// and the debug image with labels
+		detections := []common.Detection{detection}
+		drawnImage, debugImage, err := common.DrawDetectionImages(imageData, detections,
+			detectionStyles(server, detections, true), resultBanner(camera.Name, receivedAt))
 		if err != nil {
 			log.Warn(fmt.Sprintf("failed to draw image for fall detection: %v", err))
 			continue
 		}
-		// debug image.
-		debugImage, err := common.DrawDetections(imageData, []common.Detection{detection}, camera.Name, true)
-		if err != nil {
-			log.Warn(fmt.Sprintf("failed to draw debug image for fall detection: %v", err))
-			continue
-		}
 
 		// Store original image copy for DEBUG mode
 		var originalImageCopy []byte
@@ -1006,6 +2562,7 @@ func (ws *WebServer) processFallResultsFromPolling(results []FallDetectionResult
 				DisplayResultImage: drawnImage,
 				DisplayDebugImage:  debugImage,
 				OriginalImage:      originalImageCopy,
+				CapturedAt:         receivedAt,
 				Error:              nil,
 			},
 		}
@@ -1013,18 +2570,24 @@ func (ws *WebServer) processFallResultsFromPolling(results []FallDetectionResult
 		// Launch independent async operations for fall detection result
 		modelResult := singleModelResult[server.ModelType]
 
-		// 1. Save fall detection result (async)
+		// Save fall detection result, then send the alert (muted outside the schedule)
 		go func() {
-			saveModelResult(camera.Name, modelResult, ws.RtspManager.OutputDir)
-		}()
+			imagePath := saveModelResult(camera, modelResult, ws.RtspManager.OutputDir)
+			if !schedule.AlertsActive {
+				return
+			}
 
-		// 2. Send fall detection alert (async)
-		go func() {
 			// Create image data copy for alert sending
 			alertImageData := make([]byte, len(modelResult.DisplayResultImage))
 			copy(alertImageData, modelResult.DisplayResultImage)
 
-			sendDetectionAlerts(alertImageData, modelResult.Detections, camera.Name, modelResult.ModelType)
+			sendDetectionAlerts(alertImageData, modelResult.Detections, &AlertSource{
+				Camera:     camera,
+				Server:     server,
+				Detections: modelResult.Detections,
+				ImagePath:  imagePath,
+				CapturedAt: receivedAt,
+			})
 		}()
 
 		log.Info(fmt.Sprintf("processed fall detection result: confidence=%.2f, camera=%s", confidence, camera.Name))
@@ -1166,7 +2729,7 @@ func (ws *WebServer) handleAPIConfigExport(w http.ResponseWriter, r *http.Reques
 	w.Header().Set("Content-Type", "application/json")
 
 	// Generate filename with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	timestamp := config.InTimezone(time.Now()).Format("2006-01-02_15-04-05")
 	filename := fmt.Sprintf("cameras_%s.json", timestamp)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
@@ -1240,6 +2803,136 @@ func (ws *WebServer) handleAPIConfigImport(w http.ResponseWriter, r *http.Reques
 	if importedData.InferenceServers == nil {
 		importedData.InferenceServers = make(map[string]*store.InferenceServer)
 	}
+	// exports from before the model registry get the built-in models
+	if importedData.Models == nil {
+		importedData.Models = store.DefaultModelDefinitions()
+	}
+	for name, model := range importedData.Models {
+		err := model.Validate()
+		if err == nil && model.Name != name {
+			err = fmt.Errorf("model %q is stored under %q", model.Name, name)
+		}
+		if err == nil {
+			err = store.ClassIndexConflict(importedData.Models, model)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid configuration for model %s", name),
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+	importedModelExists := func(name string) bool {
+		_, exists := importedData.Models[name]
+		return exists
+	}
+	for id, server := range importedData.InferenceServers {
+		if err := validateServerModelType(server, importedModelExists); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid configuration for inference server %s", id),
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if err := server.HealthCheck.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid configuration for inference server %s", id),
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+	if importedData.Schedules == nil {
+		importedData.Schedules = make(map[string]*store.Schedule)
+	}
+	for id, schedule := range importedData.Schedules {
+		if err := schedule.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid configuration for schedule %s", id),
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+	if importedData.AlertServer != nil {
+		if err := importedData.AlertServer.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: "Invalid alert server configuration",
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+	if importedData.AlertDestinations == nil {
+		importedData.AlertDestinations = make(map[string]*store.AlertDestination)
+	}
+	for id, destination := range importedData.AlertDestinations {
+		if err := validateAlertDestination(destination); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid configuration for alert destination %s", id),
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+	importedScheduleExists := func(id string) bool {
+		_, exists := importedData.Schedules[id]
+		return exists
+	}
+	if importedData.ServerGroups == nil {
+		importedData.ServerGroups = make(map[string]*store.ServerGroup)
+	}
+	importedServer := func(id string) (*store.InferenceServer, bool) {
+		server, exists := importedData.InferenceServers[id]
+		return server, exists
+	}
+	for id, group := range importedData.ServerGroups {
+		if err := validateServerGroup(group, importedServer); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid configuration for server group %s", id),
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+	importedGroupExists := func(id string) bool {
+		_, exists := importedData.ServerGroups[id]
+		return exists
+	}
+	for id, camera := range importedData.Cameras {
+		if err := validateCameraConfig(camera, importedScheduleExists, importedGroupExists); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			response := APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("Invalid configuration for camera %s", id),
+				Error:   err.Error(),
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
 
 	// Stop all running cameras and fall detection tasks before import
 	if ws.RtspManager != nil {
@@ -1268,6 +2961,18 @@ func (ws *WebServer) handleAPIConfigImport(w http.ResponseWriter, r *http.Reques
 		store.FallDetectionTasks = make(map[string]*store.FallDetectionTaskState)
 	})
 
+	// Disconnect MQTT destinations, they reconnect with the imported settings on the next alert,
+	// and forget their templates and the health of the replaced inference servers
+	store.SafeReadDataStore(func() {
+		for id := range store.Data.AlertDestinations {
+			closeMQTTClient(id)
+			evictAlertTemplates(id)
+		}
+		for id := range store.Data.InferenceServers {
+			store.SafeDeleteServerHealth(id)
+		}
+	})
+
 	// Replace current dataStore with imported data using thread-safe access
 	store.SafeUpdateDataStore(func() {
 		store.Data = &importedData
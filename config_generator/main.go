@@ -21,6 +21,10 @@ type Config struct {
 	AlertServer string   `yaml:"alert_server"`
 	ExcelPath   string   `yaml:"excel_path"`
 	FilterMap   []string `yaml:"filter_map"`
+	// balancing of the per model server groups: round_robin (default), least_inflight or consistent_hash
+	Balance string `yaml:"balance"`
+	// model registry, the built-in models when empty
+	Models []ModelDefinition `yaml:"models"`
 }
 
 // LoadConfig loads configuration from YAML file
@@ -38,44 +42,77 @@ func LoadConfig(filePath string) (*Config, error) {
 	return &config, nil
 }
 
-// read from excel file.
-type ModelType string
-
-const (
-	ModelHelmet      ModelType = "安全帽"
-	ModelMouse       ModelType = "老鼠"
-	ModelShortSleeve ModelType = "短袖"
-	ModelPonding     ModelType = "积水"
-	ModelFall        ModelType = "倒地"
-	ModelSafetyBelt  ModelType = "安全带"
-	ModelCigar       ModelType = "吸烟"
-	ModelGesture     ModelType = "手势"
-	ModelSmoke       ModelType = "烟雾"
-	ModelFire        ModelType = "火焰"
-)
+// ModelDefinition is a model of the camera platform's model registry, Path and Host only
+// tell the generator where the model is served and are not written to the output
+type ModelDefinition struct {
+	Name             string       `yaml:"name" json:"name"`
+	DisplayName      string       `yaml:"display_name" json:"display_name"`
+	Aliases          []string     `yaml:"aliases" json:"aliases,omitempty"`
+	Classes          []ModelClass `yaml:"classes" json:"classes"`
+	IgnoredClasses   []string     `yaml:"ignored_classes" json:"ignored_classes,omitempty"`
+	ScoreStrategy    string       `yaml:"score_strategy" json:"score_strategy,omitempty"`
+	DefaultThreshold float64      `yaml:"default_threshold" json:"default_threshold"`
+	CreatedAt        time.Time    `yaml:"-" json:"created_at"`
+	UpdatedAt        time.Time    `yaml:"-" json:"updated_at"`
+	// url path on the inference hosts, defaults to the name
+	Path string `yaml:"path" json:"-"`
+	// hosts serving the model: "tianwan1", "tianwan2" or empty for none
+	Host string `yaml:"host" json:"-"`
+}
+
+type ModelClass struct {
+	Name        string `yaml:"name" json:"name"`
+	Index       int    `yaml:"index" json:"index"`
+	DisplayName string `yaml:"display_name" json:"display_name,omitempty"`
+}
 
-func convertNamesToUrl(m ModelType) string {
-	switch m {
-	case ModelHelmet:
-		return "helmet"
-	case ModelMouse:
-		return "mouse"
-	case ModelShortSleeve:
-		return "tshirt"
-	case ModelPonding:
-		return "ponding"
-	case ModelFall:
-		return "fall"
-	case ModelSafetyBelt:
-		return "safetybelt"
-	case ModelCigar:
-		return "cigar"
-	case ModelGesture:
-		return "gesture"
-	case ModelSmoke:
-		return "smoke"
-	case ModelFire:
-		return "fire"
+// builtinModels are the models of the camera platform, used when config.yaml does not list models.
+// Names, display names and class indices mirror store.DefaultModelDefinitions of cam-stream, which
+// seeds the registry when the generated file has no models section, only the hosts, paths and
+// aliases used for the inference servers and the camera sheet are kept here.
+func builtinModels() []ModelDefinition {
+	model := func(name, displayName string, index int, host string) ModelDefinition {
+		return ModelDefinition{
+			Name:             name,
+			DisplayName:      displayName,
+			Classes:          []ModelClass{{Name: name, Index: index, DisplayName: displayName}},
+			DefaultThreshold: 0.5,
+			Host:             host,
+		}
+	}
+	models := []ModelDefinition{
+		model("other", "其他", 0, ""),
+		model("gesture", "手势", 1, "tianwan1"),
+		model("ponding", "积水", 2, "tianwan1"),
+		model("smoke", "烟雾", 3, "tianwan1"),
+		model("mouse", "老鼠", 4, "tianwan1"),
+		model("tshirt", "短袖", 5, "tianwan1"),
+		model("cigar", "吸烟", 6, "tianwan1"),
+		model("helmet", "安全帽", 7, "tianwan1"),
+		model("fire", "火焰", 8, "tianwan1"),
+		model("fall", "摔倒", 9, "tianwan1"),
+		model("safetybelt", "安全带", 10, "tianwan2"),
+	}
+	// both the fire and the smoke models are served at /smoke, each drops the other's boxes
+	models[3].IgnoredClasses = []string{"fire"}
+	models[8].IgnoredClasses = []string{"smoke"}
+	models[8].Path = "smoke"
+	models[5].ScoreStrategy = "cls_score"
+	models[9].Aliases = []string{"倒地"}
+	return models
+}
+
+// findModelByDisplayName returns the name of the model with the given display name or alias
+func findModelByDisplayName(models []ModelDefinition, displayName string) string {
+	for _, m := range models {
+		if m.DisplayName == displayName {
+			return m.Name
+		}
+		for _, alias := range m.Aliases {
+			if alias == displayName {
+				return m.Name
+			}
+		}
 	}
 	return ""
 }
@@ -86,7 +123,7 @@ type CameraInfo struct {
 	Models     []string
 }
 
-func ReadCameraInfoFromExcel(filePath string) ([]CameraInfo, error) {
+func ReadCameraInfoFromExcel(filePath string, modelDefs []ModelDefinition) ([]CameraInfo, error) {
 	f, err := excelize.OpenFile(filePath)
 	if err != nil {
 		return nil, err
@@ -127,7 +164,11 @@ func ReadCameraInfoFromExcel(filePath string) ([]CameraInfo, error) {
 			for _, m := range modelList {
 				m = strings.TrimSpace(m)
 				if m != "" {
-					u := convertNamesToUrl(ModelType(m))
+					u := findModelByDisplayName(modelDefs, m)
+					if u == "" {
+						slog.Warn("skipping unknown model", "model", m, "device", deviceName)
+						continue
+					}
 					models = append(models, u)
 					modelMap[u] = true
 				}
@@ -152,8 +193,8 @@ func ReadCameraInfoFromExcel(filePath string) ([]CameraInfo, error) {
 	return cameras, nil
 }
 
-func readCamerasFromFile(filePath string, filterList []string) []CameraInfo {
-	cameras, err := ReadCameraInfoFromExcel(filePath)
+func readCamerasFromFile(filePath string, filterList []string, models []ModelDefinition) []CameraInfo {
+	cameras, err := ReadCameraInfoFromExcel(filePath, models)
 	if err != nil {
 		slog.Error("failed to read cameras' info from excel", "error", err)
 		return nil
@@ -189,9 +230,21 @@ type InferenceServer struct {
 // InferenceServerBinding represents a binding between camera and inference server with threshold
 type InferenceServerBinding struct {
 	ServerID     string  `json:"server_id"`
+	GroupID      string  `json:"group_id,omitempty"`
 	Threshold    float64 `json:"threshold"`
 	MaxThreshold float64 `json:"max_threshold"`
 }
+
+// ServerGroup balances the bindings of one model type over the servers of all hosts
+type ServerGroup struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ModelType string    `json:"model_type"`
+	ServerIDs []string  `json:"server_ids"`
+	Balance   string    `json:"balance,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
 type CameraConfig struct {
 	ID                      string                   `json:"id"`
 	Name                    string                   `json:"name"`
@@ -214,6 +267,8 @@ type DataStore struct {
 	Cameras          map[string]*CameraConfig    `json:"cameras"`
 	InferenceServers map[string]*InferenceServer `json:"inference_servers"`
 	AlertServer      *AlertServerConfig          `json:"alert_server,omitempty"`
+	ServerGroups     map[string]*ServerGroup     `json:"server_groups,omitempty"`
+	Models           map[string]*ModelDefinition `json:"models,omitempty"`
 }
 
 // TODO: move these functions to 'common' package
@@ -251,9 +306,16 @@ func main() {
 		return
 	}
 	slog.Info("loading config from: " + *configPath)
+	models := config.Models
+	customModels := len(models) > 0
+	if !customModels {
+		models = builtinModels()
+	}
 	serverConfig := DataStore{
 		Cameras:          make(map[string]*CameraConfig),
 		InferenceServers: make(map[string]*InferenceServer),
+		ServerGroups:     make(map[string]*ServerGroup),
+		Models:           make(map[string]*ModelDefinition),
 		AlertServer: &AlertServerConfig{
 			URL:       config.AlertServer,
 			Enabled:   false,
@@ -261,71 +323,84 @@ func main() {
 		},
 	}
 
-	// generate 'inference_servers' section
-	aServerModelTypes := []string{
-		"mouse",
-		"ponding",
-		"cigar",
-		"gesture",
-		"fall",
-		"tshirt",
-		"helmet",
-		"smoke",
-		"fire",
+	// generate 'models' section, the built-in models are left to cam-stream
+	if customModels {
+		for i := range models {
+			m := &models[i]
+			m.CreatedAt = time.Now()
+			m.UpdatedAt = time.Now()
+			serverConfig.Models[m.Name] = m
+		}
 	}
 
+	// generate 'inference_servers' section, one server per model and host
 	allAvailableServers := make(map[string][]AvailableServer)
-	for i, addr := range config.Tianwan1 {
-		var availableServerByIp []AvailableServer
-		for _, t := range aServerModelTypes {
-			id := fmt.Sprintf("inf_%s_%s", t, GenerateUUID())
-			// TODO: it should be noted that both the fire and the smoke
-			// models are using the same url: /smoke
-			modelUrl := t
-			if t == "fire" {
-				modelUrl = "smoke"
-			}
-			serverConfig.InferenceServers[id] = &InferenceServer{
-				ID:        id,
-				Name:      fmt.Sprintf("%s%d", t, i+1),
-				URL:       fmt.Sprintf("http://%s/%s", addr, modelUrl),
-				ModelType: t,
-				Enabled:   true,
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
+	hosts := []struct {
+		name  string
+		addrs []string
+	}{{"tianwan1", config.Tianwan1}, {"tianwan2", config.Tianwan2}}
+	for _, host := range hosts {
+		for i, addr := range host.addrs {
+			var availableServerByIp []AvailableServer
+			for _, m := range models {
+				if m.Host != host.name {
+					continue
+				}
+				modelUrl := m.Path
+				if modelUrl == "" {
+					modelUrl = m.Name
+				}
+				id := fmt.Sprintf("inf_%s_%s", m.Name, GenerateUUID())
+				serverConfig.InferenceServers[id] = &InferenceServer{
+					ID:        id,
+					Name:      fmt.Sprintf("%s%d", m.Name, i+1),
+					URL:       fmt.Sprintf("http://%s/%s", addr, modelUrl),
+					ModelType: m.Name,
+					Enabled:   true,
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				}
+				availableServerByIp = append(availableServerByIp, AvailableServer{
+					ID:        id,
+					modelType: m.Name,
+				})
 			}
-			availableServerByIp = append(availableServerByIp, AvailableServer{
-				ID:        id,
-				modelType: t,
-			})
+			allAvailableServers[addr] = availableServerByIp
 		}
-		allAvailableServers[addr] = availableServerByIp
 	}
 
-	for i, addr := range config.Tianwan2 {
-		var availableServerByIp []AvailableServer
-
-		t := "safetybelt"
-		id := fmt.Sprintf("inf_%s_%s", t, GenerateUUID())
-		serverConfig.InferenceServers[id] = &InferenceServer{
-			ID:        id,
-			Name:      fmt.Sprintf("%s%d", t, i+1),
-			URL:       fmt.Sprintf("http://%s/%s", addr, t),
-			ModelType: t,
-			Enabled:   true,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+	// generate 'server_groups' section, one group per model type over all hosts so that
+	// losing a host fails over to the others. Fall detection runs a task per camera and
+	// server on the backend and cannot be grouped.
+	groupIDs := make(map[string]string)
+	for _, addrs := range [][]string{config.Tianwan1, config.Tianwan2} {
+		for _, addr := range addrs {
+			for _, s := range allAvailableServers[addr] {
+				if s.modelType == "fall" {
+					continue
+				}
+				groupID, exists := groupIDs[s.modelType]
+				if !exists {
+					groupID = fmt.Sprintf("grp_%s", GenerateUUID())
+					groupIDs[s.modelType] = groupID
+					serverConfig.ServerGroups[groupID] = &ServerGroup{
+						ID:        groupID,
+						Name:      s.modelType,
+						ModelType: s.modelType,
+						Balance:   config.Balance,
+						CreatedAt: time.Now(),
+						UpdatedAt: time.Now(),
+					}
+				}
+				group := serverConfig.ServerGroups[groupID]
+				group.ServerIDs = append(group.ServerIDs, s.ID)
+			}
 		}
-		availableServerByIp = append(availableServerByIp, AvailableServer{
-			ID:        id,
-			modelType: t,
-		})
-		allAvailableServers[addr] = availableServerByIp
 	}
 
 	// generate 'cameras' section
-	ia, ib := 0, 0
-	for _, c := range readCamerasFromFile(config.ExcelPath, config.FilterMap) {
+	ia := 0
+	for _, c := range readCamerasFromFile(config.ExcelPath, config.FilterMap, models) {
 		// basic info
 		cid := fmt.Sprintf("cam_%s", GenerateUUID())
 		camera := CameraConfig{
@@ -339,17 +414,20 @@ func main() {
 		}
 		// bindings
 		for _, m := range c.Models {
+			model, registered := serverConfig.Models[m]
+			if !registered {
+				slog.Warn("skipping model missing from the model registry", "model", m, "device", c.DeviceName)
+				continue
+			}
 			binding := InferenceServerBinding{
-				Threshold:    0.5,
+				Threshold:    model.DefaultThreshold,
 				MaxThreshold: 0,
 			}
-			// arrange to type B server
-			if m == "safetybelt" {
-				ip := config.Tianwan2[ib]
-				binding.ServerID = findAvailableServerId(allAvailableServers[ip], m)
-				ib = (ib + 1) % len(config.Tianwan2)
+			// balanced over all servers of the model type
+			if groupID, grouped := groupIDs[m]; grouped {
+				binding.GroupID = groupID
 			} else {
-				// arrange to type A server
+				// fall detection: spread cameras across type A servers
 				ip := config.Tianwan1[ia]
 				binding.ServerID = findAvailableServerId(allAvailableServers[ip], m)
 				ia = (ia + 1) % len(config.Tianwan1)
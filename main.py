@@ -145,6 +145,30 @@ def app():
     initialize_models()
     logger.info("server is up!")
     
+    # models served by this host and the path of their endpoint, used by the
+    # camera platform to discover and validate inference servers.
+    # NOTE: fire is reported by the smoke model. fall detection runs as rtsp tasks
+    # under /fall/start, /fall/stop and /fall/result and has no per frame endpoint.
+    @app.route('/models', methods=['GET'])
+    def ListModels():
+        models = [
+            ("gesture", "Gesture Detection", "/gesture"),
+            ("ponding", "Ponding Detection", "/ponding"),
+            ("mouse", "Mouse Detection", "/mouse"),
+            ("helmet", "Helmet Detection", "/helmet"),
+            ("cigar", "Cigar Detection", "/cigar"),
+            ("tshirt", "T-shirt Detection", "/tshirt"),
+            ("smoke", "Smoke Detection", "/smoke"),
+            ("fire", "Fire Detection", "/smoke"),
+        ]
+        return {
+            "server_version": "0.0.1",
+            "models": [
+                {"model_type": t, "name": name, "path": path, "version": "0.0.1"}
+                for t, name, path in models
+            ]
+        }
+
     # router settings, no trailing slash so that:
     #   /GeneralClassifyService == /GeneralClassifyService/
     @app.route('/gesture', methods=['POST'])